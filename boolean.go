@@ -27,6 +27,7 @@ var T = MakeSymbol("T")
 func init() {
 	_ = T.Bind(T)
 	T.Freeze()
+	_ = initPackage.Export(T)
 }
 
 // IsTrue returns true, if the given object will be interpreted as "true" in a boolean context.
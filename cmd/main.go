@@ -33,10 +33,12 @@ import (
 type mainEngine struct {
 	logReader    bool
 	logParse     bool
+	logExpand    bool
 	logImprove   bool
 	logExpr      bool
 	logCompute   bool
 	logStats     bool
+	compile      bool
 	parseLevel   int
 	improveLevel int
 	nestHandler  *sxeval.NestingHandler
@@ -90,6 +92,19 @@ func (me *mainEngine) AfterParse(_ *sxeval.ParseEnvironment, _ sx.Object, expr s
 	}
 }
 
+// ----- ExpandObserver methods
+
+func (me *mainEngine) AfterExpand(_ *sxeval.ParseEnvironment, call *sx.Pair, form sx.Object, frame *sxeval.Frame, err error) {
+	if me.logExpand {
+		spaces := strings.Repeat(" ", me.parseLevel)
+		if err == nil {
+			fmt.Printf("%s;M %v<-%v %v => %v\n", spaces, frame.Name(), frame.Parent().Name(), call, form)
+		} else {
+			fmt.Printf("%s;m %v<-%v %v => %v\n", spaces, frame.Name(), frame.Parent().Name(), call, err)
+		}
+	}
+}
+
 //----- ImproveObserver methods
 
 func (me *mainEngine) BeforeImprove(imp *sxeval.Improver, expr sxeval.Expr) sxeval.Expr {
@@ -115,6 +130,18 @@ func (me *mainEngine) AfterImprove(imp *sxeval.Improver, _, result sxeval.Expr,
 	}
 }
 
+func (me *mainEngine) Optimized(imp *sxeval.Improver, name string, expr, result sxeval.Expr) {
+	if me.logImprove {
+		spaces := strings.Repeat(" ", me.improveLevel)
+		frame := imp.Frame()
+		fmt.Printf("%s;I%v %v<-%v %s ", spaces, me.improveLevel, frame.Name(), frame.Parent().Name(), name)
+		_, _ = expr.Print(os.Stdout)
+		fmt.Print(" => ")
+		_, _ = result.Print(os.Stdout)
+		fmt.Println()
+	}
+}
+
 var myBuiltins = []*sxeval.Builtin{
 	{
 		Name:     "panic",
@@ -153,6 +180,14 @@ func (me *mainEngine) bindOwn(root *sxeval.Binding) {
 				return sx.MakeBoolean(res), nil
 			},
 		},
+		&sxeval.Builtin{
+			Name: "log-expand",
+			Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
+				res := me.logExpand
+				me.logExpand = !res
+				return sx.MakeBoolean(res), nil
+			},
+		},
 		&sxeval.Builtin{
 			Name: "log-improve",
 			Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
@@ -185,11 +220,20 @@ func (me *mainEngine) bindOwn(root *sxeval.Binding) {
 				return sx.MakeBoolean(res), nil
 			},
 		},
+		&sxeval.Builtin{
+			Name: "compile",
+			Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
+				res := me.compile
+				me.compile = !res
+				return sx.MakeBoolean(res), nil
+			},
+		},
 		&sxeval.Builtin{
 			Name: "log-off",
 			Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
 				me.logReader = false
 				me.logParse = false
+				me.logExpand = false
 				me.logImprove = false
 				me.logCompute = false
 				return sx.Nil(), nil
@@ -211,6 +255,7 @@ func main() {
 	me := mainEngine{
 		logReader:   true,
 		logParse:    true,
+		logExpand:   true,
 		logImprove:  true,
 		logExpr:     false,
 		logCompute:  true,
@@ -224,6 +269,10 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Unable to read prelude: %v\n", err)
 		os.Exit(17)
 	}
+	if err = root.ExportBindings(); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to export bindings: %v\n", err)
+		os.Exit(17)
+	}
 	root.Freeze()
 	bind := root.MakeChildBinding("repl", 1024)
 	_ = bind.Bind(sx.MakeSymbol("root-binding"), root)
@@ -253,7 +302,7 @@ func repl(rd *sxreader.Reader, me *mainEngine, bind *sxeval.Binding, wg *sync.Wa
 
 	for {
 		env := sxeval.MakeEnvironment(bind)
-		env.SetParseObserver(me).SetImproveObserver(me)
+		env.SetParseObserver(me).SetExpandObserver(me).SetImproveObserver(me).SetCompile(me.compile)
 		if me.logCompute || me.logStats {
 			env.SetComputeHandler(me)
 		} else {
@@ -282,6 +331,10 @@ func repl(rd *sxreader.Reader, me *mainEngine, bind *sxeval.Binding, wg *sync.Wa
 			fmt.Println()
 		}
 		if me.logExpr {
+			if ce, isCompiled := expr.(*sxeval.CompiledExpr); isCompiled {
+				_, _ = ce.Disassemble(os.Stdout)
+				continue
+			}
 			printExpr(expr, 0)
 			continue
 		}
@@ -307,21 +360,14 @@ func repl(rd *sxreader.Reader, me *mainEngine, bind *sxeval.Binding, wg *sync.Wa
 }
 
 func printExpr(expr sxeval.Expr, level int) {
-	if level <= 0 {
-		level = -level
-	} else {
-		fmt.Print(strings.Repeat(" ", level*2))
-	}
-
+	fmt.Print(strings.Repeat(" ", level*2))
 	switch e := expr.(type) {
 	case *sxeval.CallExpr:
 		fmt.Println("CALL")
-		printExpr(e.Proc, level+1)
-		for _, arg := range e.Args {
-			printExpr(arg, level+1)
-		}
 	case sxeval.ObjExpr:
 		fmt.Printf("OBJ %T/%v\n", e.Obj, e.Obj)
+	case *sxeval.CompiledExpr:
+		fmt.Println("COMPILED")
 	case *sxbuiltins.LambdaExpr:
 		fmt.Printf("LAMBDA %q", e.Name)
 		for _, sym := range e.Params {
@@ -331,30 +377,18 @@ func printExpr(expr sxeval.Expr, level int) {
 			fmt.Printf(" . %v", e.Rest)
 		}
 		fmt.Println()
-		printExpr(e.Expr, level+1)
 	case *sxbuiltins.LetExpr:
-		fmt.Println("LET")
-		level++
-		for i, sym := range e.Symbols {
-			fmt.Print(strings.Repeat(" ", level*2))
-			fmt.Print(sym, ":")
-			printExpr(e.Vals[i], -level)
-		}
-		printExpr(e.Body, level)
+		fmt.Println("LET", e.Symbols)
 	case *sxbuiltins.IfExpr:
 		fmt.Println("IF")
-		printExpr(e.Test, level+1)
-		printExpr(e.True, level+1)
-		printExpr(e.False, level+1)
 	case *sxbuiltins.DefineExpr:
 		fmt.Println("DEFVAR", e.Sym)
-		printExpr(e.Val, level+1)
 	case *sxbuiltins.SetXExpr:
 		fmt.Println("SET!", e.Sym)
-		printExpr(e.Val, level+1)
 	case sxbuiltins.MakeListExpr:
 		fmt.Println("MAKELIST")
-		printExpr(e.Elem, level+1)
+	case sxeval.Composite:
+		fmt.Printf("%T\n", expr)
 	default:
 		switch e {
 		case sxeval.NilExpr:
@@ -363,4 +397,9 @@ func printExpr(expr sxeval.Expr, level int) {
 			fmt.Printf("%T/%v\n", expr, expr)
 		}
 	}
+	if comp, isComposite := expr.(sxeval.Composite); isComposite {
+		for _, child := range comp.Children() {
+			printExpr(child, level+1)
+		}
+	}
 }
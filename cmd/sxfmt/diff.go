@@ -0,0 +1,202 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+// Provides a line-based diff in unified format.
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines around a change.
+const diffContext = 3
+
+// diffOp is a single line of a diff.
+type diffOp struct {
+	kind byte // ' ', '-', '+'
+	line string
+}
+
+// unifiedDiff returns the difference between old and new in unified format.
+// If both are equal, the empty string is returned.
+func unifiedDiff(name, old, new string) string {
+	ops := diffLines(splitLines(old), splitLines(new))
+	var sb strings.Builder
+	for i := 0; i < len(ops); {
+		// Search next change.
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i >= len(ops) {
+			break
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s.orig\n+++ %s\n", name, name)
+		}
+
+		start := max(0, i-diffContext)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			// Hunk ends, if there are enough unchanged lines.
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next >= len(ops) || next-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = next
+		}
+		writeHunk(&sb, ops, start, end)
+		i = end
+	}
+	return sb.String()
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp, start, end int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	oldCount, newCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount))
+	for _, op := range ops[start:end] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.line)
+		sb.WriteByte('\n')
+	}
+}
+
+func hunkRange(line, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", line-1)
+	}
+	if count == 1 {
+		return fmt.Sprint(line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines computes the differences between two sequences of lines, based
+// on their longest common subsequence. It uses the algorithm of Hirschberg,
+// which needs only linear space.
+func diffLines(a, b []string) []diffOp {
+	return appendDiff(make([]diffOp, 0, max(len(a), len(b))), a, b)
+}
+
+func appendDiff(ops []diffOp, a, b []string) []diffOp {
+	// Common prefix and suffix are unchanged.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	ops = appendOps(ops, ' ', a[:prefix])
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		ops = appendOps(ops, '+', b)
+	case len(b) == 0:
+		ops = appendOps(ops, '-', a)
+	case len(a) == 1:
+		if k := slices.Index(b, a[0]); k >= 0 {
+			ops = appendOps(ops, '+', b[:k])
+			ops = append(ops, diffOp{' ', a[0]})
+			ops = appendOps(ops, '+', b[k+1:])
+		} else {
+			ops = append(ops, diffOp{'-', a[0]})
+			ops = appendOps(ops, '+', b)
+		}
+	default:
+		// Split b, so that the longest common subsequences of both halves
+		// of a with the corresponding parts of b are maximal.
+		mid := len(a) / 2
+		front, back := lcsLengths(a[:mid], b, false), lcsLengths(a[mid:], b, true)
+		split, best := 0, -1
+		for j := range len(b) + 1 {
+			if length := front[j] + back[len(b)-j]; length > best {
+				split, best = j, length
+			}
+		}
+		ops = appendDiff(ops, a[:mid], b[:split])
+		ops = appendDiff(ops, a[mid:], b[split:])
+	}
+	return appendOps(ops, ' ', common)
+}
+
+func appendOps(ops []diffOp, kind byte, lines []string) []diffOp {
+	for _, line := range lines {
+		ops = append(ops, diffOp{kind, line})
+	}
+	return ops
+}
+
+// lcsLengths returns the lengths of the longest common subsequences of a
+// and all prefixes of b: the element j is the length for b[:j]. If reverse
+// is true, both are processed backwards, i.e. the element j is the length for
+// a and the last j lines of b.
+func lcsLengths(a, b []string, reverse bool) []int {
+	line := func(lines []string, i int) string {
+		if reverse {
+			return lines[len(lines)-1-i]
+		}
+		return lines[i]
+	}
+	prev, curr := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		ai := line(a, i)
+		for j := range b {
+			if ai == line(b, j) {
+				curr[j+1] = prev[j] + 1
+			} else {
+				curr[j+1] = max(prev[j+1], curr[j])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev
+}
@@ -0,0 +1,241 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+// Provides the layout of the concrete syntax tree.
+
+import (
+	"strings"
+
+	"t73f.de/r/sx/sxpretty"
+)
+
+// formatter formats sx sources.
+type formatter struct {
+	width int
+	rules map[string]sxpretty.Rule
+}
+
+func makeFormatter() *formatter {
+	return &formatter{width: sxpretty.DefaultWidth, rules: sxpretty.DefaultRules()}
+}
+
+// format returns the canonical layout of the given source.
+func (f *formatter) format(src string) (string, error) {
+	items, comments, err := parseFile(src)
+	if err != nil {
+		return "", err
+	}
+	var docs []sxpretty.Doc
+	var prev *item
+	for _, it := range items {
+		if prev != nil {
+			docs = append(docs, separator(prev, it, sxpretty.HardLine()))
+		}
+		docs = append(docs, f.itemDoc(it))
+		prev = it
+	}
+	docs = append(docs, endCommentDocs(prev != nil, comments)...)
+	if len(docs) == 0 {
+		return "", nil
+	}
+	var sb strings.Builder
+	if _, err = sxpretty.Render(&sb, f.width, sxpretty.Concat(docs...)); err != nil {
+		return "", err
+	}
+	sb.WriteByte('\n')
+	return sb.String(), nil
+}
+
+// separator returns the document that separates two items. If there is no
+// reason to start a new line, the default separator is returned.
+func separator(prev, it *item, def sxpretty.Doc) sxpretty.Doc {
+	blank := it.blank
+	if len(it.leading) > 0 {
+		blank = it.leading[0].blank
+	}
+	if blank {
+		return sxpretty.Concat(sxpretty.HardLine(), sxpretty.HardLine())
+	}
+	if mustBreak(prev, it) {
+		return sxpretty.HardLine()
+	}
+	return def
+}
+
+// mustBreak returns true, if the item must start on a new line.
+func mustBreak(prev, it *item) bool {
+	return it.blank || len(it.leading) > 0 || (prev != nil && prev.trailing != nil)
+}
+
+// endCommentDocs returns the documents for the comments after the last item.
+func endCommentDocs(hasItems bool, comments []*comment) []sxpretty.Doc {
+	var docs []sxpretty.Doc
+	for i, c := range comments {
+		if c.blank {
+			docs = append(docs, sxpretty.HardLine())
+		}
+		if i > 0 || hasItems {
+			docs = append(docs, sxpretty.HardLine())
+		}
+		docs = append(docs, sxpretty.BreakParent(), sxpretty.Text(c.text))
+	}
+	return docs
+}
+
+// itemDoc returns the document of an item, including its comments.
+func (f *formatter) itemDoc(it *item) sxpretty.Doc {
+	return sxpretty.Concat(f.itemBodyDoc(it), trailingDoc(it))
+}
+
+// itemBodyDoc returns the document of an item, including its leading
+// comments, but without its trailing comment.
+func (f *formatter) itemBodyDoc(it *item) sxpretty.Doc {
+	var docs []sxpretty.Doc
+	for i, c := range it.leading {
+		if i > 0 && c.blank {
+			docs = append(docs, sxpretty.HardLine())
+		}
+		docs = append(docs, sxpretty.Text(c.text), sxpretty.HardLine())
+	}
+	if len(it.leading) > 0 && it.blank {
+		docs = append(docs, sxpretty.HardLine())
+	}
+	return sxpretty.Concat(append(docs, f.nodeDoc(it.node))...)
+}
+
+// trailingDoc returns the document of the trailing comment of an item.
+func trailingDoc(it *item) sxpretty.Doc {
+	if c := it.trailing; c != nil {
+		return sxpretty.Concat(sxpretty.BreakParent(), docSpace, sxpretty.Text(c.text))
+	}
+	return sxpretty.Concat()
+}
+
+func (f *formatter) nodeDoc(n *node) sxpretty.Doc {
+	switch n.kind {
+	case nodeList:
+		return f.listDoc(n)
+	case nodePrefix:
+		return sxpretty.Concat(sxpretty.Text(n.text), f.itemDoc(n.children[0]))
+	default:
+		return sxpretty.Text(n.text)
+	}
+}
+
+var (
+	docOpen  = sxpretty.Text("(")
+	docClose = sxpretty.Text(")")
+	docSpace = sxpretty.Text(" ")
+)
+
+// listDoc returns the document of a list. The layout depends on the first
+// element of the list.
+func (f *formatter) listDoc(n *node) sxpretty.Doc {
+	items := n.children
+	if len(items) == 0 {
+		if len(n.endComments) == 0 && n.openComment == nil {
+			return sxpretty.Text("()")
+		}
+		docs := []sxpretty.Doc{docOpen}
+		if c := n.openComment; c != nil {
+			docs = append(docs, sxpretty.BreakParent(), docSpace, sxpretty.Text(c.text))
+		}
+		docs = append(docs, sxpretty.Align(sxpretty.Concat(endCommentDocs(n.openComment != nil, n.endComments)...)))
+		return sxpretty.Concat(append(docs, sxpretty.HardLine(), docClose)...)
+	}
+
+	head := items[0]
+	if head.node.kind != nodeAtom || n.openComment != nil {
+		// A data list: all elements are aligned.
+		return sxpretty.Group(sxpretty.Concat(
+			docOpen,
+			sxpretty.Align(sxpretty.Concat(f.openCommentDoc(n), f.joinItems(nil, items, sxpretty.Line()), f.endDoc(n))),
+			docClose))
+	}
+
+	rule, hasRule := f.rules[head.node.text]
+	if !hasRule {
+		// A function call: arguments are aligned to the first argument.
+		if len(items) == 1 {
+			return sxpretty.Group(sxpretty.Concat(docOpen, f.itemDoc(head), f.endDoc(n), docClose))
+		}
+		if mustBreak(head, items[1]) {
+			// Arguments are aligned to the head, if the first one cannot be
+			// placed after it.
+			return sxpretty.Group(sxpretty.Align(sxpretty.Concat(
+				docOpen, f.itemDoc(head),
+				sxpretty.Nest(1, sxpretty.Concat(f.joinItems(head, items[1:], sxpretty.Line()), f.endDoc(n))),
+				docClose)))
+		}
+		return sxpretty.Group(sxpretty.Concat(
+			docOpen, f.itemDoc(head), separator(head, items[1], docSpace),
+			sxpretty.Align(sxpretty.Concat(f.itemDoc(items[1]), f.joinItems(items[1], items[2:], sxpretty.Line()), f.endDoc(n))),
+			docClose))
+	}
+
+	special := min(rule.Special, len(items)-1)
+	if special > 0 && mustBreak(head, items[1]) {
+		special = 0
+	}
+	docs := []sxpretty.Doc{docOpen, f.itemDoc(head)}
+	if special > 0 {
+		// The trailing comment of the last special argument is placed after
+		// the group, so that it does not break the header of the form.
+		last := items[special]
+		header := f.joinItems(nil, items[1:special], sxpretty.Line())
+		if special > 1 {
+			header = sxpretty.Concat(header, separator(items[special-1], last, sxpretty.Line()))
+		}
+		header = sxpretty.Concat(header, f.itemBodyDoc(last))
+		docs = append(docs,
+			separator(head, items[1], docSpace),
+			sxpretty.Align(sxpretty.Group(header)),
+			trailingDoc(last))
+	}
+	body := f.joinItems(items[special], items[special+1:], sxpretty.Line())
+	docs = append(docs, sxpretty.Nest(rule.Indent, sxpretty.Concat(body, f.endDoc(n))), docClose)
+	return sxpretty.Group(sxpretty.Align(sxpretty.Concat(docs...)))
+}
+
+// joinItems returns the document of all items, each preceded by a separator.
+func (f *formatter) joinItems(prev *item, items []*item, sep sxpretty.Doc) sxpretty.Doc {
+	docs := make([]sxpretty.Doc, 0, 2*len(items))
+	for _, it := range items {
+		if prev != nil {
+			docs = append(docs, separator(prev, it, sep))
+		}
+		docs = append(docs, f.itemDoc(it))
+		prev = it
+	}
+	return sxpretty.Concat(docs...)
+}
+
+func (f *formatter) openCommentDoc(n *node) sxpretty.Doc {
+	if c := n.openComment; c != nil {
+		return sxpretty.Concat(docSpace, sxpretty.Text(c.text), sxpretty.HardLine())
+	}
+	return sxpretty.Concat()
+}
+
+// endDoc returns the document of the comments after the last element of a
+// list. If there is a comment at the end, the closing parenthesis is placed
+// on a new line.
+func (f *formatter) endDoc(n *node) sxpretty.Doc {
+	docs := endCommentDocs(true, n.endComments)
+	if len(n.children) > 0 && n.children[len(n.children)-1].trailing != nil || len(n.endComments) > 0 {
+		docs = append(docs, sxpretty.HardLine())
+	}
+	return sxpretty.Concat(docs...)
+}
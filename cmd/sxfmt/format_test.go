@@ -0,0 +1,251 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxreader"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		name  string
+		src   string
+		width int
+		exp   string
+	}{
+		{name: "empty", src: "", exp: ""},
+		{name: "space", src: " \n\t\n", exp: ""},
+		{name: "atoms", src: "  a   b\n\n\n\nc", exp: "a\nb\n\nc\n"},
+		{name: "list", src: "( a  b\n c )", exp: "(a b c)\n"},
+		{name: "nil", src: "( )", exp: "()\n"},
+		{name: "quote", src: "' a `( b , c ,@ d)", exp: "'a\n`(b ,c ,@d)\n"},
+		{name: "string", src: `(f "a  b" #r"x ( y" #i"v ${ (g  x) }")`, exp: `(f "a  b" #r"x ( y" #i"v ${ (g  x) }")` + "\n"},
+		{name: "symbol", src: "(f |a b| c)", exp: "(f |a b| c)\n"},
+		{name: "defun", src: "(defun fac (n) (if (= n 0) 1 (* n (fac (- n 1)))))", width: 30,
+			exp: "(defun fac (n)\n  (if (= n 0)\n      1\n      (* n (fac (- n 1)))))\n"},
+		{name: "let", src: "(let ((x 1)\n (y 2))\n (+ x y))", exp: "(let ((x 1) (y 2)) (+ x y))\n"},
+		{name: "call", src: "(list alpha beta gamma delta)", width: 20,
+			exp: "(list alpha\n      beta\n      gamma\n      delta)\n"},
+		{name: "data", src: "((a b) c d)", width: 5, exp: "((a b)\n c\n d)\n"},
+		{name: "top-comments", src: ";; head\n\n\n;; more\n(a)\n;; tail\n",
+			exp: ";; head\n\n;; more\n(a)\n;; tail\n"},
+		{name: "trailing-comment", src: "(defvar x 1)    ; a comment   \n(defvar y 2)",
+			exp: "(defvar x 1) ; a comment\n(defvar y 2)\n"},
+		{name: "long-trailing-comment", src: "(a b) ; " + strings.Repeat("x", 80),
+			exp: "(a b) ; " + strings.Repeat("x", 80) + "\n"},
+		{name: "inner-trailing-comment", src: "(f a ; first\n b)",
+			exp: "(f a ; first\n   b)\n"},
+		{name: "last-trailing-comment", src: "(f a b ; last\n)",
+			exp: "(f a\n   b ; last\n   )\n"},
+		{name: "leading-comment", src: "(cond\n;; first\n(a b)\n\n  ;; second\n  (c d))",
+			exp: "(cond\n ;; first\n (a b)\n\n ;; second\n (c d))\n"},
+		{name: "rule-header-comment", src: "(defun f (x) ; doc\n(g x))",
+			exp: "(defun f (x) ; doc\n  (g x))\n"},
+		{name: "rule-header-comment-1", src: "(let ((a 1)) ; c\n a)",
+			exp: "(let ((a 1)) ; c\n  a)\n"},
+		{name: "rule-comment", src: "(defun f (x)\n;; body\n  x)",
+			exp: "(defun f (x)\n  ;; body\n  x)\n"},
+		{name: "open-comment", src: "( ; open\n a b)", exp: "( ; open\n a\n b)\n"},
+		{name: "end-comment", src: "(a b\n ;; end\n )", exp: "(a b\n   ;; end\n   )\n"},
+		{name: "only-comment", src: "( ;; nothing\n)", exp: "( ;; nothing\n)\n"},
+		{name: "quote-comment", src: "'\n;; c\nx", exp: "';; c\nx\n"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := makeFormatter()
+			if tc.width > 0 {
+				f.width = tc.width
+			}
+			got, err := f.format(tc.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.exp {
+				t.Errorf("expected:\n%s\nbut got:\n%s", tc.exp, got)
+			}
+			again, err := f.format(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != got {
+				t.Errorf("not idempotent, expected:\n%s\nbut got:\n%s", got, again)
+			}
+		})
+	}
+}
+
+func TestFormatRule(t *testing.T) {
+	t.Parallel()
+	f := makeFormatter()
+	f.width = 12
+	if err := ruleFlag(f.rules).Set("my-form=1,1"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.format("(my-form a b c)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "(my-form a\n b\n c)\n"; got != exp {
+		t.Errorf("expected:\n%s\nbut got:\n%s", exp, got)
+	}
+
+	for _, s := range []string{"", "x", "x=1", "x=a,1", "x=1,-1", "=1,1"} {
+		if err = ruleFlag(f.rules).Set(s); err == nil {
+			t.Errorf("error expected for rule %q", s)
+		}
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		src string
+		exp string
+	}{
+		{"(a b", "line 1: unexpected EOF, list not closed"},
+		{"a)", "line 1: unmatched delimiter ')'"},
+		{"\n\n[a]", "line 3: '[' is reserved"},
+		{`"abc`, "line 1: unexpected EOF"},
+	}
+	for _, tc := range testcases {
+		_, err := makeFormatter().format(tc.src)
+		if err == nil {
+			t.Errorf("%q: error expected", tc.src)
+			continue
+		}
+		if got := err.Error(); got != tc.exp {
+			t.Errorf("%q: expected error %q, but got %q", tc.src, tc.exp, got)
+		}
+	}
+}
+
+// TestFormatFiles checks that formatting the sx files of this repository is
+// idempotent and does not change their meaning.
+func TestFormatFiles(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{
+		"../../sxbuiltins/prelude.sxn",
+		"../../sxbuiltins/sxbuiltins_test.sxn",
+		"../../sxeval/tests.sxn",
+	} {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := makeFormatter()
+		got, err := f.format(string(src))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if again, _ := f.format(got); again != got {
+			t.Errorf("%s: formatting is not idempotent", name)
+		}
+		if exp, res := readAll(t, string(src)), readAll(t, got); exp != res {
+			t.Errorf("%s: meaning changed, expected:\n%s\nbut got:\n%s", name, exp, res)
+		}
+	}
+}
+
+func readAll(t *testing.T, src string) string {
+	objs, err := sxreader.MakeReader(strings.NewReader(src)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sx.MakeList(objs...).String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+	if got := unifiedDiff("f", "a\nb\n", "a\nb\n"); got != "" {
+		t.Errorf("no diff expected, but got:\n%s", got)
+	}
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	new := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n"
+	exp := "--- f.orig\n+++ f\n" +
+		"@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n" +
+		"@@ -9,4 +9,3 @@\n 9\n 10\n 11\n-12\n"
+	if got := unifiedDiff("f", old, new); got != exp {
+		t.Errorf("expected:\n%s\nbut got:\n%s", exp, got)
+	}
+	if got, exp := unifiedDiff("f", "", "a\n"), "--- f.orig\n+++ f\n@@ -0,0 +1 @@\n+a\n"; got != exp {
+		t.Errorf("expected:\n%s\nbut got:\n%s", exp, got)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		a, b string
+		lcs  int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 3},
+		{"abc", "", 0},
+		{"", "abc", 0},
+		{"abcabba", "cbabac", 4},
+		{"xaxbxcx", "abc", 3},
+		{"abcdefgh", "hgfedcba", 1},
+		{"aaaabbbb", "bbbbaaaa", 4},
+		{"abxcdyefz", "abcdef", 6},
+	}
+	for _, tc := range testcases {
+		a, b := strings.Split(tc.a, ""), strings.Split(tc.b, "")
+		ops := diffLines(a, b)
+		var gotA, gotB []string
+		common := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind == ' ' {
+				common++
+			}
+		}
+		if strings.Join(gotA, "") != tc.a || strings.Join(gotB, "") != tc.b {
+			t.Errorf("%q/%q: diff does not reproduce the input: %v", tc.a, tc.b, ops)
+		}
+		if common != tc.lcs {
+			t.Errorf("%q/%q: %d unchanged lines expected, but got %d", tc.a, tc.b, tc.lcs, common)
+		}
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	t.Parallel()
+	const n = 100000
+	var sbOld, sbNew strings.Builder
+	for i := range n {
+		line := strconv.Itoa(i) + "\n"
+		sbOld.WriteString(line)
+		if i == n/2 {
+			sbNew.WriteString("changed\n")
+		} else {
+			sbNew.WriteString(line)
+		}
+	}
+	exp := "--- f.orig\n+++ f\n@@ -49998,7 +49998,7 @@\n 49997\n 49998\n 49999\n-50000\n+changed\n 50001\n 50002\n 50003\n"
+	if got := unifiedDiff("f", sbOld.String(), sbNew.String()); got != exp {
+		t.Errorf("expected:\n%s\nbut got:\n%s", exp, got)
+	}
+}
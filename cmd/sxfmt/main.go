@@ -0,0 +1,172 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+// Package main provides sxfmt, a formatter for sx source files.
+//
+// Usage:
+//
+//	sxfmt [flags] [path ...]
+//
+// Without a path, sxfmt formats its standard input. A directory is searched
+// recursively for files with the extension ".sxn". The flags are:
+//
+//	-l          list files whose formatting differs from sxfmt's
+//	-w          write result to (source) file instead of stdout
+//	-d          display diffs instead of rewriting files
+//	-width N    maximum line width (default 80)
+//	-r RULE     add a layout rule "name=special,indent", e.g. "defun=2,2"
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"t73f.de/r/sx/sxpretty"
+)
+
+// fileExt is the file extension of sx source files.
+const fileExt = ".sxn"
+
+var (
+	list  = flag.Bool("l", false, "list files whose formatting differs from sxfmt's")
+	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+	width = flag.Int("width", sxpretty.DefaultWidth, "maximum line width")
+)
+
+// ruleFlag collects layout rules, given via the command line.
+type ruleFlag map[string]sxpretty.Rule
+
+func (rf ruleFlag) String() string { return "" }
+
+func (rf ruleFlag) Set(s string) error {
+	name, rest, found := strings.Cut(s, "=")
+	if !found || name == "" {
+		return errors.New("rule must be given as name=special,indent")
+	}
+	sSpecial, sIndent, found := strings.Cut(rest, ",")
+	if !found {
+		return errors.New("rule must be given as name=special,indent")
+	}
+	special, err := strconv.Atoi(sSpecial)
+	if err != nil || special < 0 {
+		return fmt.Errorf("invalid number of special arguments: %q", sSpecial)
+	}
+	indent, err := strconv.Atoi(sIndent)
+	if err != nil || indent < 0 {
+		return fmt.Errorf("invalid indentation: %q", sIndent)
+	}
+	rf[name] = sxpretty.Rule{Special: special, Indent: indent}
+	return nil
+}
+
+func main() {
+	f := makeFormatter()
+	flag.Var(ruleFlag(f.rules), "r", "add a layout rule `name=special,indent`")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: sxfmt [flags] [path ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	f.width = *width
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "sxfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := f.processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	exitCode := 0
+	for _, arg := range flag.Args() {
+		err := filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// A file given on the command line is always formatted.
+			if d.IsDir() || (path != arg && filepath.Ext(path) != fileExt) {
+				return nil
+			}
+			return f.processPath(path)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exitCode = 2
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func (f *formatter) processPath(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+	return f.processFile(path, file, os.Stdout)
+}
+
+// processFile formats the content of the reader and reports the result,
+// according to the given flags.
+func (f *formatter) processFile(name string, r io.Reader, out io.Writer) error {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	res, err := f.format(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if bytes.Equal(src, []byte(res)) {
+		if !*list && !*write && !*diff {
+			_, err = io.WriteString(out, res)
+		}
+		return err
+	}
+	if *list {
+		if _, err = fmt.Fprintln(out, name); err != nil {
+			return err
+		}
+	}
+	if *write {
+		info, errStat := os.Stat(name)
+		if errStat != nil {
+			return errStat
+		}
+		if err = os.WriteFile(name, []byte(res), info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *diff {
+		if _, err = io.WriteString(out, unifiedDiff(name, string(src), res)); err != nil {
+			return err
+		}
+	}
+	if !*list && !*write && !*diff {
+		_, err = io.WriteString(out, res)
+	}
+	return err
+}
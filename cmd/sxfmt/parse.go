@@ -0,0 +1,365 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package main
+
+// Provides a parser that retains comments and blank lines.
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// node is an element of the concrete syntax tree of a source.
+type node struct {
+	kind     nodeKind
+	text     string  // atom text, prefix text, comment text
+	children []*item // list elements, or the prefixed element
+
+	openComment *comment   // comment on the same line as the opening parenthesis
+	endComments []*comment // comments after the last element of a list
+}
+
+type nodeKind uint8
+
+const (
+	nodeAtom nodeKind = iota
+	nodeList
+	nodePrefix
+)
+
+// item is a node with its surrounding comments.
+type item struct {
+	blank    bool       // blank line before the node, after the leading comments
+	leading  []*comment // comments on separate lines before the node
+	node     *node
+	trailing *comment // comment on the same line after the node
+}
+
+// comment is a line comment.
+type comment struct {
+	blank bool // blank line before the comment
+	text  string
+}
+
+// parser parses a source into a sequence of items.
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+type parseError struct {
+	line int
+	msg  string
+}
+
+func (e *parseError) Error() string { return fmt.Sprintf("line %d: %s", e.line, e.msg) }
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &parseError{line: p.line + 1, msg: fmt.Sprintf(format, args...)}
+}
+
+// parseFile parses the whole source and returns all top-level items, as well
+// as the comments at the end of the source.
+func parseFile(src string) ([]*item, []*comment, error) {
+	p := parser{src: src}
+	items, comments, endCh, err := p.parseItems()
+	if err != nil {
+		return nil, nil, err
+	}
+	if endCh != 0 {
+		return nil, nil, p.errorf("unmatched delimiter '%c'", endCh)
+	}
+	return items, comments, nil
+}
+
+func (p *parser) peek() (rune, int) {
+	if p.pos >= len(p.src) {
+		return 0, 0
+	}
+	return utf8.DecodeRuneInString(p.src[p.pos:])
+}
+
+// skipSpace skips all space characters and returns the number of new lines.
+func (p *parser) skipSpace() int {
+	newlines := 0
+	for {
+		ch, size := p.peek()
+		if size == 0 || !isSpace(ch) {
+			return newlines
+		}
+		if ch == '\n' {
+			newlines++
+			p.line++
+		}
+		p.pos += size
+	}
+}
+
+func isSpace(ch rune) bool { return (ch <= ' ' && ch >= 0) || unicode.IsSpace(ch) }
+
+// parseItems parses items until EOF or a closing delimiter. It returns the
+// items, the comments after the last item, and the closing delimiter. The
+// closing delimiter itself is not consumed.
+func (p *parser) parseItems() ([]*item, []*comment, rune, error) {
+	var items []*item
+	var pending []*comment
+	var last *item
+	atStart := true
+	for {
+		newlines := p.skipSpace()
+		blank := newlines > 1 && !atStart
+		atStart = false
+		ch, size := p.peek()
+		if size == 0 {
+			return items, pending, 0, nil
+		}
+		if ch == ')' || ch == '}' {
+			return items, pending, ch, nil
+		}
+		if ch == ';' {
+			text := p.parseComment()
+			if newlines == 0 && last != nil && last.trailing == nil && len(pending) == 0 {
+				last.trailing = &comment{text: text}
+			} else {
+				pending = append(pending, &comment{blank: blank, text: text})
+			}
+			continue
+		}
+		n, err := p.parseNode()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		last = &item{blank: blank, leading: pending, node: n}
+		items = append(items, last)
+		pending = nil
+	}
+}
+
+func (p *parser) parseComment() string {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+		p.pos++
+	}
+	end := p.pos
+	for end > start && (p.src[end-1] == ' ' || p.src[end-1] == '\t' || p.src[end-1] == '\r') {
+		end--
+	}
+	return p.src[start:end]
+}
+
+// parseNode parses a single node, without surrounding comments.
+func (p *parser) parseNode() (*node, error) {
+	ch, size := p.peek()
+	switch ch {
+	case '(':
+		p.pos += size
+		return p.parseList()
+	case '[', ']', '{', '}':
+		return nil, p.errorf("'%c' is reserved", ch)
+	case '\'', '`', ',':
+		start := p.pos
+		p.pos += size
+		if ch == ',' && p.pos < len(p.src) && p.src[p.pos] == '@' {
+			p.pos++
+		}
+		prefix := p.src[start:p.pos]
+		items, err := p.parsePrefixed()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodePrefix, text: prefix, children: items}, nil
+	}
+	start := p.pos
+	if err := p.skipAtom(); err != nil {
+		return nil, err
+	}
+	return &node{kind: nodeAtom, text: p.src[start:p.pos]}, nil
+}
+
+// parsePrefixed parses the element after a quotation prefix. Comments
+// between the prefix and the element are retained as leading comments.
+func (p *parser) parsePrefixed() ([]*item, error) {
+	var leading []*comment
+	for {
+		p.skipSpace()
+		ch, size := p.peek()
+		if size == 0 {
+			return nil, p.errorf("unexpected EOF")
+		}
+		if ch != ';' {
+			break
+		}
+		leading = append(leading, &comment{text: p.parseComment()})
+	}
+	n, err := p.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	return []*item{{leading: leading, node: n}}, nil
+}
+
+func (p *parser) parseList() (*node, error) {
+	startLine := p.line
+	var openComment *comment
+	save := p.pos
+	if p.skipSpace() == 0 {
+		if ch, _ := p.peek(); ch == ';' {
+			openComment = &comment{text: p.parseComment()}
+		} else {
+			p.pos = save
+		}
+	} else {
+		p.pos, p.line = save, startLine
+	}
+	items, comments, endCh, err := p.parseItems()
+	if err != nil {
+		return nil, err
+	}
+	if endCh != ')' {
+		if endCh == 0 {
+			return nil, &parseError{line: startLine + 1, msg: "unexpected EOF, list not closed"}
+		}
+		return nil, p.errorf("'%c' is reserved", endCh)
+	}
+	p.pos++
+	return &node{kind: nodeList, children: items, openComment: openComment, endComments: comments}, nil
+}
+
+// skipAtom skips an atom, e.g. a symbol, a number, or a string. The text of
+// an atom is retained as it is, therefore an atom might contain more than one
+// object of the reader, e.g. "a'b". This is harmless, because the formatter
+// never separates them.
+func (p *parser) skipAtom() error {
+	start := p.pos
+	for {
+		ch, size := p.peek()
+		if size == 0 || isSpace(ch) || isAtomEnd(ch) {
+			break
+		}
+		var err error
+		switch ch {
+		case '"', '|':
+			p.pos += size
+			err = p.skipQuoted(byte(ch))
+		case '#':
+			p.pos += size
+			if p.pos < len(p.src) {
+				switch p.src[p.pos] {
+				case 'r':
+					p.pos++
+					err = p.skipRawString()
+				case 'i':
+					if p.pos+1 < len(p.src) && p.src[p.pos+1] == '"' {
+						p.pos += 2
+						err = p.skipInterpolated()
+					}
+				}
+			}
+		default:
+			p.pos += size
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if p.pos == start {
+		ch, _ := p.peek()
+		return p.errorf("unexpected character '%c'", ch)
+	}
+	return nil
+}
+
+// isAtomEnd returns true for all characters that end an atom.
+func isAtomEnd(ch rune) bool {
+	switch ch {
+	case '(', ')', '[', ']', '{', '}', ';':
+		return true
+	}
+	return false
+}
+
+// skipQuoted skips a string or a quoted symbol, with escape sequences.
+func (p *parser) skipQuoted(quoteCh byte) error {
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		p.pos++
+		switch ch {
+		case '\\':
+			p.pos++
+		case '\n':
+			p.line++
+		case quoteCh:
+			return nil
+		}
+	}
+	return p.errorf("unexpected EOF")
+}
+
+// skipRawString skips a raw string, after the "#r" prefix.
+func (p *parser) skipRawString() error {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != '"' {
+		if isSpace(rune(p.src[p.pos])) {
+			return p.errorf("raw string delimiter must not contain space")
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return p.errorf("unexpected EOF")
+	}
+	end := `"` + p.src[start:p.pos]
+	p.pos++
+	for p.pos < len(p.src) {
+		if p.src[p.pos] == '"' && len(p.src)-p.pos >= len(end) && p.src[p.pos:p.pos+len(end)] == end {
+			p.pos += len(end)
+			return nil
+		}
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+	return p.errorf("unexpected EOF")
+}
+
+// skipInterpolated skips an interpolating string, after the `#i"` prefix.
+func (p *parser) skipInterpolated() error {
+	for p.pos < len(p.src) {
+		ch := p.src[p.pos]
+		p.pos++
+		switch ch {
+		case '\\':
+			p.pos++
+		case '\n':
+			p.line++
+		case '"':
+			return nil
+		case '$':
+			if p.pos < len(p.src) && p.src[p.pos] == '{' {
+				p.pos++
+				_, _, endCh, err := p.parseItems()
+				if err != nil {
+					return err
+				}
+				if endCh != '}' {
+					return p.errorf("'}' expected")
+				}
+				p.pos++
+			}
+		}
+	}
+	return p.errorf("unexpected EOF")
+}
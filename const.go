@@ -22,3 +22,17 @@ var (
 	SymbolUnquote         = initPackage.MakeSymbol("unquote")
 	SymbolUnquoteSplicing = initPackage.MakeSymbol("unquote-splicing")
 )
+
+// Names of symbols used to expand interpolating strings.
+//
+// Used in package sxreader.
+var (
+	SymbolConcat   = initPackage.MakeSymbol("concat")
+	SymbolToString = initPackage.MakeSymbol("->string")
+)
+
+func init() {
+	_ = initPackage.Export(
+		SymbolQuote, SymbolQuasiquote, SymbolUnquote, SymbolUnquoteSplicing,
+		SymbolConcat, SymbolToString)
+}
@@ -16,6 +16,7 @@ package sx
 import (
 	"errors"
 	"strconv"
+	"strings"
 )
 
 // Number value store numbers.
@@ -27,14 +28,46 @@ type Number interface {
 }
 
 // ParseInteger parses the string as an integer value and returns its value as a number.
+//
+// The string may start with a sign, followed by an optional radix prefix: "0x"
+// for hexadecimal, "0o" for octal, and "0b" for binary values. Digits may be
+// grouped by single underscore characters, e.g. "1_000_000" or "0xFF_FF".
 func ParseInteger(s string) (Number, error) {
-	i64, err := strconv.ParseInt(s, 10, 64)
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return nil, &strconv.NumError{Func: "ParseInteger", Num: s, Err: strconv.ErrSyntax}
+	}
+	base := 10
+	if len(digits) > 1 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X', 'o', 'O', 'b', 'B':
+			base = 0
+		}
+	}
+	if base == 10 && strings.IndexByte(digits, '_') >= 0 {
+		if !validUnderscores(digits) {
+			return nil, &strconv.NumError{Func: "ParseInteger", Num: s, Err: strconv.ErrSyntax}
+		}
+		s = strings.ReplaceAll(s, "_", "")
+	}
+	i64, err := strconv.ParseInt(s, base, 64)
 	if err != nil {
 		return nil, err
 	}
 	return Int64(i64), err
 }
 
+// validUnderscores returns true, if all underscore characters separate two
+// decimal digits.
+func validUnderscores(digits string) bool {
+	for i := range len(digits) {
+		if digits[i] == '_' && (i == 0 || i == len(digits)-1 || digits[i-1] == '_' || digits[i+1] == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 // Int64 is a number that store 64 bit integer values.
 type Int64 int64
 
@@ -64,6 +97,25 @@ func (i Int64) String() string { return strconv.FormatInt(int64(i), 10) }
 // GoString returns the Go string representation.
 func (i Int64) GoString() string { return i.String() }
 
+// FormatRadix returns the string representation of the integer value, in
+// the given radix. The result can be read back, e.g. "#x-ff" for the value
+// -255 and radix 16. Supported radices are 2, 8, 10, and 16. All other radices
+// result in a decimal representation.
+func (i Int64) FormatRadix(radix int) string {
+	var prefix string
+	switch radix {
+	case 2:
+		prefix = "#b"
+	case 8:
+		prefix = "#o"
+	case 16:
+		prefix = "#x"
+	default:
+		return i.String()
+	}
+	return prefix + strconv.FormatInt(int64(i), radix)
+}
+
 // GetNumber returns the object as a number, if possible.
 func GetNumber(obj Object) (Number, bool) {
 	if IsNil(obj) {
@@ -34,3 +34,66 @@ func TestGetNumber(t *testing.T) {
 		t.Error("Different numbers, exptected:", o, "but got:", res)
 	}
 }
+
+func TestParseInteger(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		src     string
+		exp     sx.Int64
+		mustErr bool
+	}{
+		{"0", 0, false},
+		{"-17", -17, false},
+		{"+17", 17, false},
+		{"017", 17, false},
+		{"1_000_000", 1000000, false},
+		{"0xFF", 255, false},
+		{"-0x1f", -31, false},
+		{"0o17", 15, false},
+		{"0b1010", 10, false},
+		{"0b_1010_1010", 170, false},
+		{"1__0", 0, true},
+		{"_1", 0, true},
+		{"1_", 0, true},
+		{"+-1", 0, true},
+		{"0xG", 0, true},
+		{"0b2", 0, true},
+		{"12a", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+	for _, tc := range testcases {
+		num, err := sx.ParseInteger(tc.src)
+		if err != nil {
+			if !tc.mustErr {
+				t.Errorf("%q: unexpected error: %v", tc.src, err)
+			}
+			continue
+		}
+		if tc.mustErr {
+			t.Errorf("%q: error expected, but got %v", tc.src, num)
+		} else if !num.IsEqual(tc.exp) {
+			t.Errorf("%q: expected %v, but got %v", tc.src, tc.exp, num)
+		}
+	}
+}
+
+func TestFormatRadix(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		num   sx.Int64
+		radix int
+		exp   string
+	}{
+		{255, 10, "255"},
+		{255, 16, "#xff"},
+		{-255, 16, "#x-ff"},
+		{8, 8, "#o10"},
+		{5, 2, "#b101"},
+		{17, 3, "17"},
+	}
+	for _, tc := range testcases {
+		if got := tc.num.FormatRadix(tc.radix); got != tc.exp {
+			t.Errorf("%d/%d: expected %q, but got %q", tc.num, tc.radix, tc.exp, got)
+		}
+	}
+}
@@ -15,16 +15,44 @@ package sx
 
 import (
 	"fmt"
+	"io"
 	"iter"
 	"regexp"
+	"slices"
 	"sync"
 )
 
 // Package maps symbol names to Symbols.
+//
+// A symbol is present in a package, if it was created by the package or if
+// it was imported into it. A present symbol may be exported, i.e. it is an
+// external symbol of the package. Other symbols of the package are internal.
+// A package may use other packages. Then all external symbols of the used
+// packages are accessible in the package too, unless a present symbol with the
+// same name shadows them.
 type Package struct {
-	name    string
-	mx      sync.RWMutex
-	symbols map[string]*Symbol
+	name      string
+	mx        sync.RWMutex
+	symbols   map[string]*Symbol
+	external  map[string]bool
+	shadowing map[string]bool
+	uses      []*Package
+	usedBy    []*Package
+}
+
+// changeMx serializes all changes of packages, so that a check for conflicting
+// symbols and the following change are not interleaved with another change.
+// It must be acquired before the lock of any package. While it is held, at
+// most one package lock is held at any time.
+var changeMx sync.Mutex
+
+func newPackage(name string) *Package {
+	return &Package{
+		name:      name,
+		symbols:   map[string]*Symbol{},
+		external:  map[string]bool{},
+		shadowing: map[string]bool{},
+	}
 }
 
 // IsNil may return true if a symbol pointer is nil.
@@ -83,37 +111,218 @@ func (pkg *Package) MakeSymbol(name string) *Symbol {
 	if name == "" {
 		return nil
 	}
+	changeMx.Lock()
+	defer changeMx.Unlock()
+	if sym = pkg.FindSymbol(name); sym != nil {
+		return sym
+	}
 	pkg.mx.Lock()
+	sym = pkg.internSymbol(name)
+	pkg.mx.Unlock()
+	return sym
+}
+
+// internSymbol returns the symbol with the given name, that is present in the
+// package. If there is no such symbol, it is created. The package must be
+// locked for writing.
+func (pkg *Package) internSymbol(name string) *Symbol {
 	sym, found := pkg.symbols[name]
 	if !found {
 		sym = &Symbol{pkg: pkg, name: name}
+		if pkg == keywordPackage {
+			// Keywords evaluate to themself
+			sym.value.Store(&symbolValue{obj: sym, frozen: true})
+		}
 		pkg.symbols[name] = sym
 	}
-	pkg.mx.Unlock()
-
-	// Keywords evaluate to themself
-	if pkg == keywordPackage {
-		sym.bound = sym
-		sym.frozen = true
-	}
 	return sym
 }
 
-// FindSymbol returns the symbol with the given name.
+// FindSymbol returns the symbol with the given name, that is accessible in
+// the package. It is either present in the package, or an external symbol of
+// a used package.
 func (pkg *Package) FindSymbol(name string) *Symbol {
 	if name == "" {
 		return nil
 	}
 	pkg.mx.RLock()
 	sym, found := pkg.symbols[name]
+	uses := pkg.uses
 	pkg.mx.RUnlock()
 	if found {
 		return sym
 	}
+	for _, used := range uses {
+		if sym = used.FindExternalSymbol(name); sym != nil {
+			return sym
+		}
+	}
+	return nil
+}
+
+// FindExternalSymbol returns the external symbol of the package with the
+// given name.
+func (pkg *Package) FindExternalSymbol(name string) *Symbol {
+	pkg.mx.RLock()
+	sym, found := pkg.symbols[name]
+	external := pkg.external[name] || pkg == keywordPackage
+	pkg.mx.RUnlock()
+	if found && external {
+		return sym
+	}
 	return nil
 }
 
-// AllSymbols returns an iterator of all symbols currently manages by the package.
+// IsExternal returns true, if the given symbol is an external symbol of the
+// package.
+func (pkg *Package) IsExternal(sym *Symbol) bool {
+	return sym != nil && pkg.FindExternalSymbol(sym.name) == sym
+}
+
+// Export makes the given symbols external symbols of the package. Each symbol
+// must be accessible in the package. If it is not present, it will be
+// imported. It is an error, if a package that uses this package would see
+// a conflicting symbol with the same name. If there is an error, no symbol is
+// exported.
+func (pkg *Package) Export(syms ...*Symbol) error {
+	changeMx.Lock()
+	defer changeMx.Unlock()
+	pkg.mx.RLock()
+	usedBy := pkg.usedBy
+	pkg.mx.RUnlock()
+	for _, sym := range syms {
+		if sym == nil || pkg.FindSymbol(sym.name) != sym {
+			return fmt.Errorf("symbol %v is not accessible in %v", sym, pkg)
+		}
+		for _, user := range usedBy {
+			if other := user.FindSymbol(sym.name); other != nil && other != sym && !user.isShadowing(sym.name) {
+				return fmt.Errorf("export of %v conflicts with %v in %v", sym, other, user)
+			}
+		}
+	}
+	pkg.mx.Lock()
+	for _, sym := range syms {
+		pkg.symbols[sym.name] = sym
+		pkg.external[sym.name] = true
+	}
+	pkg.mx.Unlock()
+	return nil
+}
+
+// Import makes the given symbols present in the package. It is an error, if
+// a different symbol with the same name is already accessible in the package.
+// If there is an error, no symbol is imported.
+func (pkg *Package) Import(syms ...*Symbol) error {
+	changeMx.Lock()
+	defer changeMx.Unlock()
+	imported := make(map[string]*Symbol, len(syms))
+	for _, sym := range syms {
+		if sym == nil {
+			return fmt.Errorf("cannot import nil symbol into %v", pkg)
+		}
+		other := imported[sym.name]
+		if other == nil {
+			other = pkg.FindSymbol(sym.name)
+		}
+		if other != nil && other != sym {
+			return fmt.Errorf("import of %v conflicts with %v in %v", sym, other, pkg)
+		}
+		imported[sym.name] = sym
+	}
+	pkg.mx.Lock()
+	for _, sym := range syms {
+		pkg.symbols[sym.name] = sym
+	}
+	pkg.mx.Unlock()
+	return nil
+}
+
+// Shadow ensures that symbols with the given names are present in the
+// package. They shadow all external symbols of used packages with the same
+// name.
+func (pkg *Package) Shadow(names ...string) {
+	changeMx.Lock()
+	defer changeMx.Unlock()
+	pkg.mx.Lock()
+	defer pkg.mx.Unlock()
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		pkg.internSymbol(name)
+		pkg.shadowing[name] = true
+	}
+}
+
+func (pkg *Package) isShadowing(name string) bool {
+	pkg.mx.RLock()
+	result := pkg.shadowing[name]
+	pkg.mx.RUnlock()
+	return result
+}
+
+// UsePackage adds the given packages to the packages used by this package.
+// All their external symbols are then accessible in this package. It is an
+// error, if an external symbol conflicts with an accessible symbol of the
+// same name, except the latter is shadowing. If there is an error, no
+// package is used.
+func (pkg *Package) UsePackage(others ...*Package) error {
+	changeMx.Lock()
+	defer changeMx.Unlock()
+	uses := pkg.Uses()
+	var newUses []*Package
+	accessible := map[string]*Symbol{}
+	for _, other := range others {
+		if other == nil || other == keywordPackage {
+			return fmt.Errorf("package %v cannot be used", other)
+		}
+		if other == pkg || slices.Contains(uses, other) || slices.Contains(newUses, other) {
+			continue
+		}
+		other.mx.RLock()
+		var externals []*Symbol
+		for name := range other.external {
+			externals = append(externals, other.symbols[name])
+		}
+		other.mx.RUnlock()
+		for _, sym := range externals {
+			if pkg.isShadowing(sym.name) {
+				continue
+			}
+			acc := accessible[sym.name]
+			if acc == nil {
+				acc = pkg.FindSymbol(sym.name)
+			}
+			if acc != nil && acc != sym {
+				return fmt.Errorf("use of %v conflicts with %v in %v", other, acc, pkg)
+			}
+			accessible[sym.name] = sym
+		}
+		newUses = append(newUses, other)
+	}
+	if len(newUses) == 0 {
+		return nil
+	}
+	pkg.mx.Lock()
+	pkg.uses = append(slices.Clip(pkg.uses), newUses...)
+	pkg.mx.Unlock()
+	for _, other := range newUses {
+		other.mx.Lock()
+		other.usedBy = append(slices.Clip(other.usedBy), pkg)
+		other.mx.Unlock()
+	}
+	return nil
+}
+
+// Uses returns the packages used by this package.
+func (pkg *Package) Uses() []*Package {
+	pkg.mx.RLock()
+	result := slices.Clone(pkg.uses)
+	pkg.mx.RUnlock()
+	return result
+}
+
+// AllSymbols returns an iterator of all symbols currently present in the package.
 func (pkg *Package) AllSymbols() iter.Seq[*Symbol] {
 	return func(yield func(*Symbol) bool) {
 		pkg.mx.RLock()
@@ -134,14 +343,53 @@ func (pkg *Package) Size() int {
 	return result
 }
 
-// ----- Package package ----------------------------------------------------
+// ----- World --------------------------------------------------------------
+
+// World is a registry of packages, together with a current package. Each
+// interpreter may use its own world, e.g. to use a different current package.
+// The packages KEYWORD and INIT are shared by all worlds. It is safe to use a
+// world concurrently.
+type World struct {
+	mx       sync.RWMutex
+	packages map[string]*Package
+	current  *Package
+}
+
+// MakeWorld creates a new world, which contains only the predefined packages.
+// The current package is the INIT package.
+func MakeWorld() *World {
+	return &World{
+		packages: map[string]*Package{
+			KeywordName: keywordPackage,
+			InitName:    initPackage,
+		},
+		current: initPackage,
+	}
+}
+
+// DefaultWorld returns the world that is used, if no other world is
+// specified.
+func DefaultWorld() *World { return defaultWorld }
 
-var packageRegistry = map[string]*Package{}
+var defaultWorld = makeDefaultWorld()
+
+func makeDefaultWorld() *World {
+	w := &World{packages: map[string]*Package{}}
+	w.MustMakePackage(KeywordName)
+	w.current = w.MustMakePackage(InitName)
+	return w
+}
 
-// AllPackages returns an iterator of all packages.
-func AllPackages() iter.Seq[*Package] {
+// AllPackages returns an iterator of all packages of the world.
+func (w *World) AllPackages() iter.Seq[*Package] {
+	w.mx.RLock()
+	pkgs := make([]*Package, 0, len(w.packages))
+	for _, pkg := range w.packages {
+		pkgs = append(pkgs, pkg)
+	}
+	w.mx.RUnlock()
 	return func(yield func(*Package) bool) {
-		for _, pkg := range packageRegistry {
+		for _, pkg := range pkgs {
 			if !yield(pkg) {
 				return
 			}
@@ -149,54 +397,110 @@ func AllPackages() iter.Seq[*Package] {
 	}
 }
 
-// FindPackage returns the package with the given name.
-func FindPackage(name string) *Package {
-	if pkg, found := packageRegistry[name]; found {
-		return pkg
-	}
-	return nil
+// FindPackage returns the package of the world with the given name.
+func (w *World) FindPackage(name string) *Package {
+	w.mx.RLock()
+	pkg := w.packages[name]
+	w.mx.RUnlock()
+	return pkg
 }
 
 var validPackageName = regexp.MustCompile("^[A-Za-z][0-9A-Za-z-]*$")
 
-// MakePackage builds a new package.
-func MakePackage(name string) (*Package, error) {
+// MakePackage builds a new package within the world.
+func (w *World) MakePackage(name string) (*Package, error) {
 	if !validPackageName.MatchString(name) {
 		return nil, fmt.Errorf("invalid package name: %q", name)
 	}
-	if pkg := FindPackage(name); pkg != nil {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if _, found := w.packages[name]; found {
 		return nil, fmt.Errorf("package %q already made", name)
 	}
-	pkg := &Package{
-		name:    name,
-		symbols: map[string]*Symbol{},
-	}
-	packageRegistry[name] = pkg
+	pkg := newPackage(name)
+	w.packages[name] = pkg
 	return pkg, nil
 }
 
-// MustMakePackage builds a new package and panics if something went wrong.
-func MustMakePackage(name string) *Package {
-	pkg, err := MakePackage(name)
+// MustMakePackage builds a new package within the world and panics if
+// something went wrong.
+func (w *World) MustMakePackage(name string) *Package {
+	pkg, err := w.MakePackage(name)
 	if err == nil {
 		return pkg
 	}
 	panic(err)
 }
 
+// CurrentPackage returns the current package of the world.
+func (w *World) CurrentPackage() *Package {
+	w.mx.RLock()
+	pkg := w.current
+	w.mx.RUnlock()
+	return pkg
+}
+
+// SetCurrentPackage sets the current package of the world. The package must
+// belong to the world.
+func (w *World) SetCurrentPackage(pkg *Package) error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if pkg == nil || w.packages[pkg.name] != pkg {
+		return fmt.Errorf("package %v does not belong to world", pkg)
+	}
+	w.current = pkg
+	return nil
+}
+
+// MakeSymbol creates a symbol within the current package of the world.
+func (w *World) MakeSymbol(name string) *Symbol { return w.CurrentPackage().MakeSymbol(name) }
+
+// WorldWriter is a writer that specifies the world to be used when objects
+// are printed. The package prefix of a symbol is omitted, if its package is
+// the current package of the world.
+type WorldWriter struct {
+	io.Writer
+	World *World
+}
+
+// writerWorld returns the world to be used when printing to the writer.
+func writerWorld(w io.Writer) *World {
+	if ww, isWorldWriter := w.(WorldWriter); isWorldWriter && ww.World != nil {
+		return ww.World
+	}
+	return defaultWorld
+}
+
+// ----- Package package ----------------------------------------------------
+
+// AllPackages returns an iterator of all packages of the default world.
+func AllPackages() iter.Seq[*Package] { return defaultWorld.AllPackages() }
+
+// FindPackage returns the package of the default world with the given name.
+func FindPackage(name string) *Package { return defaultWorld.FindPackage(name) }
+
+// MakePackage builds a new package within the default world.
+func MakePackage(name string) (*Package, error) { return defaultWorld.MakePackage(name) }
+
+// MustMakePackage builds a new package within the default world and panics
+// if something went wrong.
+func MustMakePackage(name string) *Package { return defaultWorld.MustMakePackage(name) }
+
 // Predefined package names.
 const (
 	InitName    = "INIT"
 	KeywordName = "KEYWORD"
 )
 
-var keywordPackage = MustMakePackage(KeywordName)
+var keywordPackage = defaultWorld.FindPackage(KeywordName)
 
 // KeywordPackage return the package that manages keyword symbols.
 func KeywordPackage() *Package { return keywordPackage }
 
-var initPackage = MustMakePackage(InitName)
-var currentPackage = initPackage
+var initPackage = defaultWorld.FindPackage(InitName)
+
+// InitPackage returns the package INIT, which is shared by all worlds.
+func InitPackage() *Package { return initPackage }
 
-// CurrentPackage returns the currently selected package.
-func CurrentPackage() *Package { return currentPackage }
+// CurrentPackage returns the current package of the default world.
+func CurrentPackage() *Package { return defaultWorld.CurrentPackage() }
@@ -14,6 +14,9 @@
 package sx_test
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"t73f.de/r/sx"
@@ -105,3 +108,182 @@ func TestTwoPackages(t *testing.T) {
 	checkPackage(t, pkg1)
 	checkPackage(t, pkg2)
 }
+
+func TestWorld(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	if got := w.FindPackage(sx.KeywordName); got != sx.KeywordPackage() {
+		t.Errorf("keyword package must be shared, but got %v", got)
+	}
+	if got, exp := w.CurrentPackage(), sx.FindPackage(sx.InitName); got != exp {
+		t.Errorf("current package must be %v, but got %v", exp, got)
+	}
+	pkg := w.MustMakePackage("world-only")
+	if got := sx.FindPackage("world-only"); got != nil {
+		t.Errorf("package %v must not be found in default world", got)
+	}
+	if err := sx.DefaultWorld().SetCurrentPackage(pkg); err == nil {
+		t.Error("package of other world must not be current package of default world")
+	}
+	if err := w.SetCurrentPackage(pkg); err != nil {
+		t.Fatal(err)
+	}
+	if got := sx.CurrentPackage(); got == pkg {
+		t.Error("current package of default world must not change")
+	}
+
+	sym := w.MakeSymbol("sym")
+	if got := sym.String(); got != "world-only::sym" {
+		t.Errorf("expected %q, but got %q", "world-only::sym", got)
+	}
+	var sb strings.Builder
+	if _, err := sym.Print(sx.WorldWriter{Writer: &sb, World: w}); err != nil {
+		t.Fatal(err)
+	}
+	if got := sb.String(); got != "sym" {
+		t.Errorf("expected %q, but got %q", "sym", got)
+	}
+}
+
+func TestWorldConcurrent(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	const n = 32
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Go(func() {
+			_, errs[i] = w.MakePackage("pkg")
+			_ = w.FindPackage("pkg")
+			for range w.AllPackages() {
+			}
+		})
+	}
+	wg.Wait()
+	ok := 0
+	for _, err := range errs {
+		if err == nil {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Errorf("package must be made exactly once, but was made %d times", ok)
+	}
+}
+
+func TestPackageExportImport(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	lib, app := w.MustMakePackage("lib"), w.MustMakePackage("app")
+	pub, priv := lib.MakeSymbol("pub"), lib.MakeSymbol("priv")
+	if err := lib.Export(pub); err != nil {
+		t.Fatal(err)
+	}
+	if !lib.IsExternal(pub) || lib.IsExternal(priv) {
+		t.Error("only pub must be external")
+	}
+	if got := lib.FindExternalSymbol("priv"); got != nil {
+		t.Errorf("internal symbol found as external: %v", got)
+	}
+	if err := app.Export(pub); err == nil {
+		t.Error("symbol not accessible in app must not be exported")
+	}
+
+	if err := app.UsePackage(lib); err != nil {
+		t.Fatal(err)
+	}
+	if got := app.FindSymbol("pub"); got != pub {
+		t.Errorf("external symbol %v must be accessible, but got %v", pub, got)
+	}
+	if got := app.FindSymbol("priv"); got != nil {
+		t.Errorf("internal symbol must not be accessible, but got %v", got)
+	}
+	if got := app.MakeSymbol("pub"); got != pub {
+		t.Errorf("MakeSymbol must return accessible symbol %v, but got %v", pub, got)
+	}
+	if err := app.UsePackage(sx.KeywordPackage()); err == nil {
+		t.Error("keyword package must not be used")
+	}
+
+	own := app.MakeSymbol("priv")
+	if err := lib.Export(priv); err == nil {
+		t.Errorf("export of %v must conflict with %v", priv, own)
+	}
+	if err := app.Import(priv); err == nil {
+		t.Errorf("import of %v must conflict with %v", priv, own)
+	}
+
+	other := w.MustMakePackage("other")
+	if err := other.Import(priv); err != nil {
+		t.Fatal(err)
+	}
+	if got := other.FindSymbol("priv"); got != priv {
+		t.Errorf("imported symbol %v expected, but got %v", priv, got)
+	}
+	other.Shadow("pub")
+	if err := other.UsePackage(lib); err != nil {
+		t.Fatal(err)
+	}
+	if got := other.FindSymbol("pub"); got == pub || got.Package() != other {
+		t.Errorf("shadowing symbol expected, but got %v", got)
+	}
+}
+
+func TestPackageChangeAtomic(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	lib, app := w.MustMakePackage("lib"), w.MustMakePackage("app")
+	if err := app.UsePackage(lib); err != nil {
+		t.Fatal(err)
+	}
+	first, second := lib.MakeSymbol("first"), lib.MakeSymbol("second")
+	app.MakeSymbol("second")
+	if err := lib.Export(first, second); err == nil {
+		t.Error("export of second must conflict")
+	}
+	if lib.IsExternal(first) {
+		t.Errorf("%v must not be exported after a failed export", first)
+	}
+
+	priv := lib.MakeSymbol("priv")
+	other := w.MustMakePackage("other")
+	if err := other.Import(priv, nil); err == nil {
+		t.Error("import of nil must fail")
+	}
+	if got := other.FindSymbol("priv"); got != nil {
+		t.Errorf("symbol must not be imported after a failed import, but got %v", got)
+	}
+	if err := other.Import(priv, w.MustMakePackage("third").MakeSymbol("priv")); err == nil {
+		t.Error("import of two symbols with the same name must conflict")
+	}
+
+	used, conflicting := w.MustMakePackage("used"), w.MustMakePackage("conflicting")
+	_ = used.Export(used.MakeSymbol("x"))
+	_ = conflicting.Export(conflicting.MakeSymbol("x"))
+	if err := other.UsePackage(used, conflicting); err == nil {
+		t.Error("use of two packages with conflicting symbols must fail")
+	}
+	if uses := other.Uses(); len(uses) != 0 {
+		t.Errorf("no package must be used after a failed use, but got %v", uses)
+	}
+}
+
+func TestPackageChangeConcurrent(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	const n = 32
+	for i := range n {
+		user := w.MustMakePackage(fmt.Sprintf("user%d", i))
+		lib := w.MustMakePackage(fmt.Sprintf("lib%d", i))
+		libSym := lib.MakeSymbol("x")
+		user.MakeSymbol("x")
+		var wg sync.WaitGroup
+		var errExport, errUse error
+		wg.Go(func() { errExport = lib.Export(libSym) })
+		wg.Go(func() { errUse = user.UsePackage(lib) })
+		wg.Wait()
+		if errExport == nil && errUse == nil {
+			t.Errorf("%d: export and use of conflicting symbols must not both succeed", i)
+		}
+	}
+}
@@ -61,33 +61,35 @@ func (s String) String() string {
 func (s String) GoString() string { return s.val }
 
 var (
-	quote        = []byte{'"'}
-	escQuote     = []byte{'\\', '"'}
 	escBackslash = []byte{'\\', '\\'}
 	escTab       = []byte{'\\', 't'}
 	escLF        = []byte{'\\', 'n'}
 	escCR        = []byte{'\\', 'r'}
-	esc2         = []byte{'\\', 'x', 0, 0}
-	esc4         = []byte{'\\', 'u', 0, 0, 0, 0}
-	esc6         = []byte{'\\', 'U', 0, 0, 0, 0, 0, 0}
 	encHex       = []byte("0123456789ABCDEF")
 )
 
 // Print write the string representation to the given Writer.
-func (s String) Print(w io.Writer) (int, error) {
+func (s String) Print(w io.Writer) (int, error) { return printQuoted(w, s.val, '"') }
+
+// printQuoted writes the given string, delimited by the given quote
+// character. Special characters are escaped, including the quote character.
+func printQuoted(w io.Writer, s string, quoteCh byte) (int, error) {
 	last := 0
+	quote := []byte{quoteCh}
 	length, err := w.Write(quote)
 	if err != nil {
 		return length, err
 	}
+	escQuote := []byte{'\\', quoteCh}
 	var esc []byte
-	for i := 0; i < len(s.val); {
-		ch, size := rune(s.val[i]), 1
+	var buf [8]byte
+	for i := 0; i < len(s); {
+		ch, size := rune(s[i]), 1
 		if ch >= utf8.RuneSelf {
-			ch, size = utf8.DecodeRuneInString(s.val[i:])
+			ch, size = utf8.DecodeRuneInString(s[i:])
 		}
 		switch ch {
-		case '"':
+		case rune(quoteCh):
 			esc = escQuote
 		case '\\':
 			esc = escBackslash
@@ -103,26 +105,17 @@ func (s String) Print(w io.Writer) (int, error) {
 				continue
 			}
 			if ch <= 0xff {
-				esc = esc2
-				esc[2] = encHex[ch>>4]
-				esc[3] = encHex[ch&0xF]
+				esc = append(buf[:0], '\\', 'x', encHex[ch>>4], encHex[ch&0xF])
 			} else if ch <= 0xffff {
-				esc = esc4
-				esc[2] = encHex[(ch>>12)&0xF]
-				esc[3] = encHex[(ch>>8)&0xF]
-				esc[4] = encHex[(ch>>4)&0xF]
-				esc[5] = encHex[ch&0xF]
+				esc = append(buf[:0], '\\', 'u',
+					encHex[(ch>>12)&0xF], encHex[(ch>>8)&0xF], encHex[(ch>>4)&0xF], encHex[ch&0xF])
 			} else {
-				esc = esc6
-				esc[2] = encHex[(ch>>20)&0xF]
-				esc[3] = encHex[(ch>>16)&0xF]
-				esc[4] = encHex[(ch>>12)&0xF]
-				esc[5] = encHex[(ch>>8)&0xF]
-				esc[6] = encHex[(ch>>4)&0xF]
-				esc[7] = encHex[ch&0xF]
+				esc = append(buf[:0], '\\', 'U',
+					encHex[(ch>>20)&0xF], encHex[(ch>>16)&0xF], encHex[(ch>>12)&0xF],
+					encHex[(ch>>8)&0xF], encHex[(ch>>4)&0xF], encHex[ch&0xF])
 			}
 		}
-		l, err2 := io.WriteString(w, s.val[last:i])
+		l, err2 := io.WriteString(w, s[last:i])
 		length += l
 		if err2 != nil {
 			return length, err2
@@ -135,8 +128,8 @@ func (s String) Print(w io.Writer) (int, error) {
 		i += size
 		last = i
 	}
-	if last <= len(s.val) {
-		l, err2 := io.WriteString(w, s.val[last:])
+	if last <= len(s) {
+		l, err2 := io.WriteString(w, s[last:])
 		length += l
 		if err2 != nil {
 			return length, err2
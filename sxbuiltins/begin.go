@@ -15,6 +15,7 @@ package sxbuiltins
 
 import (
 	"io"
+	"slices"
 
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
@@ -83,6 +84,37 @@ func (es *ExprSeq) Unparse(sym *sx.Symbol) sx.Object {
 	return sx.Cons(sym, obj)
 }
 
+// children returns the sub-expressions of the sequence.
+func (es *ExprSeq) children() []sxeval.Expr {
+	return append(slices.Clone(es.Front), es.Last)
+}
+
+// withChildren returns a sequence with the given sub-expressions.
+func withChildren(children []sxeval.Expr) ExprSeq {
+	last := len(children) - 1
+	return ExprSeq{Front: slices.Clone(children[:last]), Last: children[last]}
+}
+
+// encode the sequence for an image.
+func (es *ExprSeq) encode(enc *sxeval.ImageEncoder) (sx.Vector, error) {
+	front, err := enc.EncodeExprList(es.Front)
+	if err != nil {
+		return nil, err
+	}
+	last, err := enc.EncodeExpr(es.Last)
+	return sx.Vector{front, last}, err
+}
+
+// decodeExprSeq restores a sequence from an image.
+func decodeExprSeq(dec *sxeval.ImageDecoder, args sx.Vector) (ExprSeq, error) {
+	front, err := dec.DecodeExprList(args[0])
+	if err != nil {
+		return ExprSeq{}, err
+	}
+	last, err := dec.DecodeExpr(args[1])
+	return ExprSeq{Front: front, Last: last}, err
+}
+
 // ImproveSeq moves seq and last to the appropriate fields, if possible.
 // seq must have at least length 1.
 func (es *ExprSeq) ImproveSeq(seq []sxeval.Expr, last sxeval.Expr) {
@@ -96,6 +128,28 @@ func (es *ExprSeq) ImproveSeq(seq []sxeval.Expr, last sxeval.Expr) {
 	es.Last = last
 }
 
+// compileShortCircuit compiles the sequence, where the computation stops at
+// the first value that is taken by the emitted jump.
+func (es *ExprSeq) compileShortCircuit(c *sxeval.Compiler, tail bool, emitJump func() sxeval.Label) error {
+	labels := make([]sxeval.Label, len(es.Front))
+	for i, e := range es.Front {
+		if err := c.Compile(e, false); err != nil {
+			return err
+		}
+		labels[i] = emitJump()
+	}
+	if err := c.CompileTCO(es.Last, tail); err != nil {
+		return err
+	}
+	for _, l := range labels {
+		c.SetLabel(l)
+	}
+	if tail && len(labels) > 0 {
+		c.EmitReturn()
+	}
+	return nil
+}
+
 // Print the expression on the given writer.
 func (es *ExprSeq) Print(w io.Writer, prefix string) (int, error) {
 	length, err := io.WriteString(w, prefix)
@@ -164,6 +218,25 @@ func (be *BeginExpr) IsPure() bool { return be.ExprSeq.IsPure() }
 // Unparse the expression as an sx.Object
 func (be *BeginExpr) Unparse() sx.Object { return be.ExprSeq.Unparse(sx.MakeSymbol(beginName)) }
 
+// Children returns the sub-expressions.
+func (be *BeginExpr) Children() []sxeval.Expr { return be.children() }
+
+// WithChildren returns an expression with the given sub-expressions.
+func (*BeginExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &BeginExpr{withChildren(children)}
+}
+
+// Encode the expression for an image.
+func (be *BeginExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := be.encode(enc)
+	return beginName, args, err
+}
+
+func decodeBeginExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	seq, err := decodeExprSeq(dec, args)
+	return &BeginExpr{seq}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (be *BeginExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	last, err := imp.Improve(be.Last)
@@ -200,6 +273,17 @@ func (be *BeginExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.O
 	return env.ExecuteTCO(be.Last, frame)
 }
 
+// Compile the expression.
+func (be *BeginExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	for _, e := range be.Front {
+		if err := c.Compile(e, false); err != nil {
+			return err
+		}
+		c.EmitPop()
+	}
+	return c.CompileTCO(be.Last, tail)
+}
+
 // Print the expression on the given writer.
 func (be *BeginExpr) Print(w io.Writer) (int, error) { return be.ExprSeq.Print(w, "{BEGIN") }
 
@@ -235,6 +319,25 @@ func (be1 *Begin1Expr) IsPure() bool { return be1.ExprSeq.IsPure() }
 // Unparse the expression as an sx.Object
 func (be1 *Begin1Expr) Unparse() sx.Object { return be1.ExprSeq.Unparse(sx.MakeSymbol(begin1Name)) }
 
+// Children returns the sub-expressions.
+func (be1 *Begin1Expr) Children() []sxeval.Expr { return be1.children() }
+
+// WithChildren returns an expression with the given sub-expressions.
+func (*Begin1Expr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &Begin1Expr{withChildren(children)}
+}
+
+// Encode the expression for an image.
+func (be1 *Begin1Expr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := be1.encode(enc)
+	return begin1Name, args, err
+}
+
+func decodeBegin1Expr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	seq, err := decodeExprSeq(dec, args)
+	return &Begin1Expr{seq}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (be1 *Begin1Expr) Improve(imp *sxeval.Improver) (_ sxeval.Expr, err error) {
 	last := be1.Last
@@ -318,6 +421,25 @@ func (ae *AndExpr) IsPure() bool { return ae.ExprSeq.IsPure() }
 // Unparse the expression as an sx.Object
 func (ae *AndExpr) Unparse() sx.Object { return ae.ExprSeq.Unparse(sx.MakeSymbol(andName)) }
 
+// Children returns the sub-expressions.
+func (ae *AndExpr) Children() []sxeval.Expr { return ae.children() }
+
+// WithChildren returns an expression with the given sub-expressions.
+func (*AndExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &AndExpr{withChildren(children)}
+}
+
+// Encode the expression for an image.
+func (ae *AndExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := ae.encode(enc)
+	return andName, args, err
+}
+
+func decodeAndExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	seq, err := decodeExprSeq(dec, args)
+	return &AndExpr{seq}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (ae *AndExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	last, err := imp.Improve(ae.Last)
@@ -365,6 +487,11 @@ func (ae *AndExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Obj
 	return env.ExecuteTCO(ae.Last, frame)
 }
 
+// Compile the expression.
+func (ae *AndExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	return ae.ExprSeq.compileShortCircuit(c, tail, c.EmitJumpFalseKeep)
+}
+
 // Print the expression on the given writer.
 func (ae *AndExpr) Print(w io.Writer) (int, error) { return ae.ExprSeq.Print(w, "{AND") }
 
@@ -396,6 +523,25 @@ func (oe *OrExpr) IsPure() bool { return oe.ExprSeq.IsPure() }
 // Unparse the expression as an sx.Object
 func (oe *OrExpr) Unparse() sx.Object { return oe.ExprSeq.Unparse(sx.MakeSymbol(orName)) }
 
+// Children returns the sub-expressions.
+func (oe *OrExpr) Children() []sxeval.Expr { return oe.children() }
+
+// WithChildren returns an expression with the given sub-expressions.
+func (*OrExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &OrExpr{withChildren(children)}
+}
+
+// Encode the expression for an image.
+func (oe *OrExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := oe.encode(enc)
+	return orName, args, err
+}
+
+func decodeOrExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	seq, err := decodeExprSeq(dec, args)
+	return &OrExpr{seq}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (oe *OrExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	last, err := imp.Improve(oe.Last)
@@ -450,5 +596,10 @@ func (oe *OrExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Obje
 	return env.ExecuteTCO(oe.Last, frame)
 }
 
+// Compile the expression.
+func (oe *OrExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	return oe.ExprSeq.compileShortCircuit(c, tail, c.EmitJumpTrueKeep)
+}
+
 // Print the expression on the given writer.
 func (oe *OrExpr) Print(w io.Writer) (int, error) { return oe.ExprSeq.Print(w, "{OR") }
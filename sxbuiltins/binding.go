@@ -53,15 +53,15 @@ var ResolveSymbol = sxeval.Builtin{
 		}
 		return sx.MakeUndefined(), nil
 	},
-	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		sym, err := GetSymbol(args[0], 0)
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		sym, err := GetSymbol(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
 
-		frame, err := GetFrame(args[1], 1)
+		frame, err := GetFrame(arg1, 1)
 		if err != nil {
-			if !sx.IsNil(args[1]) {
+			if !sx.IsNil(arg1) {
 				return nil, err
 			}
 			frame = nil
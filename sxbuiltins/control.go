@@ -0,0 +1,1012 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins
+
+// Contains non-local exits and the handling of conditions.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxeval"
+)
+
+// ----- (catch TAG BODY...), (throw TAG VALUE)
+
+const catchName = "catch"
+
+// CatchS parses a (catch TAG BODY...) form. The body is computed, and its
+// value is returned. If a (throw TAG VALUE) with the same tag is executed
+// while the body is computed, the computation is stopped and VALUE is
+// returned.
+var CatchS = sxeval.Special{
+	Name: catchName,
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+		if args == nil {
+			return nil, sxeval.ErrNoArgs
+		}
+		tag, err := pe.Parse(args.Car(), frame)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ParseBeginExpr(pe, args.Tail(), frame)
+		if err != nil {
+			return nil, err
+		}
+		return &CatchExpr{Tag: tag, Body: body}, nil
+	},
+}
+
+// CatchExpr stores everything for a (catch ...) expression.
+type CatchExpr struct {
+	Tag  sxeval.Expr
+	Body sxeval.Expr
+}
+
+// IsPure signals an expression that has no side effects.
+func (*CatchExpr) IsPure() bool { return false }
+
+// Unparse the expression as an sx.Object
+func (ce *CatchExpr) Unparse() sx.Object {
+	return sx.MakeList(sx.MakeSymbol(catchName), ce.Tag.Unparse(), ce.Body.Unparse())
+}
+
+// Children returns the tag and the body.
+func (ce *CatchExpr) Children() []sxeval.Expr { return []sxeval.Expr{ce.Tag, ce.Body} }
+
+// WithChildren returns an expression with the given tag and body.
+func (*CatchExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &CatchExpr{Tag: children[0], Body: children[1]}
+}
+
+// Encode the expression for an image.
+func (ce *CatchExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := enc.EncodeExprs(ce.Tag, ce.Body)
+	return catchName, args, err
+}
+
+func decodeCatchExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	exprs, err := dec.DecodeExprs(args...)
+	if err != nil {
+		return nil, err
+	}
+	return &CatchExpr{Tag: exprs[0], Body: exprs[1]}, nil
+}
+
+// Improve the expression into a possible simpler one.
+func (ce *CatchExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
+	tag, err := imp.Improve(ce.Tag)
+	if err != nil {
+		return ce, err
+	}
+	body, err := imp.Improve(ce.Body)
+	if err != nil {
+		return ce, err
+	}
+	ce.Tag, ce.Body = tag, body
+	return ce, nil
+}
+
+// Compute the expression in a frame and return the result.
+func (ce *CatchExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Object, error) {
+	tag, err := env.Execute(ce.Tag, frame)
+	if err != nil {
+		return nil, err
+	}
+	size := env.Size()
+	res, err := env.Execute(ce.Body, frame)
+	if err != nil {
+		var te ThrowError
+		if errors.As(err, &te) && tag.IsEqual(te.Tag) {
+			env.Kill(env.Size() - size)
+			return te.Value, nil
+		}
+	}
+	return res, err
+}
+
+// Print the expression on the given writer.
+func (ce *CatchExpr) Print(w io.Writer) (int, error) {
+	length, err := io.WriteString(w, "{CATCH")
+	if err != nil {
+		return length, err
+	}
+	l, err := sxeval.PrintExprs(w, []sxeval.Expr{ce.Tag, ce.Body})
+	length += l
+	if err != nil {
+		return length, err
+	}
+	l, err = io.WriteString(w, "}")
+	length += l
+	return length, err
+}
+
+// ThrowError transfers the control to the innermost (catch TAG ...) with the
+// same tag.
+type ThrowError struct {
+	Tag   sx.Object
+	Value sx.Object
+}
+
+// IsControl marks the error as a control transfer.
+func (ThrowError) IsControl() {}
+
+func (te ThrowError) Error() string { return fmt.Sprintf("no catch for tag %v", te.Tag) }
+
+// Throw implements (throw TAG [VALUE]).
+var Throw = sxeval.Builtin{
+	Name:     "throw",
+	MinArity: 1,
+	MaxArity: 2,
+	TestPure: nil,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return nil, ThrowError{Tag: arg, Value: sx.Nil()}
+	},
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return nil, ThrowError{Tag: arg0, Value: arg1}
+	},
+}
+
+// ----- (call-with-escape-continuation FN), (let/ec SYMBOL BODY...)
+
+const letECName = "let/ec"
+
+// EscapeContinuation is a function that stops the computation of its
+// (call/ec ...) or (let/ec ...) form, which then returns the argument of the
+// call. It can only be called within the dynamic extent of its form.
+type EscapeContinuation struct {
+	active bool
+}
+
+// IsNil returns true if the object must be treated like a sx.Nil() object.
+func (ec *EscapeContinuation) IsNil() bool { return ec == nil }
+
+// IsAtom returns true if the object is atomic.
+func (ec *EscapeContinuation) IsAtom() bool { return ec == nil }
+
+// IsTrue returns true if the continuation can be interpreted as a "true" value.
+func (ec *EscapeContinuation) IsTrue() bool { return ec != nil }
+
+// IsEqual returns true if the other object is the same continuation.
+func (ec *EscapeContinuation) IsEqual(other sx.Object) bool { return ec == other }
+
+// String returns a string representation.
+func (ec *EscapeContinuation) String() string { return "#<escape-continuation>" }
+
+// GoString returns a string representation to be used in Go code.
+func (ec *EscapeContinuation) GoString() string { return ec.String() }
+
+// IsPure signals that calling the continuation has side effects.
+func (*EscapeContinuation) IsPure(sx.Vector) bool { return false }
+
+// ExecuteCall stops the computation of the form that created the
+// continuation, with the optional argument as its value.
+func (ec *EscapeContinuation) ExecuteCall(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("escape continuation needs at most 1 argument, but %d given: %v", len(args), args)
+	}
+	if !ec.active {
+		return nil, ErrEscapeExited
+	}
+	var val sx.Object = sx.Nil()
+	if len(args) > 0 {
+		val = args[0]
+	}
+	return nil, EscapeError{Cont: ec, Value: val}
+}
+
+// ErrEscapeExited is returned, if an escape continuation is called after its
+// form was left.
+var ErrEscapeExited = errors.New("escape continuation called after its extent was exited")
+
+// EscapeError transfers the control to the form that created the escape
+// continuation.
+type EscapeError struct {
+	Cont  *EscapeContinuation
+	Value sx.Object
+}
+
+// IsControl marks the error as a control transfer.
+func (EscapeError) IsControl() {}
+
+func (EscapeError) Error() string { return "escape continuation not in extent" }
+
+// withEscape computes a function within the dynamic extent of the escape
+// continuation. If the continuation is called, the data stack is restored
+// and its value is returned.
+func withEscape(env *sxeval.Environment, ec *EscapeContinuation, fn func() (sx.Object, error)) (sx.Object, error) {
+	size := env.Size()
+	ec.active = true
+	res, err := fn()
+	ec.active = false
+	if err != nil {
+		var ee EscapeError
+		if errors.As(err, &ee) && ee.Cont == ec {
+			env.Kill(env.Size() - size)
+			return ee.Value, nil
+		}
+	}
+	return res, err
+}
+
+// CallWithEscapeContinuation implements (call-with-escape-continuation FN),
+// which calls FN with an escape continuation as its only argument.
+var CallWithEscapeContinuation = sxeval.Builtin{
+	Name:     "call-with-escape-continuation",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn1:      callEC,
+}
+
+// CallEC is the short name of CallWithEscapeContinuation.
+var CallEC = sxeval.Builtin{
+	Name:     "call/ec",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn1:      callEC,
+}
+
+func callEC(env *sxeval.Environment, arg sx.Object, frame *sxeval.Frame) (sx.Object, error) {
+	fn, err := GetCallable(arg, 0)
+	if err != nil {
+		return nil, err
+	}
+	ec := &EscapeContinuation{}
+	return withEscape(env, ec, func() (sx.Object, error) {
+		return env.Apply(fn, sx.Vector{ec}, frame)
+	})
+}
+
+// LetECS parses a (let/ec SYMBOL BODY...) form. The body is computed with
+// SYMBOL bound to an escape continuation of the form.
+var LetECS = sxeval.Special{
+	Name: letECName,
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+		if args == nil {
+			return nil, sxeval.ErrNoArgs
+		}
+		sym, err := GetParameterSymbol(nil, args.Car())
+		if err != nil {
+			return nil, err
+		}
+		ecFrame := frame.MakeChildFrame(letECName+"-def", 1)
+		ecFrame.Bind(sym, sx.MakeUndefined())
+		body, err := ParseBeginExpr(pe, args.Tail(), ecFrame)
+		if err != nil {
+			return nil, err
+		}
+		return &LetECExpr{Sym: sym, Body: body}, nil
+	},
+	Walk: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+		if args == nil {
+			return args, nil
+		}
+		ecFrame := frame.MakeChildFrame(letECName+"-walk", 1)
+		if sym, isSymbol := sx.GetSymbol(args.Car()); isSymbol {
+			ecFrame.Bind(sym, sx.MakeUndefined())
+		}
+		body, err := pe.MacroExpandAllList(args.Tail(), ecFrame)
+		if err != nil {
+			return nil, err
+		}
+		return body.Cons(args.Car()), nil
+	},
+}
+
+// LetECExpr stores everything for a (let/ec ...) expression.
+type LetECExpr struct {
+	Sym  *sx.Symbol
+	Body sxeval.Expr
+}
+
+// IsPure signals an expression that has no side effects.
+func (*LetECExpr) IsPure() bool { return false }
+
+// Unparse the expression as an sx.Object
+func (le *LetECExpr) Unparse() sx.Object {
+	return sx.MakeList(sx.MakeSymbol(letECName), le.Sym, le.Body.Unparse())
+}
+
+// Children returns the body.
+func (le *LetECExpr) Children() []sxeval.Expr { return []sxeval.Expr{le.Body} }
+
+// WithChildren returns an expression with the given body.
+func (le *LetECExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &LetECExpr{Sym: le.Sym, Body: children[0]}
+}
+
+// Encode the expression for an image.
+func (le *LetECExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	sym, err := enc.EncodeObject(le.Sym)
+	if err != nil {
+		return "", nil, err
+	}
+	body, err := enc.EncodeExpr(le.Body)
+	return letECName, sx.Vector{sym, body}, err
+}
+
+func decodeLetECExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	sym, err := dec.DecodeSymbol(args[0])
+	if err != nil {
+		return nil, err
+	}
+	body, err := dec.DecodeChildExpr(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return &LetECExpr{Sym: sym, Body: body}, nil
+}
+
+// Improve the expression into a possible simpler one.
+func (le *LetECExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
+	ecImp := imp.MakeChildImprover(letECName+"-improve", 1, false)
+	ecImp.Bind(le.Sym)
+	body, err := ecImp.Improve(le.Body)
+	if err == nil {
+		le.Body = body
+	}
+	return le, err
+}
+
+// Compute the expression in a frame and return the result. The body is not
+// computed in tail position, because the continuation must not be used after
+// the computation of the body.
+func (le *LetECExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Object, error) {
+	ec := &EscapeContinuation{}
+	ecFrame := frame.MakeChildFrame(letECName, 1)
+	ecFrame.Bind(le.Sym, ec)
+	return withEscape(env, ec, func() (sx.Object, error) {
+		return env.Execute(le.Body, ecFrame)
+	})
+}
+
+// Print the expression on the given writer.
+func (le *LetECExpr) Print(w io.Writer) (int, error) {
+	length, err := fmt.Fprintf(w, "{LET/EC %v ", le.Sym)
+	if err != nil {
+		return length, err
+	}
+	l, err := le.Body.Print(w)
+	length += l
+	if err != nil {
+		return length, err
+	}
+	l, err = io.WriteString(w, "}")
+	length += l
+	return length, err
+}
+
+// ----- (try BODY... (except KINDS SYMBOL HANDLER...)... (finally CLEANUP...))
+
+const (
+	tryName           = "try"
+	exceptName        = "except"
+	finallyName       = "finally"
+	unwindProtectName = "unwind-protect"
+)
+
+// TryS parses a (try BODY... CLAUSE...) form. A clause is either
+// (except KINDS SYMBOL HANDLER...) or (finally CLEANUP...). KINDS is a
+// symbol or a list of symbols that must contain the kind of a signalled
+// condition. If KINDS is the symbol T, all conditions are handled. The
+// handler is computed with SYMBOL bound to the condition, and its value is
+// the value of the try form. At most one finally clause is allowed; it must
+// be the last clause. Its cleanup forms are computed after the body and the
+// handler, even if the computation is unwound by an error or by a throw.
+var TryS = sxeval.Special{
+	Name: tryName,
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+		node := args
+		var body sx.ListBuilder
+		for ; node != nil && getClauseName(node.Car()) == ""; node = node.Tail() {
+			body.Add(node.Car())
+		}
+		bodyExpr, err := ParseBeginExpr(pe, body.List(), frame)
+		if err != nil {
+			return nil, err
+		}
+		te := TryExpr{Body: bodyExpr}
+		for ; node != nil; node = node.Tail() {
+			name := getClauseName(node.Car())
+			if name == "" {
+				return nil, fmt.Errorf("%s or %s clause expected, but got: %T/%v", exceptName, finallyName, node.Car(), node.Car())
+			}
+			clause := node.Car().(*sx.Pair)
+			if te.Finally != nil {
+				return nil, fmt.Errorf("no clause allowed after %s, but got: %v", finallyName, clause)
+			}
+			if name == finallyName {
+				if te.Finally, err = ParseBeginExpr(pe, clause.Tail(), frame); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			handler, err2 := parseExcept(pe, clause.Tail(), frame)
+			if err2 != nil {
+				return nil, err2
+			}
+			te.Handlers = append(te.Handlers, handler)
+		}
+		return &te, nil
+	},
+	Walk: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+		var lb sx.ListBuilder
+		for node := args; node != nil; node = node.Tail() {
+			obj := node.Car()
+			var err error
+			switch getClauseName(obj) {
+			case "":
+				obj, err = pe.MacroExpandAll(obj, frame)
+			case finallyName:
+				clause := obj.(*sx.Pair)
+				var body *sx.Pair
+				body, err = pe.MacroExpandAllList(clause.Tail(), frame)
+				obj = body.Cons(clause.Car())
+			default:
+				obj, err = walkExcept(pe, obj.(*sx.Pair), frame)
+			}
+			if err != nil {
+				return nil, err
+			}
+			lb.Add(obj)
+		}
+		return lb.List(), nil
+	},
+}
+
+// walkExcept expands all macros within the handler of an except clause,
+// where the symbol of the clause shadows a macro of the same name.
+func walkExcept(pe *sxeval.ParseEnvironment, clause *sx.Pair, frame *sxeval.Frame) (sx.Object, error) {
+	args := clause.Tail()
+	if args == nil || args.Tail() == nil {
+		return clause, nil
+	}
+	handlerFrame := frame.MakeChildFrame(exceptName+"-walk", 1)
+	if sym, isSymbol := sx.GetSymbol(args.Tail().Car()); isSymbol {
+		handlerFrame.Bind(sym, sx.MakeUndefined())
+	}
+	body, err := pe.MacroExpandAllList(args.Tail().Tail(), handlerFrame)
+	if err != nil {
+		return nil, err
+	}
+	return sx.Cons(clause.Car(), sx.Cons(args.Car(), body.Cons(args.Tail().Car()))), nil
+}
+
+// UnwindProtectS parses a (unwind-protect PROTECTED CLEANUP...) form. It is
+// the same as (try PROTECTED (finally CLEANUP...)).
+var UnwindProtectS = sxeval.Special{
+	Name: unwindProtectName,
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+		if args == nil {
+			return nil, sxeval.ErrNoArgs
+		}
+		body, err := pe.Parse(args.Car(), frame)
+		if err != nil {
+			return nil, err
+		}
+		cleanup, err := ParseBeginExpr(pe, args.Tail(), frame)
+		if err != nil {
+			return nil, err
+		}
+		return &TryExpr{Body: body, Finally: cleanup}, nil
+	},
+}
+
+// getClauseName returns the name of the try clause, or the empty string.
+func getClauseName(obj sx.Object) string {
+	if pair, isPair := sx.GetPair(obj); isPair && pair != nil {
+		if sym, isSymbol := sx.GetSymbol(pair.Car()); isSymbol {
+			if name := sym.GetValue(); name == exceptName || name == finallyName {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func parseExcept(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (TryHandler, error) {
+	if args == nil || args.Tail() == nil {
+		return TryHandler{}, fmt.Errorf("%s needs kinds and a symbol", exceptName)
+	}
+	var kinds []*sx.Symbol
+	switch k := args.Car().(type) {
+	case *sx.Symbol:
+		if !sx.T.IsEqual(k) {
+			kinds = []*sx.Symbol{k}
+		}
+	case *sx.Pair:
+		for obj := range k.Values() {
+			kind, isSymbol := sx.GetSymbol(obj)
+			if !isSymbol {
+				return TryHandler{}, fmt.Errorf("kind must be a symbol, but got: %T/%v", obj, obj)
+			}
+			kinds = append(kinds, kind)
+		}
+		if len(kinds) == 0 {
+			return TryHandler{}, fmt.Errorf("%s needs at least one kind", exceptName)
+		}
+	default:
+		return TryHandler{}, fmt.Errorf("kinds must be a symbol or a list, but got: %T/%v", k, k)
+	}
+	sym, err := GetParameterSymbol(nil, args.Tail().Car())
+	if err != nil {
+		return TryHandler{}, err
+	}
+	handlerFrame := frame.MakeChildFrame(exceptName+"-def", 1)
+	handlerFrame.Bind(sym, sx.MakeUndefined())
+	body, err := ParseBeginExpr(pe, args.Tail().Tail(), handlerFrame)
+	if err != nil {
+		return TryHandler{}, err
+	}
+	return TryHandler{Kinds: kinds, Sym: sym, Body: body}, nil
+}
+
+// TryExpr stores everything for a (try ...) expression.
+type TryExpr struct {
+	Body     sxeval.Expr
+	Handlers []TryHandler
+	Finally  sxeval.Expr // may be nil
+}
+
+// TryHandler stores the data of an except clause. If Kinds is empty, the
+// handler is applicable to all conditions.
+type TryHandler struct {
+	Kinds []*sx.Symbol
+	Sym   *sx.Symbol
+	Body  sxeval.Expr
+}
+
+// IsPure signals an expression that has no side effects.
+func (*TryExpr) IsPure() bool { return false }
+
+// Unparse the expression as an sx.Object
+func (te *TryExpr) Unparse() sx.Object {
+	var lb sx.ListBuilder
+	lb.Add(sx.MakeSymbol(tryName))
+	lb.Add(te.Body.Unparse())
+	for _, h := range te.Handlers {
+		var kinds sx.Object = sx.T
+		if len(h.Kinds) > 0 {
+			var kl sx.ListBuilder
+			for _, kind := range h.Kinds {
+				kl.Add(kind)
+			}
+			kinds = kl.List()
+		}
+		lb.Add(sx.MakeList(sx.MakeSymbol(exceptName), kinds, h.Sym, h.Body.Unparse()))
+	}
+	if te.Finally != nil {
+		lb.Add(sx.MakeList(sx.MakeSymbol(finallyName), te.Finally.Unparse()))
+	}
+	return lb.List()
+}
+
+// Children returns the body, the bodies of all handlers, and the finally
+// expression, if there is one.
+func (te *TryExpr) Children() []sxeval.Expr {
+	children := make([]sxeval.Expr, 0, len(te.Handlers)+2)
+	children = append(children, te.Body)
+	for _, h := range te.Handlers {
+		children = append(children, h.Body)
+	}
+	if te.Finally != nil {
+		children = append(children, te.Finally)
+	}
+	return children
+}
+
+// WithChildren returns an expression with the given body, handler bodies,
+// and finally expression.
+func (te *TryExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	result := &TryExpr{Body: children[0], Handlers: slices.Clone(te.Handlers)}
+	for i := range result.Handlers {
+		result.Handlers[i].Body = children[i+1]
+	}
+	if te.Finally != nil {
+		result.Finally = children[len(children)-1]
+	}
+	return result
+}
+
+// Encode the expression for an image. Each handler is encoded as a list of
+// its kinds, its symbol, and its body.
+func (te *TryExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	body, err := enc.EncodeExpr(te.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	handlers := make(sx.Vector, len(te.Handlers))
+	for i, h := range te.Handlers {
+		kinds, err2 := enc.EncodeSymbols(h.Kinds...)
+		if err2 != nil {
+			return "", nil, err2
+		}
+		sym, err2 := enc.EncodeObject(h.Sym)
+		if err2 != nil {
+			return "", nil, err2
+		}
+		hbody, err2 := enc.EncodeExpr(h.Body)
+		if err2 != nil {
+			return "", nil, err2
+		}
+		handlers[i] = sx.MakeList(sx.MakeList(kinds...), sym, hbody)
+	}
+	finally, err := enc.EncodeExpr(te.Finally)
+	return tryName, sx.Vector{body, sx.MakeList(handlers...), finally}, err
+}
+
+func decodeTryExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	body, err := dec.DecodeExpr(args[0])
+	if err != nil {
+		return nil, err
+	}
+	elems, err := dec.Elems(args[1])
+	if err != nil {
+		return nil, err
+	}
+	var handlers []TryHandler
+	for _, elem := range elems {
+		vals, err2 := dec.Elems(elem)
+		if err2 != nil {
+			return nil, err2
+		}
+		if len(vals) != 3 {
+			return nil, fmt.Errorf("invalid try handler: %v", elem)
+		}
+		kindElems, err2 := dec.Elems(vals[0])
+		if err2 != nil {
+			return nil, err2
+		}
+		kinds, err2 := dec.DecodeSymbols(kindElems...)
+		if err2 != nil {
+			return nil, err2
+		}
+		sym, err2 := dec.DecodeSymbol(vals[1])
+		if err2 != nil {
+			return nil, err2
+		}
+		hbody, err2 := dec.DecodeChildExpr(vals[2])
+		if err2 != nil {
+			return nil, err2
+		}
+		handlers = append(handlers, TryHandler{Kinds: kinds, Sym: sym, Body: hbody})
+	}
+	finally, err := dec.DecodeExpr(args[2])
+	if err != nil {
+		return nil, err
+	}
+	return &TryExpr{Body: body, Handlers: handlers, Finally: finally}, nil
+}
+
+// Improve the expression into a possible simpler one.
+func (te *TryExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
+	body, err := imp.Improve(te.Body)
+	if err != nil {
+		return te, err
+	}
+	te.Body = body
+	for i, h := range te.Handlers {
+		handlerImp := imp.MakeChildImprover(exceptName+"-improve", 1, false)
+		handlerImp.Bind(h.Sym)
+		if te.Handlers[i].Body, err = handlerImp.Improve(h.Body); err != nil {
+			return te, err
+		}
+	}
+	if te.Finally != nil {
+		if te.Finally, err = imp.Improve(te.Finally); err != nil {
+			return te, err
+		}
+	}
+	return te, nil
+}
+
+// Compute the expression in a frame and return the result.
+func (te *TryExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Object, error) {
+	size := env.Size()
+	res, err := env.Execute(te.Body, frame)
+	if err != nil && len(te.Handlers) > 0 && !sxeval.IsControlError(err) && !sxeval.IsLimitError(err) {
+		cond := MakeCondition(err)
+		for _, h := range te.Handlers {
+			if h.matches(cond.Kind) {
+				env.Kill(env.Size() - size)
+				handlerFrame := frame.MakeChildFrame(exceptName, 1)
+				handlerFrame.Bind(h.Sym, cond)
+				res, err = env.Execute(h.Body, handlerFrame)
+				break
+			}
+		}
+	}
+	if te.Finally != nil {
+		if err != nil {
+			env.Kill(env.Size() - size)
+		}
+		if _, errFinally := env.Execute(te.Finally, frame); errFinally != nil {
+			return nil, errFinally
+		}
+	}
+	return res, err
+}
+
+func (h *TryHandler) matches(kind *sx.Symbol) bool {
+	if len(h.Kinds) == 0 {
+		return true
+	}
+	for _, k := range h.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Print the expression on the given writer.
+func (te *TryExpr) Print(w io.Writer) (int, error) {
+	length, err := io.WriteString(w, "{TRY ")
+	if err != nil {
+		return length, err
+	}
+	l, err := te.Body.Print(w)
+	length += l
+	if err != nil {
+		return length, err
+	}
+	for _, h := range te.Handlers {
+		l, err = fmt.Fprintf(w, " {EXCEPT %v %v ", h.Kinds, h.Sym)
+		length += l
+		if err != nil {
+			return length, err
+		}
+		l, err = h.Body.Print(w)
+		length += l
+		if err != nil {
+			return length, err
+		}
+		l, err = io.WriteString(w, "}")
+		length += l
+		if err != nil {
+			return length, err
+		}
+	}
+	if te.Finally != nil {
+		l, err = io.WriteString(w, " {FINALLY ")
+		length += l
+		if err != nil {
+			return length, err
+		}
+		l, err = te.Finally.Print(w)
+		length += l
+		if err != nil {
+			return length, err
+		}
+		l, err = io.WriteString(w, "}")
+		length += l
+		if err != nil {
+			return length, err
+		}
+	}
+	l, err = io.WriteString(w, "}")
+	length += l
+	return length, err
+}
+
+// ----- Conditions
+
+// Kinds of conditions that are created from errors.
+var (
+	KindError    = sx.MakeSymbol("error")
+	KindNotBound = sx.MakeSymbol("not-bound")
+)
+
+func init() {
+	for _, kind := range []*sx.Symbol{KindError, KindNotBound} {
+		_ = kind.Package().Export(kind)
+	}
+}
+
+// Condition is an error as a first-class object.
+type Condition struct {
+	Kind      *sx.Symbol
+	Message   string
+	Irritants *sx.Pair
+	Err       error // wrapped Go error, nil for raised conditions
+	CallStack []sxeval.ExecuteState
+}
+
+// MakeCondition creates a condition from the given error. If the error
+// wraps a condition, e.g. one that was raised, this condition is used.
+func MakeCondition(err error) *Condition {
+	var result Condition
+	var cond *Condition
+	if errors.As(err, &cond) {
+		result = *cond
+	} else {
+		result = Condition{Kind: KindError, Message: err.Error(), Err: err}
+		var nbe sxeval.NotBoundError
+		if errors.As(err, &nbe) {
+			result.Kind = KindNotBound
+			result.Irritants = sx.MakeList(nbe.Sym)
+		}
+	}
+	var execErr sxeval.ExecuteError
+	if errors.As(err, &execErr) {
+		result.CallStack = execErr.CallStack
+	}
+	return &result
+}
+
+// IsNil returns true if the object must be treated like a sx.Nil() object.
+func (c *Condition) IsNil() bool { return c == nil }
+
+// IsAtom returns true if the object is atomic.
+func (c *Condition) IsAtom() bool { return c == nil }
+
+// IsTrue returns true if the condition can be interpreted as a "true" value.
+func (c *Condition) IsTrue() bool { return c != nil }
+
+// IsEqual returns true if the other object has the same content.
+func (c *Condition) IsEqual(other sx.Object) bool { return c == other }
+
+// String returns a string representation.
+func (c *Condition) String() string { return "#<condition:" + c.Kind.GetValue() + ">" }
+
+// GoString returns a string representation to be used in Go code.
+func (c *Condition) GoString() string { return c.String() }
+
+// Error returns the condition as an error message.
+func (c *Condition) Error() string {
+	if c.Err != nil {
+		return c.Err.Error()
+	}
+	var sb strings.Builder
+	sb.WriteString(c.Kind.GetValue())
+	if c.Message != "" {
+		sb.WriteString(": ")
+		sb.WriteString(c.Message)
+	}
+	for obj := range c.Irritants.Values() {
+		sb.WriteByte(' ')
+		_, _ = sx.Print(&sb, obj)
+	}
+	return sb.String()
+}
+
+// Unwrap returns the wrapped Go error.
+func (c *Condition) Unwrap() error { return c.Err }
+
+// GetCondition returns the given argument as a condition, if possible.
+func GetCondition(arg sx.Object, pos int) (*Condition, error) {
+	if cond, isCondition := arg.(*Condition); isCondition {
+		return cond, nil
+	}
+	return nil, fmt.Errorf("argument %d is not a condition, but %T/%v", pos+1, arg, arg)
+}
+
+const raiseName = "raise"
+
+// Raise signals a condition: (raise KIND [MESSAGE IRRITANT...]) creates a
+// new condition, (raise CONDITION) signals the given condition again.
+var Raise = sxeval.Builtin{
+	Name:     raiseName,
+	MinArity: 1,
+	MaxArity: -1,
+	TestPure: nil,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		if cond, isCondition := arg.(*Condition); isCondition {
+			return nil, cond
+		}
+		kind, err := GetSymbol(arg, 0)
+		if err != nil {
+			return nil, sxeval.CallError{Name: raiseName, Err: err}
+		}
+		return nil, &Condition{Kind: kind}
+	},
+	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+		kind, err := GetSymbol(args[0], 0)
+		if err != nil {
+			return nil, sxeval.CallError{Name: raiseName, Err: err}
+		}
+		msg, err := GetString(args[1], 1)
+		if err != nil {
+			return nil, sxeval.CallError{Name: raiseName, Err: err}
+		}
+		return nil, &Condition{Kind: kind, Message: msg.GetValue(), Irritants: sx.MakeList(args[2:]...)}
+	},
+	NoCallError: true,
+}
+
+// ConditionP returns true, if the argument is a condition.
+var ConditionP = sxeval.Builtin{
+	Name:     "condition?",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: sxeval.AssertPure,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		_, isCondition := arg.(*Condition)
+		return sx.MakeBoolean(isCondition), nil
+	},
+}
+
+// ConditionKind returns the kind of a condition.
+var ConditionKind = sxeval.Builtin{
+	Name:     "condition-kind",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: sxeval.AssertPure,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		cond, err := GetCondition(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		return cond.Kind, nil
+	},
+}
+
+// ConditionMessage returns the message of a condition.
+var ConditionMessage = sxeval.Builtin{
+	Name:     "condition-message",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: sxeval.AssertPure,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		cond, err := GetCondition(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		return sx.MakeString(cond.Message), nil
+	},
+}
+
+// ConditionIrritants returns the list of irritants of a condition.
+var ConditionIrritants = sxeval.Builtin{
+	Name:     "condition-irritants",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: sxeval.AssertPure,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		cond, err := GetCondition(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		return cond.Irritants, nil
+	},
+}
+
+// ConditionStack returns the call stack of a condition as a list of the
+// unparsed expressions, innermost first.
+var ConditionStack = sxeval.Builtin{
+	Name:     "condition-stack",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		cond, err := GetCondition(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err = AllocPairs(env, len(cond.CallStack)); err != nil {
+			return nil, err
+		}
+		var lb sx.ListBuilder
+		for _, state := range cond.CallStack {
+			lb.Add(state.Expr.Unparse())
+		}
+		return lb.List(), nil
+	},
+}
@@ -0,0 +1,188 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+func TestControl(t *testing.T) {
+	t.Parallel()
+	tcsControl.Run(t)
+}
+
+var tcsControl = tTestCases{
+	{name: "err-catch-0", src: "(catch)", exp: "{[{catch: no arguments given}]}", withErr: true},
+	{name: "catch-no-throw", src: "(catch 'tag 1 2)", exp: "2"},
+	{name: "catch-empty", src: "(catch 'tag)", exp: "()"},
+	{name: "catch-throw", src: "(catch 'tag 1 (throw 'tag 2) 3)", exp: "2"},
+	{name: "catch-throw-nil", src: "(catch 'tag (throw 'tag))", exp: "()"},
+	{name: "catch-throw-nested",
+		src: "(catch 'outer (list 1 (catch 'inner (list 2 (throw 'outer 3)))))",
+		exp: "3"},
+	{name: "catch-throw-inner",
+		src: "(catch 'outer (list 1 (catch 'inner (list 2 (throw 'inner 3)))))",
+		exp: "(1 3)"},
+	{name: "catch-throw-fn",
+		src: "(defun f (n) (if (= n 0) (throw 'done 'zero) (f (- n 1)))) (catch 'done (f 1000))",
+		exp: "#<lambda:f> zero"},
+	{name: "catch-throw-map",
+		src: "(catch 'found (map (lambda (x) (if (= x 3) (throw 'found x) x)) '(1 2 3 4)))",
+		exp: "3"},
+	{name: "err-throw-uncaught", src: "(throw 'tag 1)", exp: "{[{no catch for tag tag}]}", withErr: true},
+	{name: "err-throw-other", src: "(catch 'a (throw 'b 1))", exp: "{[{no catch for tag b}]}", withErr: true},
+	{name: "err-throw-0", src: "(throw)", exp: "{[{throw: between 1 and 2 arguments required, but none given}]}", withErr: true},
+
+	{name: "call/ec-no-escape", src: "(call/ec (lambda (k) 1))", exp: "1"},
+	{name: "call/ec-escape", src: "(list 1 (call/ec (lambda (k) (list 2 (k 3) 4))) 5)", exp: "(1 3 5)"},
+	{name: "call/ec-escape-nil", src: "(call/ec (lambda (k) (k) 1))", exp: "()"},
+	{name: "call-with-escape-continuation",
+		src: "(call-with-escape-continuation (lambda (return) (return 7) 8))",
+		exp: "7"},
+	{name: "call/ec-map",
+		src: "(call/ec (lambda (k) (map (lambda (x) (if (= x 3) (k x) (* x x))) '(1 2 3 4))))",
+		exp: "3"},
+	{name: "call/ec-fold",
+		src: "(call/ec (lambda (k) (fold (lambda (x acc) (if (< acc 5) (+ x acc) (k acc))) 0 '(1 2 3 4 5 6))))",
+		exp: "6"},
+	{name: "call/ec-tco",
+		src: "(defun loop (n k) (if (= n 0) (k 'done) (loop (- n 1) k))) (list (call/ec (lambda (k) (loop 10000 k))) 1)",
+		exp: "#<lambda:loop> (done 1)"},
+	{name: "call/ec-apply", src: "(call/ec (lambda (k) (apply k '(1)) 2))", exp: "1"},
+	{name: "call/ec-nested-outer",
+		src: "(call/ec (lambda (outer) (list 1 (call/ec (lambda (inner) (list 2 (outer 3)))))))",
+		exp: "3"},
+	{name: "call/ec-nested-inner",
+		src: "(call/ec (lambda (outer) (list 1 (call/ec (lambda (inner) (list 2 (inner 3)))))))",
+		exp: "(1 3)"},
+	{name: "call/ec-not-caught-by-try",
+		src: "(call/ec (lambda (k) (try (k 1) (except T c 2))))",
+		exp: "1"},
+	{name: "call/ec-finally",
+		src: "(defvar x 0) (list (call/ec (lambda (k) (try (k 1) (finally (set! x 2))))) x)",
+		exp: "0 (1 2)"},
+	{name: "err-call/ec-exited",
+		src: "((call/ec (lambda (k) k)) 1)",
+		exp: "{[{escape continuation called after its extent was exited}]}", withErr: true},
+	{name: "call/ec-exited-try",
+		src: "(defvar saved ()) (call/ec (lambda (k) (set! saved k))) (try (saved 1) (except error c 'exited))",
+		exp: "() #<escape-continuation> exited"},
+	{name: "err-call/ec-args",
+		src: "(call/ec (lambda (k) (k 1 2)))",
+		exp: "{[{escape continuation needs at most 1 argument, but 2 given: (vector 1 2)}]}", withErr: true},
+	{name: "err-call/ec-fn", src: "(call/ec 1)", exp: "{[{call/ec: argument 1 is not a function, but sx.Int64/1}]}", withErr: true},
+	{name: "let/ec", src: "(let/ec k 1 2)", exp: "2"},
+	{name: "let/ec-escape", src: "(list 1 (let/ec return (list 2 (return 3))) 4)", exp: "(1 3 4)"},
+	{name: "let/ec-early-return",
+		src: "(defun find-first (pred lst) (let/ec return (map (lambda (x) (if (pred x) (return x))) lst) ()))" +
+			" (find-first (lambda (x) (> x 2)) '(1 2 3 4))",
+		exp: "#<lambda:find-first> 3"},
+	{name: "err-let/ec-0", src: "(let/ec)", exp: "{[{let/ec: no arguments given}]}", withErr: true},
+	{name: "err-let/ec-sym", src: "(let/ec 1)", exp: "{[{let/ec: symbol in list expected, but got sx.Int64/1}]}", withErr: true},
+	{name: "let/ec-macroexpand-all",
+		src: "(defmacro k (x) `(list ,x)) (macroexpand-all '(let/ec k (k 1)))",
+		exp: "#<macro:k> (let/ec k (k 1))"},
+
+	{name: "try-no-error", src: "(try 1 2 (except T e 3))", exp: "2"},
+	{name: "try-empty", src: "(try)", exp: "()"},
+	{name: "try-error", src: "(try (error \"bad\") (except T e e))", exp: "#<condition:error>"},
+	{name: "try-error-kind", src: "(try (error \"bad\") (except error e (condition-kind e)))", exp: "error"},
+	{name: "try-error-message", src: "(try (error \"bad\" 7) (except T e (condition-message e)))", exp: "\"bad 7\""},
+	{name: "try-not-bound",
+		src: "(try (list unknown-sym) (except (not-bound) e (list (condition-kind e) (condition-irritants e))))",
+		exp: "(not-bound (unknown-sym))"},
+	{name: "try-raise",
+		src: "(try (raise 'my-error \"my message\" 1 'a) (except (other my-error) c (list (condition-kind c) (condition-message c) (condition-irritants c))))",
+		exp: "(my-error \"my message\" (1 a))"},
+	{name: "try-raise-kind-only",
+		src: "(try (raise 'my-error) (except my-error c (list (condition? c) (condition-message c) (condition-irritants c))))",
+		exp: "(T \"\" ())"},
+	{name: "try-raise-first-handler",
+		src: "(try (raise 'b) (except a c 1) (except b c 2) (except T c 3))",
+		exp: "2"},
+	{name: "err-try-raise-no-handler", src: "(try (raise 'b \"msg\" 1) (except a c 1))", exp: "{[{b: msg 1}]}", withErr: true},
+	{name: "try-reraise",
+		src: "(try (try (raise 'a \"inner\") (except a c (raise c))) (except T c (condition-message c)))",
+		exp: "\"inner\""},
+	{name: "try-finally",
+		src: "(defvar x 0) (try (set! x 1) (finally (set! x (+ x 10)))) x",
+		exp: "0 1 11"},
+	{name: "try-finally-error",
+		src: "(defvar x 0) (try (try (error \"bad\") (finally (set! x 1))) (except T c x))",
+		exp: "0 1"},
+	{name: "try-finally-handler",
+		src: "(defvar x 0) (list (try (error \"bad\") (except T c 'handled) (finally (set! x 1))) x)",
+		exp: "0 (handled 1)"},
+	{name: "try-finally-throw",
+		src: "(defvar x 0) (list (catch 'tag (try (throw 'tag 1) (except T c 'handled) (finally (set! x 2)))) x)",
+		exp: "0 (1 2)"},
+	{name: "try-condition-stack",
+		src: "(defun f (x) (raise 'k)) (try (f 1) (except T c (condition-stack c)))",
+		exp: "#<lambda:f> ((#<builtin:raise> k))"},
+	{name: "err-try-finally-not-last", src: "(try 1 (finally 2) (except T c 3))", exp: "{[{try: no clause allowed after finally, but got: (except T c 3)}]}", withErr: true},
+	{name: "err-try-no-clause", src: "(try 1 (except T e 2) 3)", exp: "{[{try: except or finally clause expected, but got: sx.Int64/3}]}", withErr: true},
+	{name: "err-try-no-clause-pair", src: "(try 1 (except T e 2) (foo))", exp: "{[{try: except or finally clause expected, but got: *sx.Pair/(foo)}]}", withErr: true},
+	{name: "err-try-except-0", src: "(try 1 (except))", exp: "{[{try: except needs kinds and a symbol}]}", withErr: true},
+	{name: "err-try-except-kind", src: "(try 1 (except 1 c))", exp: "{[{try: kinds must be a symbol or a list, but got: sx.Int64/1}]}", withErr: true},
+	{name: "err-try-except-kinds", src: "(try 1 (except (a 1) c))", exp: "{[{try: kind must be a symbol, but got: sx.Int64/1}]}", withErr: true},
+	{name: "err-try-except-sym", src: "(try 1 (except T 1))", exp: "{[{try: symbol in list expected, but got sx.Int64/1}]}", withErr: true},
+
+	{name: "try-macroexpand-all",
+		src: "(defmacro inc (var) `(set! ,var (+ ,var 1)))" +
+			" (macroexpand-all '(try (inc a) (except T inc (inc b)) (except T c (inc c)) (finally (inc d))))",
+		exp: "#<macro:inc> (try (set! a (+ a 1)) (except T inc (inc b)) (except T c (set! c (+ c 1))) (finally (set! d (+ d 1))))"},
+
+	{name: "err-unwind-protect-0", src: "(unwind-protect)", exp: "{[{unwind-protect: no arguments given}]}", withErr: true},
+	{name: "unwind-protect",
+		src: "(defvar x 0) (list (catch 'tag (unwind-protect (throw 'tag 1) (set! x 2) (set! x (+ x 1)))) x)",
+		exp: "0 (1 3)"},
+
+	{name: "err-raise-0", src: "(raise)", exp: "{[{at least 1 arguments required, but none given}]}", withErr: true},
+	{name: "err-raise-kind", src: "(raise 1)", exp: "{[{raise: argument 1 is not a symbol, but sx.Int64/1}]}", withErr: true},
+	{name: "err-raise-msg", src: "(raise 'k 1)", exp: "{[{raise: argument 2 is not a string, but sx.Int64/1}]}", withErr: true},
+	{name: "err-raise", src: "(raise 'my-error \"my message\" 1 \"a\")", exp: "{[{my-error: my message 1 \"a\"}]}", withErr: true},
+	{name: "err-condition-kind", src: "(condition-kind 1)", exp: "{[{condition-kind: argument 1 is not a condition, but sx.Int64/1}]}", withErr: true},
+	{name: "condition?", src: "(condition? 1)", exp: "()"},
+}
+
+func TestTryLimit(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	_ = sxbuiltins.BindAll(root)
+	root.Freeze()
+	env := sxeval.MakeEnvironment(root.MakeChildBinding("try-limit", 2)).
+		SetComputeHandler(sxeval.MakeStepsLimitHandler(1000, sxeval.DefaultHandler{}))
+	src := "(defun f (n) (f (+ n 1))) (try (f 0) (except T c 'caught))"
+	objs, err := sxreader.MakeReader(strings.NewReader(src)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res sx.Object
+	for _, obj := range objs {
+		if res, err = env.Eval(obj, nil); err != nil {
+			break
+		}
+	}
+	var stepsErr sxeval.ErrStepsLimit
+	if !errors.As(err, &stepsErr) {
+		t.Errorf("steps limit error expected, but got %v/%v", res, err)
+	}
+}
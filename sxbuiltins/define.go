@@ -46,6 +46,7 @@ func parseSymValue(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Fra
 	if !isSymbol {
 		return nil, nil, fmt.Errorf("argument 1 must be a symbol, but is: %T/%v", car, car)
 	}
+	sym = pe.ResolveAlias(sym, frame)
 	cdr := args.Cdr()
 	if sx.IsNil(cdr) {
 		return nil, nil, fmt.Errorf("argument 2 missing")
@@ -72,6 +73,36 @@ func (de *DefineExpr) Unparse() sx.Object {
 	return sx.MakeList(sx.MakeSymbol(defvarName), de.Sym, de.Val.Unparse())
 }
 
+// Children returns the value.
+func (de *DefineExpr) Children() []sxeval.Expr { return []sxeval.Expr{de.Val} }
+
+// WithChildren returns an expression with the given value.
+func (de *DefineExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &DefineExpr{Sym: de.Sym, Val: children[0]}
+}
+
+// Encode the expression for an image.
+func (de *DefineExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	sym, err := enc.EncodeObject(de.Sym)
+	if err != nil {
+		return "", nil, err
+	}
+	val, err := enc.EncodeExpr(de.Val)
+	return defvarName, sx.Vector{sym, val}, err
+}
+
+func decodeDefineExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	sym, err := dec.DecodeSymbol(args[0])
+	if err != nil {
+		return nil, err
+	}
+	val, err := dec.DecodeExpr(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return &DefineExpr{Sym: sym, Val: val}, nil
+}
+
 // Improve the expression into a possible simpler one.
 func (de *DefineExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	expr, err := imp.Improve(de.Val)
@@ -90,6 +121,17 @@ func (de *DefineExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.
 	return val, err
 }
 
+// Compile the expression. Only the value is compiled.
+func (de *DefineExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	expr, err := c.Lower(de.Val)
+	if err != nil {
+		return err
+	}
+	de.Val = expr
+	c.EmitExpr(de, tail)
+	return nil
+}
+
 // Print the expression on the given writer.
 func (de *DefineExpr) Print(w io.Writer) (int, error) {
 	length, err := io.WriteString(w, "{DEFINE ")
@@ -130,6 +172,7 @@ var SetXS = sxeval.Special{
 		if !ok {
 			return nil, fmt.Errorf("argument 1 must be a symbol, but is: %T/%v", car, car)
 		}
+		sym = pe.ResolveAlias(sym, frame)
 		cdr := args.Cdr()
 		if sx.IsNil(cdr) {
 			return nil, fmt.Errorf("argument 2 missing")
@@ -160,6 +203,36 @@ func (se *SetXExpr) Unparse() sx.Object {
 	return sx.MakeList(sx.MakeSymbol(setXName), se.Sym, se.Val.Unparse())
 }
 
+// Children returns the value.
+func (se *SetXExpr) Children() []sxeval.Expr { return []sxeval.Expr{se.Val} }
+
+// WithChildren returns an expression with the given value.
+func (se *SetXExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &SetXExpr{Sym: se.Sym, Val: children[0]}
+}
+
+// Encode the expression for an image.
+func (se *SetXExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	sym, err := enc.EncodeObject(se.Sym)
+	if err != nil {
+		return "", nil, err
+	}
+	val, err := enc.EncodeExpr(se.Val)
+	return setXName, sx.Vector{sym, val}, err
+}
+
+func decodeSetXExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	sym, err := dec.DecodeSymbol(args[0])
+	if err != nil {
+		return nil, err
+	}
+	val, err := dec.DecodeExpr(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return &SetXExpr{Sym: sym, Val: val}, nil
+}
+
 // Improve the expression into a possible simpler one.
 func (se *SetXExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	expr, err := imp.Improve(se.Val)
@@ -191,6 +264,17 @@ func (se *SetXExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Ob
 	return val, err
 }
 
+// Compile the expression. Only the value is compiled.
+func (se *SetXExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	expr, err := c.Lower(se.Val)
+	if err != nil {
+		return err
+	}
+	se.Val = expr
+	c.EmitExpr(se, tail)
+	return nil
+}
+
 // Print the expression on the given writer.
 func (se *SetXExpr) Print(w io.Writer) (int, error) {
 	length, err := io.WriteString(w, "{SET! ")
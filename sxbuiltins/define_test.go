@@ -77,4 +77,12 @@ var tcsSetX = tTestCases{
 		withErr: true,
 	},
 	{name: "define-set", src: "(defvar a 1) (set! a 17)", exp: "1 17"},
+	{name: "defvar-after-lookup",
+		src: "(defvar a 1) (defun get-a () a) (get-a) (defvar a 2) (get-a)",
+		exp: "1 #<lambda:get-a> 1 2 2",
+	},
+	{name: "set!-after-lookup",
+		src: "(defvar a 1) (defun get-a () a) (get-a) (set! a 3) (get-a)",
+		exp: "1 #<lambda:get-a> 1 3 3",
+	},
 }
@@ -32,12 +32,12 @@ var ParseExpression = sxeval.Builtin{
 		}
 		return sxeval.MakeExprObj(expr), nil
 	},
-	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		frame, err := GetFrame(args[1], 1)
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		frame, err := GetFrame(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
-		expr, err := env.Parse(args[0], frame)
+		expr, err := env.Parse(arg0, frame)
 		if err != nil {
 			return nil, err
 		}
@@ -74,12 +74,12 @@ var ExecuteExpression = sxeval.Builtin{
 		}
 		return env.Execute(expr.GetExpr(), frame)
 	},
-	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		expr, err := GetExprObj(args[0], 0)
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		expr, err := GetExprObj(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		frame, err := GetFrame(args[1], 1)
+		frame, err := GetFrame(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -100,12 +100,12 @@ var Eval = sxeval.Builtin{
 		}
 		return env.Execute(expr, frame)
 	},
-	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		frame, err := GetFrame(args[1], 1)
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		frame, err := GetFrame(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
-		expr, err := getEvalExpr(env, args[0], frame)
+		expr, err := getEvalExpr(env, arg0, frame)
 		if err != nil {
 			return nil, err
 		}
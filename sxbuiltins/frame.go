@@ -79,12 +79,12 @@ var FrameLookup = sxeval.Builtin{
 		}
 		return sx.MakeUndefined(), nil
 	},
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		sym, err := GetSymbol(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		sym, err := GetSymbol(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		if arg1 := args[1]; !sx.IsNil(arg1) {
+		if arg1 := arg1; !sx.IsNil(arg1) {
 			frame, err2 := GetFrame(arg1, 1)
 			if err2 != nil {
 				return nil, err2
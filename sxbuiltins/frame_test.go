@@ -55,6 +55,18 @@ var tcsFrame = tTestCases{
 		src: "(let ((a 3)) (bindings (current-frame)))",
 		exp: "((a . 3))",
 	},
+	{name: "lambda-bindings",
+		src: "((lambda (a b . c) (bindings (current-frame))) 1 2 3)",
+		exp: "((a . 1) (b . 2) (c 3))",
+	},
+	{name: "lambda-nil-binding",
+		src: "((lambda (l) (list l (bindings (current-frame)))) (cdr '(1)))",
+		exp: "(() ((l)))",
+	},
+	{name: "let*-bindings",
+		src: "(let* ((a 1) (b 2) (a 3)) (bindings (current-frame)))",
+		exp: "((a . 3) (b . 2))",
+	},
 
 	{name: "err-lookup-0",
 		src:     "(frame-lookup)",
@@ -84,6 +96,7 @@ var tcsFrame = tTestCases{
 	// {name: "lookup-a", src: "(defvar a 3)(binding-lookup 'a)", exp: "3 3"},
 	{name: "lookup-a", src: "(let ((a 3)) (frame-lookup 'a))", exp: "3"},
 	{name: "lookup-a-2", src: "(let ((a 3)) (frame-lookup 'a (current-frame)))", exp: "3"},
+	{name: "lookup-lambda", src: "((lambda (a b) (frame-lookup 'b)) 1 2)", exp: "2"},
 	{name: "lookup-b", src: "(frame-lookup 'b)", exp: "#<undefined>"},
 	{name: "lookup-b-2", src: "(frame-lookup 'b (current-frame))", exp: "#<undefined>"},
 }
@@ -82,12 +82,51 @@ func (ife *IfExpr) Unparse() sx.Object {
 	return sx.MakeList(sx.MakeSymbol(ifName), ife.Test.Unparse(), ife.True.Unparse(), ife.False.Unparse())
 }
 
+// Children returns the test and both branches.
+func (ife *IfExpr) Children() []sxeval.Expr { return []sxeval.Expr{ife.Test, ife.True, ife.False} }
+
+// WithChildren returns an expression with the given test and branches.
+func (*IfExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &IfExpr{Test: children[0], True: children[1], False: children[2]}
+}
+
+// Encode the expression for an image.
+func (ife *IfExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := enc.EncodeExprs(ife.Test, ife.True, ife.False)
+	return ifName, args, err
+}
+
+func decodeIfExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	exprs, err := dec.DecodeExprs(args...)
+	if err != nil {
+		return nil, err
+	}
+	return &IfExpr{Test: exprs[0], True: exprs[1], False: exprs[2]}, nil
+}
+
 // Improve the expression into a possible simpler one.
 func (ife *IfExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	testExpr, err := imp.Improve(ife.Test)
 	if err != nil {
 		return ife, err
 	}
+
+	// Improve only the branch that will be computed, if the condition is
+	// constant. The other branch must not be seen as a use of its symbols.
+	if objectExpr, isConstObject := sxeval.GetConstExpr(testExpr); isConstObject {
+		branch := ife.False
+		if sx.IsTrue(objectExpr.ConstObject()) {
+			branch = ife.True
+		}
+		result, err := imp.Improve(branch)
+		if err != nil {
+			return ife, err
+		}
+		ife.Test = testExpr
+		imp.Optimized(sxeval.OptDeadBranch, ife, result)
+		return result, nil
+	}
+
 	trueExpr, err := imp.Improve(ife.True)
 	if err != nil {
 		return ife, err
@@ -120,8 +159,10 @@ restart:
 			falseIsTrue := sx.IsTrue(nestedFalseExpr.ConstObject())
 			switch {
 			case trueIsTrue && falseIsTrue:
+				imp.Optimized(sxeval.OptDeadBranch, ife, trueExpr)
 				return trueExpr, nil
 			case !trueIsTrue && !falseIsTrue:
+				imp.Optimized(sxeval.OptDeadBranch, ife, falseExpr)
 				return falseExpr, nil
 			case trueIsTrue && !falseIsTrue:
 				testExpr = nestedIfe.Test
@@ -135,8 +176,10 @@ restart:
 	// Check for constant condition
 	if objectExpr, isConstObject := sxeval.GetConstExpr(testExpr); isConstObject {
 		if sx.IsTrue(objectExpr.ConstObject()) {
+			imp.Optimized(sxeval.OptDeadBranch, ife, trueExpr)
 			return trueExpr, nil
 		}
+		imp.Optimized(sxeval.OptDeadBranch, ife, falseExpr)
 		return falseExpr, nil
 	}
 
@@ -164,6 +207,28 @@ func (ife *IfExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Obj
 	return env.ExecuteTCO(ife.False, frame)
 }
 
+// Compile the expression.
+func (ife *IfExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	if err := c.Compile(ife.Test, false); err != nil {
+		return err
+	}
+	falseLabel := c.EmitJumpFalse()
+	if err := c.CompileTCO(ife.True, tail); err != nil {
+		return err
+	}
+	if tail {
+		c.SetLabel(falseLabel)
+		return c.CompileTCO(ife.False, true)
+	}
+	endLabel := c.EmitJump()
+	c.SetLabel(falseLabel)
+	if err := c.CompileTCO(ife.False, false); err != nil {
+		return err
+	}
+	c.SetLabel(endLabel)
+	return nil
+}
+
 // Print the expression on the given writer.
 func (ife *IfExpr) Print(w io.Writer) (int, error) {
 	length, err := io.WriteString(w, "{IF ")
@@ -17,6 +17,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync/atomic"
 
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
@@ -44,6 +45,7 @@ var DefunS = sxeval.Special{
 		}
 		return &DefineExpr{Sym: sym, Val: le}, nil
 	},
+	Walk: walkDefProc,
 }
 
 var errNoParameterSpecAndBody = errors.New("parameter spec and body missing")
@@ -56,6 +58,7 @@ func parseDefProc(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Fram
 	if !isSymbol {
 		return nil, nil, fmt.Errorf("not a symbol: %T/%v", args.Car(), args.Car())
 	}
+	sym = pe.ResolveAlias(sym, frame)
 	args = args.Tail()
 	if args == nil {
 		return nil, nil, errNoParameterSpecAndBody
@@ -85,6 +88,64 @@ var LambdaS = sxeval.Special{
 		}
 		return ParseProcedure(pe, name, car, args.Cdr(), frame)
 	},
+	Walk: walkLambda,
+}
+
+// walkDefProc expands all macros within a procedure definition
+// `(NAME PARAMS BODY...)`.
+func walkDefProc(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+	if args == nil {
+		return args, nil
+	}
+	rest, err := walkProcedure(pe, args.Tail(), frame)
+	if err != nil {
+		return nil, err
+	}
+	return rest.Cons(args.Car()), nil
+}
+
+// walkLambda expands all macros within a procedure specification
+// `(["NAME"] PARAMS BODY...)`.
+func walkLambda(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+	if args != nil {
+		if _, isString := sx.GetString(args.Car()); isString {
+			return walkDefProc(pe, args, frame)
+		}
+	}
+	return walkProcedure(pe, args, frame)
+}
+
+// walkProcedure expands all macros within `(PARAMS BODY...)`, where the
+// parameters shadow macros of the same name.
+func walkProcedure(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+	if args == nil {
+		return args, nil
+	}
+	body, isPair := sx.GetPair(args.Cdr())
+	if !isPair {
+		return args, nil
+	}
+	paramSpec := args.Car()
+	lambdaFrame := frame.MakeChildFrame("walk", 0)
+	for {
+		if sym, isSymbol := sx.GetSymbol(paramSpec); isSymbol {
+			lambdaFrame.Bind(sym, sx.MakeUndefined())
+			break
+		}
+		pair, isParamPair := sx.GetPair(paramSpec)
+		if !isParamPair || pair == nil {
+			break
+		}
+		if sym, isSymbol := sx.GetSymbol(pair.Car()); isSymbol {
+			lambdaFrame.Bind(sym, sx.MakeUndefined())
+		}
+		paramSpec = pair.Cdr()
+	}
+	body, err := pe.MacroExpandAllList(body, lambdaFrame)
+	if err != nil {
+		return nil, err
+	}
+	return body.Cons(args.Car()), nil
 }
 
 // ParseProcedure parses a procedure definition, where some parsing is already done.
@@ -133,6 +194,7 @@ func ParseProcedure(pe *sxeval.ParseEnvironment, name string, paramSpec, bodySpe
 		Rest:   rest,
 		Expr:   expr,
 		Type:   lexLambdaType,
+		Layout: makeProcLayout(params, rest),
 	}
 	return fn, nil
 }
@@ -184,7 +246,18 @@ type LambdaExpr struct {
 	Params []*sx.Symbol
 	Rest   *sx.Symbol
 	Expr   sxeval.Expr
-	Type   int // <0: Macro, =0: LexLambda, >0: DynLambda
+	Type   int                 // <0: Macro, =0: LexLambda, >0: DynLambda
+	Layout *sxeval.FrameLayout // slots of the parameters, computed on improve, if missing
+
+	used []bool // parameters referenced by the body, known after improve
+}
+
+// makeProcLayout creates the frame layout of a procedure. The parameters
+// are stored in the first slots, the rest parameter follows.
+func makeProcLayout(params []*sx.Symbol, rest *sx.Symbol) *sxeval.FrameLayout {
+	syms := make([]*sx.Symbol, len(params), len(params)+1)
+	copy(syms, params)
+	return sxeval.MakeFrameLayout(append(syms, rest)...)
 }
 
 const (
@@ -217,13 +290,144 @@ func (le *LambdaExpr) Unparse() sx.Object {
 	return sx.MakeList(sx.MakeSymbol(name), params, expr)
 }
 
+// Children returns the body.
+func (le *LambdaExpr) Children() []sxeval.Expr { return []sxeval.Expr{le.Expr} }
+
+// WithChildren returns an expression with the given body. Since the new
+// body might use other parameters, their usage is not known.
+func (le *LambdaExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	result := *le
+	result.Expr, result.used = children[0], nil
+	return &result
+}
+
+// Encode the expression for an image.
+func (le *LambdaExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := encodeProc(enc, le.Name, le.Params, le.Rest, le.Expr, le.Layout)
+	if err != nil {
+		return "", nil, err
+	}
+	return lambdaName, append(args, sx.Int64(le.Type), encodeUsed(le.used)), nil
+}
+
+func decodeLambdaExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	leType, err := dec.DecodeInt(args[5])
+	if err != nil {
+		return nil, err
+	}
+	decodeBody := dec.DecodeChildExpr
+	if leType > 0 {
+		decodeBody = dec.DecodeDynamicExpr
+	}
+	le, err := decodeProc(dec, args, decodeBody)
+	if err != nil {
+		return nil, err
+	}
+	le.Type = leType
+	if le.used, err = decodeUsed(dec, args[6], len(le.Params)); err != nil {
+		return nil, err
+	}
+	return le, nil
+}
+
+// encodeProc encodes the data that all procedures have in common.
+func encodeProc(enc *sxeval.ImageEncoder, name string, params []*sx.Symbol, rest *sx.Symbol, expr sxeval.Expr, layout *sxeval.FrameLayout) (sx.Vector, error) {
+	eparams, err := enc.EncodeSymbols(params...)
+	if err != nil {
+		return nil, err
+	}
+	erest, err := enc.EncodeObject(rest)
+	if err != nil {
+		return nil, err
+	}
+	eexpr, err := enc.EncodeExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	elayout, err := enc.EncodeLayout(layout)
+	if err != nil {
+		return nil, err
+	}
+	return sx.Vector{sx.MakeString(name), sx.MakeList(eparams...), erest, eexpr, elayout}, nil
+}
+
+// decodeProc restores the data that all procedures have in common from the
+// first five arguments. The body is restored by the given function, which
+// knows the frames the body is computed in.
+func decodeProc(dec *sxeval.ImageDecoder, args sx.Vector, decodeBody func(sx.Object) (sxeval.Expr, error)) (*LambdaExpr, error) {
+	name, err := dec.DecodeString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	elems, err := dec.Elems(args[1])
+	if err != nil {
+		return nil, err
+	}
+	params, err := dec.DecodeSymbols(elems...)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := dec.DecodeSymbol(args[2])
+	if err != nil {
+		return nil, err
+	}
+	expr, err := decodeBody(args[3])
+	if err != nil {
+		return nil, err
+	}
+	layout, err := dec.DecodeLayout(args[4])
+	if err != nil {
+		return nil, err
+	}
+	return &LambdaExpr{Name: name, Params: params, Rest: rest, Expr: expr, Layout: layout}, nil
+}
+
+// encodeUsed encodes the usage of parameters as a list of 0 and 1. Since
+// a procedure without parameters does not need their usage, an unknown
+// usage is encoded as the empty list, too.
+func encodeUsed(used []bool) sx.Object {
+	if used == nil {
+		return sx.Nil()
+	}
+	result := make(sx.Vector, len(used))
+	for i, u := range used {
+		result[i] = sx.Int64(0)
+		if u {
+			result[i] = sx.Int64(1)
+		}
+	}
+	return sx.MakeList(result...)
+}
+
+// decodeUsed restores the usage of the given number of parameters.
+func decodeUsed(dec *sxeval.ImageDecoder, obj sx.Object, numParams int) ([]bool, error) {
+	if sx.IsNil(obj) {
+		return nil, nil
+	}
+	elems, err := dec.Elems(obj)
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) != numParams {
+		return nil, fmt.Errorf("usage of %d parameters expected, but got: %v", numParams, obj)
+	}
+	used := make([]bool, numParams)
+	for i, elem := range elems {
+		u, err2 := dec.DecodeInt(elem)
+		if err2 != nil {
+			return nil, err2
+		}
+		used[i] = u != 0
+	}
+	return used, nil
+}
+
 // Improve the expression into a possible simpler one.
 func (le *LambdaExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
-	frameSize := len(le.Params)
-	if le.Rest != nil {
-		frameSize++
+	if le.Layout == nil {
+		le.Layout = makeProcLayout(le.Params, le.Rest)
 	}
-	lambdaImp := imp.MakeChildImprover(le.Name+"-improve", frameSize, le.Type > 0)
+	lambdaImp := imp.MakeSlotImprover(le.Name+"-improve", le.Layout, le.Type > 0)
 	for _, sym := range le.Params {
 		lambdaImp.Bind(sym)
 	}
@@ -232,10 +436,15 @@ func (le *LambdaExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	}
 
 	expr, err := lambdaImp.Improve(le.Expr)
-	if err == nil {
-		le.Expr = expr
+	if err != nil {
+		return le, err
+	}
+	le.Expr = expr
+	le.used = make([]bool, len(le.Params))
+	for i, sym := range le.Params {
+		le.used[i] = lambdaImp.IsUsed(sym)
 	}
-	return le, err
+	return le, nil
 }
 
 // Compute the expression in a frame and return the result.
@@ -248,6 +457,8 @@ func (le *LambdaExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.
 			Params: le.Params,
 			Rest:   le.Rest,
 			Expr:   le.Expr,
+			Layout: le.Layout,
+			used:   le.used,
 		}, nil
 	}
 	if leType > 0 {
@@ -256,6 +467,7 @@ func (le *LambdaExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.
 			Params: le.Params,
 			Rest:   le.Rest,
 			Expr:   le.Expr,
+			Layout: le.Layout,
 		}, nil
 	}
 	return &Macro{
@@ -265,9 +477,22 @@ func (le *LambdaExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.
 		Params: le.Params,
 		Rest:   le.Rest,
 		Expr:   le.Expr,
+		Layout: le.Layout,
 	}, nil
 }
 
+// Compile the expression. Only the body is compiled, the procedure is
+// created by the tree walker.
+func (le *LambdaExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	expr, err := c.Lower(le.Expr)
+	if err != nil {
+		return err
+	}
+	le.Expr = expr
+	c.EmitExpr(le, tail)
+	return nil
+}
+
 // Print the expression on the given writer.
 func (le *LambdaExpr) Print(w io.Writer) (int, error) {
 	var typeString string
@@ -328,6 +553,11 @@ type LexLambda struct {
 	Params []*sx.Symbol
 	Rest   *sx.Symbol
 	Expr   sxeval.Expr
+	Layout *sxeval.FrameLayout // if nil, parameters are bound dynamically
+
+	purity atomic.Int32 // 0: unknown, 1: pure, 2: not pure
+	size   atomic.Int32 // 0: unknown, else size of the body for inlining + 1
+	used   []bool       // parameters referenced by the body; nil, if unknown
 }
 
 // IsNil returns true if the object must be treated like a sx.Nil() object.
@@ -348,11 +578,129 @@ func (ll *LexLambda) String() string { return "#<lambda:" + ll.Name + ">" }
 // GoString returns a string representation to be used in Go code.
 func (ll *LexLambda) GoString() string { return ll.String() }
 
+// Encode the procedure for an image.
+func (ll *LexLambda) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	frame, err := enc.EncodeObject(ll.Frame)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := encodeProc(enc, ll.Name, ll.Params, ll.Rest, ll.Expr, ll.Layout)
+	if err != nil {
+		return "", nil, err
+	}
+	return lambdaName, append(sx.Vector{frame}, append(args, encodeUsed(ll.used))...), nil
+}
+
+func decodeLexLambda(dec *sxeval.ImageDecoder, args sx.Vector) (sx.Object, error) {
+	ll := &LexLambda{}
+	dec.Define(ll)
+	frame, err := dec.DecodeFrame(args[0])
+	if err != nil {
+		return nil, err
+	}
+	le, err := decodeProc(dec, args[1:], func(obj sx.Object) (sxeval.Expr, error) { return dec.DecodeProcExpr(obj, frame) })
+	if err != nil {
+		return nil, err
+	}
+	used, err := decodeUsed(dec, args[6], len(le.Params))
+	if err != nil {
+		return nil, err
+	}
+	ll.Frame, ll.Name, ll.Params, ll.Rest, ll.Expr, ll.Layout, ll.used = frame, le.Name, le.Params, le.Rest, le.Expr, le.Layout, used
+	return ll, nil
+}
+
 // --- Builtin methods to implement sxeval.Callable
 
 // IsPure tests if the Procedure needs an environment value and does not
 // produce any other side effects.
-func (ll *LexLambda) IsPure(sx.Vector) bool { return false }
+//
+// A lambda is pure, if its body is pure and if it does not refer to the
+// bindings of an enclosing frame, which might be changed.
+func (ll *LexLambda) IsPure(args sx.Vector) bool {
+	if numArgs, numParams := len(args), len(ll.Params); numArgs < numParams || (ll.Rest == nil && numArgs > numParams) {
+		return false
+	}
+	return ll.isPure()
+}
+
+// isPure returns true, if the body is pure and does not refer to an
+// enclosing frame or to global bindings. The result is cached.
+func (ll *LexLambda) isPure() bool {
+	switch ll.purity.Load() {
+	case 1:
+		return true
+	case 2:
+		return false
+	}
+	pure := ll.Frame == nil && ll.Expr.IsPure() && !sxeval.DependsOnGlobals(ll.Expr)
+	if pure {
+		ll.purity.Store(1)
+	} else {
+		ll.purity.Store(2)
+	}
+	return pure
+}
+
+// inlineSizeLimit is the maximum size of a procedure body to be inlined.
+const inlineSizeLimit = 32
+
+// Inline returns a let expression that computes a call with the given
+// arguments in a frame of the same layout. Only small pure procedures are
+// inlined, which do not refer to the bindings of an enclosing frame. Such a
+// procedure does not call itself, because its body was improved before the
+// procedure was created. Unused parameters with a pure argument are not bound.
+// The let expression gets a copy of the body, because it might be changed
+// later, e.g. when it is compiled.
+func (ll *LexLambda) Inline(imp *sxeval.Improver, args []sxeval.Expr) sxeval.Expr {
+	if ll.Rest != nil || ll.Layout == nil || len(args) != len(ll.Params) || !ll.isPure() {
+		return nil
+	}
+	if ll.bodySize() > inlineSizeLimit {
+		return nil
+	}
+	full := &LetExpr{LetData{Symbols: ll.Params, Vals: args, Body: sxeval.Copy(ll.Expr), layout: ll.Layout}}
+	ld, removed := full.withoutUnused(func(sym *sx.Symbol) bool {
+		slot, _ := ll.Layout.Slot(sym)
+		return ll.used == nil || ll.used[slot]
+	})
+	if !removed {
+		return full
+	}
+	result := &LetExpr{LetData: ld}
+	imp.Optimized(sxeval.OptDeadBinding, full, result)
+	return result
+}
+
+// bodySize returns the size of the body, as used for inlining. Counting
+// stops, if the limit is exceeded. The result is cached.
+func (ll *LexLambda) bodySize() int {
+	if size := ll.size.Load(); size > 0 {
+		return int(size - 1)
+	}
+	size := objSize(ll.Expr.Unparse(), inlineSizeLimit)
+	ll.size.Store(int32(size + 1))
+	return size
+}
+
+// objSize returns the number of atoms of the given object. Counting stops,
+// if the limit is exceeded.
+func objSize(obj sx.Object, limit int) int {
+	pair, isPair := sx.GetPair(obj)
+	if !isPair || pair == nil {
+		return 1
+	}
+	size := 0
+	for node := pair; node != nil && size <= limit; {
+		size += objSize(node.Car(), limit-size)
+		next, isPair := sx.GetPair(node.Cdr())
+		if !isPair {
+			return size + 1
+		}
+		node = next
+	}
+	return size
+}
 
 // ExecuteCall the Procedure with any number of arguments.
 func (ll *LexLambda) ExecuteCall(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
@@ -360,6 +708,20 @@ func (ll *LexLambda) ExecuteCall(env *sxeval.Environment, args sx.Vector, _ *sxe
 	if len(args) < numParams {
 		return nil, fmt.Errorf("%s: missing arguments: %v", ll.Name, ll.Params[len(args):])
 	}
+	if ll.Rest == nil && len(args) > numParams {
+		return nil, fmt.Errorf("%s: excess arguments: %v", ll.Name, []sx.Object(args[numParams:]))
+	}
+	if layout := ll.Layout; layout != nil {
+		lexFrame := ll.Frame.MakeSlotFrame(ll.Name, layout)
+		for i, arg := range args[:numParams] {
+			lexFrame.SetSlot(i, arg)
+		}
+		if ll.Rest != nil {
+			lexFrame.SetSlot(numParams, sx.MakeList(args[numParams:]...))
+		}
+		return env.ExecuteTCO(ll.Expr, lexFrame)
+	}
+
 	frameSize := numParams
 	if ll.Rest != nil {
 		frameSize++
@@ -370,8 +732,6 @@ func (ll *LexLambda) ExecuteCall(env *sxeval.Environment, args sx.Vector, _ *sxe
 	}
 	if ll.Rest != nil {
 		lexFrame.Bind(ll.Rest, sx.MakeList(args[numParams:]...))
-	} else if len(args) > numParams {
-		return nil, fmt.Errorf("%s: excess arguments: %v", ll.Name, []sx.Object(args[numParams:]))
 	}
 	return env.ExecuteTCO(ll.Expr, lexFrame)
 }
@@ -387,6 +747,7 @@ var DefDynS = sxeval.Special{
 		le.Type = dynLambdaType
 		return &DefineExpr{Sym: sym, Val: le}, nil
 	},
+	Walk: walkDefProc,
 }
 
 // DynLambdaS parses a dynamically scoped procedure specification.
@@ -401,6 +762,7 @@ var DynLambdaS = sxeval.Special{
 		}
 		return expr, err
 	},
+	Walk: walkLambda,
 }
 
 // DynLambda represents the dynamic binding procedure definition form.
@@ -409,6 +771,7 @@ type DynLambda struct {
 	Params []*sx.Symbol
 	Rest   *sx.Symbol
 	Expr   sxeval.Expr
+	Layout *sxeval.FrameLayout
 }
 
 // IsNil returns true if the object must be treated like a sx.Nil() object.
@@ -429,6 +792,23 @@ func (dl *DynLambda) String() string { return "#<dyn-lambda:" + dl.Name + ">" }
 // GoString returns a string representation to be used in Go code.
 func (dl *DynLambda) GoString() string { return dl.String() }
 
+// Encode the procedure for an image.
+func (dl *DynLambda) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := encodeProc(enc, dl.Name, dl.Params, dl.Rest, dl.Expr, dl.Layout)
+	return dynLambdaName, args, err
+}
+
+func decodeDynLambda(dec *sxeval.ImageDecoder, args sx.Vector) (sx.Object, error) {
+	dl := &DynLambda{}
+	dec.Define(dl)
+	le, err := decodeProc(dec, args, dec.DecodeDynamicExpr)
+	if err != nil {
+		return nil, err
+	}
+	dl.Name, dl.Params, dl.Rest, dl.Expr, dl.Layout = le.Name, le.Params, le.Rest, le.Expr, le.Layout
+	return dl, nil
+}
+
 // --- Builtin methods to implement sxeval.Callable
 
 // IsPure tests if the Procedure needs an environment value and does not
@@ -444,5 +824,6 @@ func (dl *DynLambda) ExecuteCall(env *sxeval.Environment, args sx.Vector, frame
 		Params: dl.Params,
 		Rest:   dl.Rest,
 		Expr:   dl.Expr,
+		Layout: dl.Layout,
 	}).ExecuteCall(env, args, frame)
 }
@@ -13,7 +13,16 @@
 
 package sxbuiltins_test
 
-import "testing"
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
 
 func TestLambda(t *testing.T) {
 	t.Parallel()
@@ -116,3 +125,234 @@ var tcsLambda = tTestCases{
 		exp: "3 #<dyn-lambda:fn> 21",
 	},
 }
+
+func TestLambdaFold(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+
+	// Procedures are folded only if they are bound in frozen bindings.
+	// Each layer may use the procedures of the previous layers.
+	layers := []string{
+		`(defun sq (x) (* x x))
+		 (defun sq-let (x) (let ((y x)) (let* ((z (* y y)) (z (+ z 0))) (begin y z))))
+		 (defun with-set (x) (set! x (+ x 1)) x)
+		 (defvar adder (let ((k 2)) (lambda (x) (+ x k))))
+		 (defvar g 7)
+		 (defun get-g () g)
+		 (defun f0 (x) (+ x x))`,
+		`(defun use-sq (x) (sq (sq-let x)))`,
+	}
+	for i := 1; i <= 15; i++ {
+		layers = append(layers, fmt.Sprintf("(defun f%d (x) (+ (f%d x) (f%d x)))", i, i-1, i-1))
+	}
+	bind := root
+	for i, src := range layers {
+		bind = bind.MakeChildBinding(fmt.Sprintf("layer%d", i), 8)
+		env := sxeval.MakeEnvironment(bind)
+		objs, err := sxreader.MakeReader(strings.NewReader(src)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, obj := range objs {
+			if _, err = env.Eval(obj, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		bind.Freeze()
+	}
+
+	testcases := []struct {
+		src    string
+		exp    string
+		folded bool
+	}{
+		{"(sq 3)", "9", true},
+		{"(sq (+ 1 2))", "9", true},
+		{"(sq-let 3)", "9", true},
+		{"(use-sq 2)", "16", true},
+		{"(map sq '(1 2 3))", "(1 4 9)", true},
+		{"(sq)", "{sq: missing arguments: [x]}", false},
+		{"(sq g)", "49", true},
+		{"(begin (defvar v 5) (sq v))", "25", false},
+		{"(with-set 1)", "2", false},
+		{"(adder 1)", "3", false},
+		{"(get-g)", "7", false},
+		{"(f1 1)", "4", true},
+		{"(f15 1)", "65536", false}, // exceeds the steps limit of folding
+	}
+	for _, tc := range testcases {
+		t.Run(tc.src, func(t *testing.T) {
+			env := sxeval.MakeEnvironment(bind.MakeChildBinding("fold", 1))
+			obj, err := sxreader.MakeReader(strings.NewReader(tc.src)).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expr, err := env.Parse(obj, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, isConst := sxeval.GetConstExpr(expr); isConst != tc.folded {
+				var sb strings.Builder
+				_, _ = expr.Print(&sb)
+				t.Errorf("folded=%v expected, but got %s", tc.folded, sb.String())
+			}
+			res, err := env.Run(expr, nil)
+			var got string
+			if err != nil {
+				got = "{" + err.Error() + "}"
+			} else {
+				got = res.String()
+			}
+			if got != tc.exp {
+				t.Errorf("%s expected, but got %s", tc.exp, got)
+			}
+		})
+	}
+}
+
+// optimizeLog records the names of all optimizations that were applied.
+type optimizeLog []string
+
+func (*optimizeLog) BeforeImprove(_ *sxeval.Improver, expr sxeval.Expr) sxeval.Expr { return expr }
+func (*optimizeLog) AfterImprove(*sxeval.Improver, sxeval.Expr, sxeval.Expr, error) {}
+func (ol *optimizeLog) Optimized(_ *sxeval.Improver, name string, _, _ sxeval.Expr) {
+	*ol = append(*ol, name)
+}
+
+func TestLambdaInline(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+
+	bind := root.MakeChildBinding("inline", 8)
+	src := `(defun sq (x) (* x x))
+		(defun first-of (x y) x)
+		(defun third-of (x y z) z)
+		(defun big (x) (list x x x x x x x x x x x x x x x x x x x x x x x x x x x x x x x x))
+		(defvar g 7)
+		(defun get-g () g)
+		(defun sum (x . rest) (apply + (cons x rest)))`
+	env := sxeval.MakeEnvironment(bind)
+	objs, err := sxreader.MakeReader(strings.NewReader(src)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if _, err = env.Eval(obj, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bind.Freeze()
+
+	testcases := []struct {
+		src  string
+		exp  string
+		opts string
+	}{
+		{"(let ((n 3)) (sq n))", "9", "inline"},
+		{"(let ((n 3)) (sq (sq n)))", "81", "inline inline"},
+		{"(let ((n 3)) (first-of n (car '(1))))", "3", "fold dead-binding inline"},
+		{"(let ((n 3)) (third-of (car '(1)) n (+ n 1)))", "4", "fold dead-binding inline"},
+		{"(let ((n 3)) (first-of n (defvar v 1)))", "3", "inline"},
+		{"(let ((n 3) (m 4)) (if (sq 1) n m))", "3", "fold dead-branch dead-binding"},
+		{"(let ((n 3) (m 4)) (if (sq 1) m n))", "4", "fold dead-branch dead-binding"},
+		{"(let ((n 1)) (if (> n 0) n (sq n)))", "1", "inline"},
+		{"(let ((n 1)) (length (big n)))", "32", ""},
+		{"(let ((n 1)) (get-g))", "7", ""},
+		{"(let ((n 1)) (sum n 2))", "3", ""},
+		{"(let ((n 1)) (sq n n))", "{sq: excess arguments: [1]}", ""},
+	}
+	for _, tc := range testcases {
+		for _, compile := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/%v", tc.src, compile), func(t *testing.T) {
+				var log optimizeLog
+				env := sxeval.MakeEnvironment(bind.MakeChildBinding("use", 1)).
+					SetImproveObserver(&log).
+					SetCompile(compile)
+				obj, err := sxreader.MakeReader(strings.NewReader(tc.src)).Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				expr, err := env.Parse(obj, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.Join(log, " "); got != tc.opts {
+					t.Errorf("optimizations %q expected, but got %q", tc.opts, got)
+				}
+				res, err := env.Run(expr, nil)
+				var got string
+				if err != nil {
+					got = "{" + err.Error() + "}"
+				} else {
+					got = res.String()
+				}
+				if got != tc.exp {
+					t.Errorf("%s expected, but got %s", tc.exp, got)
+				}
+			})
+		}
+	}
+}
+
+// inlineLog records the results of all inline optimizations.
+type inlineLog []sxeval.Expr
+
+func (*inlineLog) BeforeImprove(_ *sxeval.Improver, expr sxeval.Expr) sxeval.Expr { return expr }
+func (*inlineLog) AfterImprove(*sxeval.Improver, sxeval.Expr, sxeval.Expr, error) {}
+func (il *inlineLog) Optimized(_ *sxeval.Improver, name string, _, result sxeval.Expr) {
+	if name == sxeval.OptInline {
+		*il = append(*il, result)
+	}
+}
+
+func TestLambdaInlineCopy(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	bind := root.MakeChildBinding("inline-copy", 1)
+	env := sxeval.MakeEnvironment(bind)
+	obj, err := sxreader.MakeReader(strings.NewReader("(defun sq (x) (* x x))")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = env.Eval(obj, nil); err != nil {
+		t.Fatal(err)
+	}
+	bind.Freeze()
+	val, _ := bind.Lookup(sx.MakeSymbol("sq"))
+	ll := val.(*sxbuiltins.LexLambda)
+	body := ll.Expr
+
+	var log inlineLog
+	env = sxeval.MakeEnvironment(bind.MakeChildBinding("use", 1)).SetImproveObserver(&log).SetCompile(true)
+	if obj, err = sxreader.MakeReader(strings.NewReader("(let ((n 3)) (sq n))")).Read(); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.Parse(obj, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 1 {
+		t.Fatalf("one inline expected, but got %v", log)
+	}
+	if le, isLet := log[0].(*sxbuiltins.LetExpr); !isLet || le.Body == body {
+		t.Errorf("inlined let expression must not share the body of the lambda: %v", log[0])
+	}
+	if ll.Expr != body {
+		t.Errorf("body of lambda was changed to %v", ll.Expr)
+	}
+	if res, errRun := env.Run(expr, nil); errRun != nil || !res.IsEqual(sx.Int64(9)) {
+		t.Errorf("9 expected, but got %v/%v", res, errRun)
+	}
+}
@@ -17,10 +17,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
-	"t73f.de/r/zero/set"
 )
 
 // ----- Notes
@@ -35,6 +35,18 @@ type LetData struct {
 	Symbols []*sx.Symbol
 	Vals    []sxeval.Expr
 	Body    sxeval.Expr
+
+	layout *sxeval.FrameLayout // slots of the unique symbols
+	sparse bool                // some symbols are not bound to their own index
+}
+
+// frameLayout returns the layout of the let frame. It must be called while
+// the expression is parsed or improved.
+func (ld *LetData) frameLayout() *sxeval.FrameLayout {
+	if ld.layout == nil {
+		ld.layout = sxeval.MakeFrameLayout(ld.Symbols...)
+	}
+	return ld.layout
 }
 
 var errNoBindingSpecAndBody = errors.New("binding spec and body missing")
@@ -109,10 +121,148 @@ func parseBindingsBody(pe *sxeval.ParseEnvironment, args *sx.Pair, forLetStar bo
 	if err != nil {
 		return err
 	}
-	*data = LetData{symbols, vals, body}
+	*data = LetData{Symbols: symbols, Vals: vals, Body: body}
+	data.frameLayout()
 	return nil
 }
 
+// walkBindingsBody expands all macros within `((SYMBOL VALUE)...) BODY...`.
+// The bound symbols shadow macros of the same name.
+func walkBindingsBody(pe *sxeval.ParseEnvironment, args *sx.Pair, forLetStar bool, frame *sxeval.Frame) (*sx.Pair, error) {
+	if args == nil {
+		return args, nil
+	}
+	bindings, isBindings := sx.GetPair(args.Car())
+	bodyArgs, isBody := sx.GetPair(args.Cdr())
+	if !isBindings || !isBody {
+		return args, nil
+	}
+	letFrame := frame.MakeChildFrame("let-walk", bindings.Length())
+	valFrame := frame
+	if forLetStar {
+		valFrame = letFrame
+	}
+	var lb sx.ListBuilder
+	for node := range bindings.Pairs() {
+		binding, isPair := sx.GetPair(node.Car())
+		if !isPair || binding == nil {
+			lb.Add(node.Car())
+			continue
+		}
+		if val, isVal := sx.GetPair(binding.Cdr()); isVal && val != nil {
+			obj, err := pe.MacroExpandAll(val.Car(), valFrame)
+			if err != nil {
+				return nil, err
+			}
+			binding = sx.Cons(binding.Car(), val.Tail().Cons(obj))
+		}
+		if sym, isSymbol := sx.GetSymbol(binding.Car()); isSymbol {
+			letFrame.Bind(sym, sx.MakeUndefined())
+		}
+		lb.Add(binding)
+	}
+	body, err := pe.MacroExpandAllList(bodyArgs, letFrame)
+	if err != nil {
+		return nil, err
+	}
+	return body.Cons(lb.List()), nil
+}
+
+// IsPure returns true, if all values and the body have no side effects.
+// Creating the let frame has no side effects.
+func (ld *LetData) IsPure() bool {
+	for _, val := range ld.Vals {
+		if !val.IsPure() {
+			return false
+		}
+	}
+	return ld.Body.IsPure()
+}
+
+// children returns the values and the body.
+func (ld *LetData) children() []sxeval.Expr {
+	return append(slices.Clone(ld.Vals), ld.Body)
+}
+
+// withChildren returns the let data with the given values and body.
+func (ld *LetData) withChildren(children []sxeval.Expr) LetData {
+	result := *ld
+	last := len(children) - 1
+	result.Vals, result.Body = slices.Clone(children[:last]), children[last]
+	return result
+}
+
+// encode the let data for an image.
+func (ld *LetData) encode(enc *sxeval.ImageEncoder) (sx.Vector, error) {
+	syms, err := enc.EncodeSymbols(ld.Symbols...)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := enc.EncodeExprList(ld.Vals)
+	if err != nil {
+		return nil, err
+	}
+	body, err := enc.EncodeExpr(ld.Body)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := enc.EncodeLayout(ld.layout)
+	if err != nil {
+		return nil, err
+	}
+	sparse := sx.Int64(0)
+	if ld.sparse {
+		sparse = 1
+	}
+	return sx.Vector{sx.MakeList(syms...), vals, body, layout, sparse}, nil
+}
+
+// decodeLetData restores let data from an image. The values of let* are
+// computed in the frame of the let*, except the first one.
+func decodeLetData(dec *sxeval.ImageDecoder, args sx.Vector, forLetStar bool) (LetData, error) {
+	elems, err := dec.Elems(args[0])
+	if err != nil {
+		return LetData{}, err
+	}
+	syms, err := dec.DecodeSymbols(elems...)
+	if err != nil {
+		return LetData{}, err
+	}
+	if elems, err = dec.Elems(args[1]); err != nil {
+		return LetData{}, err
+	}
+	vals := make([]sxeval.Expr, len(elems))
+	for i, elem := range elems {
+		decodeVal := dec.DecodeExpr
+		if forLetStar && i > 0 {
+			decodeVal = dec.DecodeChildExpr
+		}
+		if vals[i], err = decodeVal(elem); err != nil {
+			return LetData{}, err
+		}
+	}
+	decodeBody := dec.DecodeChildExpr
+	if forLetStar && len(vals) == 0 {
+		decodeBody = dec.DecodeExpr
+	}
+	body, err := decodeBody(args[2])
+	if err != nil {
+		return LetData{}, err
+	}
+	layout, err := dec.DecodeLayout(args[3])
+	if err != nil {
+		return LetData{}, err
+	}
+	sparse, err := dec.DecodeInt(args[4])
+	if err != nil {
+		return LetData{}, err
+	}
+	if len(syms) != len(vals) {
+		return LetData{}, fmt.Errorf("let data with %d symbols, but %d values", len(syms), len(vals))
+	}
+	return LetData{Symbols: syms, Vals: vals, Body: body, layout: layout, sparse: sparse != 0}, nil
+}
+
 // Unparse the expression as an sx.Object
 func (ld *LetData) Unparse(letSym *sx.Symbol) sx.Object {
 	var bindings sx.ListBuilder
@@ -190,6 +340,9 @@ var LetS = sxeval.Special{
 		}
 		return &result, nil
 	},
+	Walk: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+		return walkBindingsBody(pe, args, false, frame)
+	},
 }
 
 // LetExpr stores everything for a (let ...) expression.
@@ -198,14 +351,33 @@ type LetExpr struct {
 }
 
 // IsPure signals an expression that has no side effects.
-func (*LetExpr) IsPure() bool { return false } // TODO: check pure-ness of binding-creation and pure-ness of body
+func (le *LetExpr) IsPure() bool { return le.LetData.IsPure() }
 
 // Unparse the expression as an sx.Object
 func (le *LetExpr) Unparse() sx.Object { return le.LetData.Unparse(sx.MakeSymbol(letName)) }
 
+// Children returns the values and the body.
+func (le *LetExpr) Children() []sxeval.Expr { return le.LetData.children() }
+
+// WithChildren returns an expression with the given values and body.
+func (le *LetExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &LetExpr{le.LetData.withChildren(children)}
+}
+
+// Encode the expression for an image.
+func (le *LetExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := le.encode(enc)
+	return letName, args, err
+}
+
+func decodeLetExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	data, err := decodeLetData(dec, args, false)
+	return &LetExpr{data}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (le *LetExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
-	if len(le.Vals) == 0 {
+	if le.frameLayout().Size() == 0 {
 		return imp.Improve(le.Body)
 	}
 
@@ -213,31 +385,91 @@ func (le *LetExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 		return le, err
 	}
 
-	letImp := imp.MakeChildImprover("let-improve", len(le.Vals), false)
+	letImp := imp.MakeSlotImprover("let-improve", le.frameLayout(), false)
 	for _, sym := range le.Symbols {
 		letImp.Bind(sym)
 	}
 	expr, err := letImp.Improve(le.Body)
-	if err == nil {
-		le.Body = expr
+	if err != nil {
+		return le, err
 	}
-	return le, err
+	le.Body = expr
+	if expr.IsPure() {
+		if ld, removed := le.withoutUnused(letImp.IsUsed); removed {
+			result := &LetExpr{LetData: ld}
+			imp.Optimized(sxeval.OptDeadBinding, le, result)
+			return result, nil
+		}
+	}
+	return le, nil
+}
+
+// withoutUnused returns the let data without all bindings, whose symbol is
+// not used and whose value is pure. The body must be pure too, because
+// otherwise a symbol might be looked up dynamically.
+//
+// The frame layout is not changed, because the body refers to the slots of
+// the symbols. Therefore, some symbols might be bound to another slot.
+func (ld *LetData) withoutUnused(isUsed func(*sx.Symbol) bool) (LetData, bool) {
+	result := LetData{Body: ld.Body, layout: ld.layout}
+	for i, sym := range ld.Symbols {
+		if val := ld.Vals[i]; isUsed(sym) || !val.IsPure() {
+			if slot, _ := ld.layout.Slot(sym); slot != len(result.Symbols) {
+				result.sparse = true
+			}
+			result.Symbols = append(result.Symbols, sym)
+			result.Vals = append(result.Vals, val)
+		}
+	}
+	return result, len(result.Symbols) < len(ld.Symbols)
 }
 
 // Compute the expression in a frame and return the result.
 func (le *LetExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (sx.Object, error) {
 	syms, vals := le.Symbols, le.Vals
-	letFrame := frame.MakeChildFrame("let", len(syms))
+	letFrame := frame.MakeSlotFrame("let", le.layout)
 	for i, sym := range syms {
 		obj, err := env.Execute(vals[i], frame)
 		if err != nil {
 			return nil, err
 		}
-		letFrame.Bind(sym, obj)
+		if le.sparse {
+			letFrame.Bind(sym, obj)
+		} else {
+			letFrame.SetSlot(i, obj)
+		}
 	}
 	return env.ExecuteTCO(le.Body, letFrame)
 }
 
+// Compile the expression.
+func (le *LetExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	if err := c.CompileSlice(le.Vals); err != nil {
+		return err
+	}
+	if !le.sparse {
+		c.EmitLet("let", le.layout, len(le.Symbols))
+		return compileLetBody(c, le.Body, tail)
+	}
+	c.EmitLet("let", le.layout, 0)
+	for i := len(le.Symbols) - 1; i >= 0; i-- {
+		c.EmitBind(le.Symbols[i])
+	}
+	return compileLetBody(c, le.Body, tail)
+}
+
+// compileLetBody compiles the body of a let form, and restores the frame,
+// if the body is not in tail position.
+func compileLetBody(c *sxeval.Compiler, body sxeval.Expr, tail bool) error {
+	if err := c.CompileTCO(body, tail); err != nil {
+		return err
+	}
+	if !tail {
+		c.EmitPopFrame()
+	}
+	return nil
+}
+
 // Print the expression on the given writer.
 func (le *LetExpr) Print(w io.Writer) (int, error) { return le.LetData.Print(w, "{LET (") }
 
@@ -253,24 +485,43 @@ var LetStarS = sxeval.Special{
 		if err := parseBindingsBody(pe, args, true, frame, &result.LetData); err != nil {
 			return nil, err
 		}
-		result.numSymbols = set.New(result.Symbols...).Length()
 		return &result, nil
 	},
+	Walk: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+		return walkBindingsBody(pe, args, true, frame)
+	},
 }
 
 // LetStarExpr stores everything for a (let* ...) expression.
 type LetStarExpr struct {
 	LetData
-
-	numSymbols int // number of unique symbols, since symbols may be given multiple times in (let* ...)
 }
 
 // IsPure signals an expression that has no side effects.
-func (*LetStarExpr) IsPure() bool { return false } // TODO: check pure-ness: bindings, body
+func (lse *LetStarExpr) IsPure() bool { return lse.LetData.IsPure() }
 
 // Unparse the expression as an sx.Object
 func (lse *LetStarExpr) Unparse() sx.Object { return lse.LetData.Unparse(sx.MakeSymbol(letStarName)) }
 
+// Children returns the values and the body.
+func (lse *LetStarExpr) Children() []sxeval.Expr { return lse.LetData.children() }
+
+// WithChildren returns an expression with the given values and body.
+func (lse *LetStarExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return &LetStarExpr{lse.LetData.withChildren(children)}
+}
+
+// Encode the expression for an image.
+func (lse *LetStarExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	args, err := lse.encode(enc)
+	return letStarName, args, err
+}
+
+func decodeLetStarExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	data, err := decodeLetData(dec, args, true)
+	return &LetStarExpr{data}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (lse *LetStarExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	if len(lse.Vals) < 2 {
@@ -285,7 +536,7 @@ func (lse *LetStarExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 		}
 		lse.Vals[i] = iexpr
 		if i == 0 {
-			letStarImp = imp.MakeChildImprover("let*-improve", lse.numSymbols, false)
+			letStarImp = imp.MakeSlotImprover("let*-improve", lse.frameLayout(), false)
 		}
 		letStarImp.Bind(lse.Symbols[i])
 	}
@@ -307,12 +558,31 @@ func (lse *LetStarExpr) Compute(env *sxeval.Environment, frame *sxeval.Frame) (s
 			return nil, err
 		}
 		if i == 0 {
-			letStarFrame = frame.MakeChildFrame("let*", lse.numSymbols)
+			letStarFrame = frame.MakeSlotFrame("let*", lse.layout)
 		}
 		letStarFrame.Bind(sym, obj)
 	}
 	return env.ExecuteTCO(lse.Body, letStarFrame)
 }
 
+// Compile the expression.
+func (lse *LetStarExpr) Compile(c *sxeval.Compiler, tail bool) error {
+	syms, vals := lse.Symbols, lse.Vals
+	if len(syms) == 0 {
+		return c.CompileTCO(lse.Body, tail)
+	}
+	if err := c.Compile(vals[0], false); err != nil {
+		return err
+	}
+	c.EmitLet("let*", lse.layout, 1)
+	for i, sym := range syms[1:] {
+		if err := c.Compile(vals[i+1], false); err != nil {
+			return err
+		}
+		c.EmitBind(sym)
+	}
+	return compileLetBody(c, lse.Body, tail)
+}
+
 // Print the expression on the given writer.
 func (lse *LetStarExpr) Print(w io.Writer) (int, error) { return lse.LetData.Print(w, "{LET* (") }
@@ -44,6 +44,10 @@ var tcsLet = tTestCases{
 	{name: "let-a-b", src: "(let ((a 1) (b 2)) (lambda () a) b)", exp: "2"},
 	{name: "let-nested-0", src: "(let ((a 1)) (let ((a 2)) a))", exp: "2"},
 	{name: "let-no-nested", src: "(let ((a 1)) (let ((a 2)) (let ((a 3) (b a)) a)) a)", exp: "1"},
+	{name: "let-dead-first", src: "(let ((a (car '(1))) (b 2) (c 3)) (+ b c))", exp: "5"},
+	{name: "let-dead-all", src: "(let ((a 1)) (let ((b 2)) (let ((c 3)) a)))", exp: "1"},
+	{name: "let-dead-impure", src: "(list (let ((a (defvar v 2))) 3) v)", exp: "(3 2)"},
+	{name: "let-dead-frame", src: "(let ((a 1) (b 2)) (bindings (current-frame)))", exp: "((a . 1) (b . 2))"},
 	{name: "err-let-double-sym",
 		src:     "(let ((a 1) (a 2)) a)",
 		exp:     "{[{let: symbol a already defined}]}",
@@ -28,8 +28,11 @@ var Cons = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		return sx.Cons(args[0], args[1]), nil
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		if err := AllocPairs(env, 1); err != nil {
+			return nil, err
+		}
+		return sx.Cons(arg0, arg1), nil
 	},
 }
 
@@ -194,10 +197,16 @@ var List = sxeval.Builtin{
 	Fn0: func(_ *sxeval.Environment, _ *sxeval.Frame) (sx.Object, error) {
 		return sx.Nil(), nil
 	},
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		if err := AllocPairs(env, 1); err != nil {
+			return nil, err
+		}
 		return sx.Cons(arg, sx.Nil()), nil
 	},
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+		if err := AllocPairs(env, len(args)); err != nil {
+			return nil, err
+		}
 		return sx.MakeList(args...), nil
 	},
 }
@@ -235,7 +244,7 @@ var Append = sxeval.Builtin{
 		lst, err := GetList(arg, 0)
 		return lst, err
 	},
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
 		lastList := len(args) - 1
 		lsts := make([]*sx.Pair, lastList)
 		for i := range lastList {
@@ -249,6 +258,9 @@ var Append = sxeval.Builtin{
 		curr := &sentinel
 		for _, lst := range lsts {
 			for node := lst; node != nil; {
+				if err := AllocPairs(env, 1); err != nil {
+					return nil, err
+				}
 				curr = curr.AppendBang(node.Car())
 				next, isPair := sx.GetPair(node.Cdr())
 				if !isPair {
@@ -284,12 +296,12 @@ var Assoc = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		lst, err := GetList(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		lst, err := GetList(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		return lst.Assoc(args[1]), nil
+		return lst.Assoc(arg1), nil
 	},
 }
 
@@ -343,11 +355,14 @@ var List2Vector = sxeval.Builtin{
 	MinArity: 1,
 	MaxArity: 1,
 	TestPure: sxeval.AssertPure,
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		lst, err := GetList(arg, 0)
 		if err != nil {
 			return nil, err
 		}
+		if err = AllocVector(env, lst.Length()); err != nil {
+			return nil, err
+		}
 		return sx.Collect(lst.Values()), nil
 	},
 }
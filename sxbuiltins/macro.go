@@ -29,6 +29,7 @@ var DefMacroS = sxeval.Special{
 		le.Type = macroType
 		return &DefineExpr{Sym: sym, Val: le}, nil
 	},
+	Walk: walkDefProc,
 }
 
 // Macro represents the macro definition form.
@@ -39,6 +40,7 @@ type Macro struct {
 	Params []*sx.Symbol
 	Rest   *sx.Symbol
 	Expr   sxeval.Expr
+	Layout *sxeval.FrameLayout
 }
 
 // IsNil returns true if the object must be treated like a sx.Nil() object.
@@ -59,6 +61,35 @@ func (m *Macro) String() string { return "#<macro:" + m.Name + ">" }
 // GoString returns a string representation to be used in Go code.
 func (m *Macro) GoString() string { return m.String() }
 
+// Encode the macro for an image. Its environment is not stored, a restored
+// macro uses an environment of the restored binding.
+func (m *Macro) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	frame, err := enc.EncodeObject(m.Frame)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := encodeProc(enc, m.Name, m.Params, m.Rest, m.Expr, m.Layout)
+	if err != nil {
+		return "", nil, err
+	}
+	return macroName, append(sx.Vector{frame}, args...), nil
+}
+
+func decodeMacro(dec *sxeval.ImageDecoder, args sx.Vector) (sx.Object, error) {
+	m := &Macro{Env: dec.Environment()}
+	dec.Define(m)
+	frame, err := dec.DecodeFrame(args[0])
+	if err != nil {
+		return nil, err
+	}
+	le, err := decodeProc(dec, args[1:], func(obj sx.Object) (sxeval.Expr, error) { return dec.DecodeProcExpr(obj, frame) })
+	if err != nil {
+		return nil, err
+	}
+	m.Frame, m.Name, m.Params, m.Rest, m.Expr, m.Layout = frame, le.Name, le.Params, le.Rest, le.Expr, le.Layout
+	return m, nil
+}
+
 // Parse transforms a macro call into its expanded form. Some kind of
 // iterative expansion may happen.
 func (m *Macro) Parse(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
@@ -91,13 +122,15 @@ func (m *Macro) Expand(_ *sxeval.ParseEnvironment, args *sx.Pair, _ *sxeval.Fram
 		Params: m.Params,
 		Rest:   m.Rest,
 		Expr:   m.Expr,
+		Layout: m.Layout,
 	}
 	return m.Env.ApplyMacro(proc.Name, &proc, macroArgs, m.Frame)
 }
 
 // Macroexpand0 implements one level of macro expansion.
 //
-// It is mostly used for debugging macros.
+// It is mostly used for debugging macros. Symbols introduced by hygienic
+// macros are replaced by the symbols they rename.
 var Macroexpand0 = sxeval.Builtin{
 	Name:     "macroexpand-0",
 	MinArity: 1,
@@ -105,15 +138,43 @@ var Macroexpand0 = sxeval.Builtin{
 	TestPure: nil,
 	Fn1: func(env *sxeval.Environment, arg sx.Object, frame *sxeval.Frame) (sx.Object, error) {
 		lst, err := GetList(arg, 0)
-		if err == nil && lst != nil {
-			if sym, isSymbol := sx.GetSymbol(lst.Car()); isSymbol {
-				if obj, found := env.Resolve(sym, frame); found {
-					if macro, isMacro := obj.(*Macro); isMacro {
-						return macro.Expand(env.MakeParseEnvironment(), lst.Tail(), frame)
-					}
-				}
-			}
+		if err != nil {
+			return nil, err
 		}
-		return lst, err
+		pe := env.MakeParseEnvironment()
+		result, _, err := pe.MacroExpand1(lst, frame)
+		return pe.StripAliases(result), err
+	},
+}
+
+// Macroexpand expands a macro call repeatedly, until the form is no longer
+// a macro call.
+//
+// It is mostly used for debugging macros.
+var Macroexpand = sxeval.Builtin{
+	Name:     "macroexpand",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, frame *sxeval.Frame) (sx.Object, error) {
+		pe := env.MakeParseEnvironment()
+		result, _, err := pe.MacroExpand(arg, frame)
+		return pe.StripAliases(result), err
+	},
+}
+
+// MacroexpandAll expands all macro calls within a form. Quoted data is not
+// expanded, and local bindings shadow macros of the same name.
+//
+// It is mostly used for debugging macros.
+var MacroexpandAll = sxeval.Builtin{
+	Name:     "macroexpand-all",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, frame *sxeval.Frame) (sx.Object, error) {
+		pe := env.MakeParseEnvironment()
+		result, err := pe.MacroExpandAll(arg, frame)
+		return pe.StripAliases(result), err
 	},
 }
@@ -31,6 +31,35 @@ var tcsMacro = tTestCases{
 		src: "(defmacro inc (var) `(set! ,var (+ ,var 1))) (macroexpand-0 '(inc a))",
 		exp: "#<macro:inc> (set! a (+ a 1))",
 	},
+	{name: "defmacro-inc-expand0-no-macro", src: "(macroexpand-0 '(list 1))", exp: "(list 1)"},
+	{name: "defmacro-expand",
+		src: "(defmacro inc (var) `(add! ,var 1)) (defmacro add! (var n) `(set! ,var (+ ,var ,n)))" +
+			" (macroexpand-0 '(inc a)) (macroexpand '(inc a)) (macroexpand 7)",
+		exp: "#<macro:inc> #<macro:add!> (add! a 1) (set! a (+ a 1)) 7",
+	},
+	{name: "defmacro-expand-all",
+		src: "(defmacro inc (var) `(set! ,var (+ ,var 1)))" +
+			" (macroexpand-all '(if (inc a) (list (inc b) '(inc c)) `(x ,(inc d) (inc e))))",
+		exp: "#<macro:inc> (if (set! a (+ a 1)) (list (set! b (+ b 1)) (quote (inc c))) (quasiquote (x (unquote (set! d (+ d 1))) (inc e))))",
+	},
+	{name: "defmacro-expand-all-shadow",
+		src: "(defmacro inc (var) `(set! ,var (+ ,var 1)))" +
+			" (macroexpand-all '(let ((inc list) (x (inc a))) (inc x)))" +
+			" (macroexpand-all '(let* ((inc list) (x (inc a))) (inc x)))" +
+			" (macroexpand-all '(lambda (a inc) (inc a)))" +
+			" (macroexpand-all '(defun f (a . inc) (inc a)))" +
+			" (macroexpand-all '(lambda \"g\" (a) (inc a)))",
+		exp: "#<macro:inc> (let ((inc list) (x (set! a (+ a 1)))) (inc x))" +
+			" (let* ((inc list) (x (inc a))) (inc x))" +
+			" (lambda (a inc) (inc a))" +
+			" (defun f (a . inc) (inc a))" +
+			" (lambda \"g\" (a) (set! a (+ a 1)))",
+	},
+	{name: "defmacro-expand-all-syntax-rules",
+		src: "(define-syntax my-if (syntax-rules () ((_ c a b) (if c a b))))" +
+			" (macroexpand-all '(my-if x 1 (my-if y 2 3)))",
+		exp: "#<syntax-rules:my-if> (if x 1 (if y 2 3))",
+	},
 	{name: "defmacro-eq-same",
 		src: "(defmacro inc1 (var) `(set! ,var (+ ,var 1))) (== inc1 inc1) (= inc1 inc1)",
 		exp: "#<macro:inc1> T T",
@@ -79,14 +79,14 @@ var Map = sxeval.Builtin{
 			lst = pair
 		}
 	},
-	Fn: func(env *sxeval.Environment, args sx.Vector, frame *sxeval.Frame) (sx.Object, error) {
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, frame *sxeval.Frame) (sx.Object, error) {
 		// fn must be checked first, because it is an error, if argument 0 is
 		// not a callable, even if the list is empty and fn will never be called.
-		fn, err := GetCallable(args[0], 0)
+		fn, err := GetCallable(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		lst, err := GetList(args[1], 1)
+		lst, err := GetList(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -99,6 +99,9 @@ var Map = sxeval.Builtin{
 		if err != nil {
 			return sx.Nil(), err
 		}
+		if err = AllocPairs(env, 1); err != nil {
+			return sx.Nil(), err
+		}
 		result := sx.Cons(val, sx.Nil())
 		curr := result
 		for {
@@ -121,6 +124,9 @@ var Map = sxeval.Builtin{
 			if err2 != nil {
 				return result, err2
 			}
+			if err2 = AllocPairs(env, 1); err2 != nil {
+				return result, err2
+			}
 			curr = curr.AppendBang(val2)
 			lst = pair
 		}
@@ -134,12 +140,12 @@ var Apply = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: nil, // Might be changed in the future
-	Fn: func(env *sxeval.Environment, args sx.Vector, frame *sxeval.Frame) (res sx.Object, err error) {
-		fn, err := GetCallable(args[0], 0)
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, frame *sxeval.Frame) (res sx.Object, err error) {
+		fn, err := GetCallable(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		lst, err := GetList(args[1], 1)
+		lst, err := GetList(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -174,16 +180,16 @@ var Fold = sxeval.Builtin{
 	MinArity: 3,
 	MaxArity: 3,
 	TestPure: nil, // Might be changed in the future
-	Fn: func(env *sxeval.Environment, args sx.Vector, frame *sxeval.Frame) (sx.Object, error) {
-		fn, err := GetCallable(args[0], 0)
+	Fn3: func(env *sxeval.Environment, arg0, arg1, arg2 sx.Object, frame *sxeval.Frame) (sx.Object, error) {
+		fn, err := GetCallable(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		lst, err := GetList(args[2], 2)
+		lst, err := GetList(arg2, 2)
 		if err != nil {
 			return nil, err
 		}
-		res := args[1]
+		res := arg1
 		params := sx.Vector{res, res}
 		for node := lst; node != nil; {
 			params[0] = node.Car()
@@ -208,12 +214,12 @@ var FoldReverse = sxeval.Builtin{
 	MinArity: 3,
 	MaxArity: 3,
 	TestPure: nil, // Might be changed in the future
-	Fn: func(env *sxeval.Environment, args sx.Vector, frame *sxeval.Frame) (sx.Object, error) {
-		fn, err := GetCallable(args[0], 0)
+	Fn3: func(env *sxeval.Environment, arg0, arg1, arg2 sx.Object, frame *sxeval.Frame) (sx.Object, error) {
+		fn, err := GetCallable(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		lst, err := GetList(args[2], 2)
+		lst, err := GetList(arg2, 2)
 		if err != nil {
 			return nil, err
 		}
@@ -221,7 +227,7 @@ var FoldReverse = sxeval.Builtin{
 		if err != nil {
 			return nil, err
 		}
-		res := args[1]
+		res := arg1
 		params := sx.Vector{res, res}
 		for node := rev; node != nil; {
 			params[0] = node.Car()
@@ -16,6 +16,8 @@ package sxbuiltins
 // Contains builtins to work with numbers.
 
 import (
+	"fmt"
+
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
 )
@@ -45,6 +47,17 @@ var Add = sxeval.Builtin{
 		num, err := GetNumber(arg, 0)
 		return num, err
 	},
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num0, err := GetNumber(arg0, 0)
+		if err != nil {
+			return nil, err
+		}
+		num1, err := GetNumber(arg1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return sx.NumAdd(num0, num1), nil
+	},
 	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
 		acc := sx.Number(sx.Int64(0))
 		for i := range len(args) {
@@ -71,6 +84,17 @@ var Sub = sxeval.Builtin{
 		}
 		return sx.NumNeg(num), nil
 	},
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num0, err := GetNumber(arg0, 0)
+		if err != nil {
+			return nil, err
+		}
+		num1, err := GetNumber(arg1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return sx.NumSub(num0, num1), nil
+	},
 	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
 		acc, err := GetNumber(args[0], 0)
 		if err != nil {
@@ -100,6 +124,17 @@ var Mul = sxeval.Builtin{
 		num, err := GetNumber(arg, 0)
 		return num, err
 	},
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num0, err := GetNumber(arg0, 0)
+		if err != nil {
+			return nil, err
+		}
+		num1, err := GetNumber(arg1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return sx.NumMul(num0, num1), nil
+	},
 	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
 		acc := sx.Number(sx.Int64(1))
 		for i := range len(args) {
@@ -119,12 +154,12 @@ var Div = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		num0, err := GetNumber(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num0, err := GetNumber(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		num1, err := GetNumber(args[1], 1)
+		num1, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -138,15 +173,46 @@ var Mod = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		num0, err := GetNumber(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num0, err := GetNumber(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		num1, err := GetNumber(args[1], 1)
+		num1, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
 		return sx.NumMod(num0, num1)
 	},
 }
+
+// NumberToString is the builtin that implements (number->string n [radix]).
+var NumberToString = sxeval.Builtin{
+	Name:     "number->string",
+	MinArity: 1,
+	MaxArity: 2,
+	TestPure: sxeval.AssertPure,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num, err := GetNumber(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		return sx.MakeString(num.String()), nil
+	},
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		num, err := GetNumber(arg0, 0)
+		if err != nil {
+			return nil, err
+		}
+		radix, err := GetNumber(arg1, 1)
+		if err != nil {
+			return nil, err
+		}
+		switch radix {
+		case sx.Int64(2), sx.Int64(8), sx.Int64(10), sx.Int64(16):
+		default:
+			return nil, fmt.Errorf("radix must be 2, 8, 10, or 16, but got %v", radix)
+		}
+		return sx.MakeString(num.(sx.Int64).FormatRadix(int(radix.(sx.Int64)))), nil
+	},
+}
@@ -119,4 +119,25 @@ var tcsNumber = tTestCases{
 	},
 	{name: "mod-full", src: "(mod 35 7)", exp: "0"},
 	{name: "mod-rest", src: "(mod 34 7)", exp: "6"},
+
+	{name: "err-number->string-0",
+		src:     "(number->string)",
+		exp:     "{[{number->string: between 1 and 2 arguments required, but none given}]}",
+		withErr: true,
+	},
+	{name: "err-number->string-nonum",
+		src:     `(number->string "1")`,
+		exp:     `{[{number->string: argument 1 is not a number, but sx.String/"1"}]}`,
+		withErr: true,
+	},
+	{name: "err-number->string-radix",
+		src:     "(number->string 10 3)",
+		exp:     "{[{number->string: radix must be 2, 8, 10, or 16, but got 3}]}",
+		withErr: true,
+	},
+	{name: "number->string", src: "(number->string #xff)", exp: `"255"`},
+	{name: "number->string-10", src: "(number->string 1_024 10)", exp: `"1024"`},
+	{name: "number->string-16", src: "(number->string -255 16)", exp: `"#x-ff"`},
+	{name: "number->string-8", src: "(number->string 0o17 8)", exp: `"#o17"`},
+	{name: "number->string-2", src: "(number->string 10 2)", exp: `"#b1010"`},
 }
@@ -38,6 +38,17 @@ func cmpMakeBuiltin(name string, cmpFn func(int) bool) sxeval.Builtin {
 		MinArity: 2,
 		MaxArity: -1,
 		TestPure: sxeval.AssertPure,
+		Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+			num0, err := GetNumber(arg0, 0)
+			if err != nil {
+				return nil, err
+			}
+			num1, err := GetNumber(arg1, 1)
+			if err != nil {
+				return nil, err
+			}
+			return sx.MakeBoolean(cmpFn(sx.NumCmp(num0, num1))), nil
+		},
 		Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
 			acc, err := GetNumber(args[0], 0)
 			if err != nil {
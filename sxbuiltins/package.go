@@ -14,6 +14,9 @@
 package sxbuiltins
 
 import (
+	"fmt"
+	"io"
+
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
 )
@@ -23,8 +26,8 @@ var CurrentPackage = sxeval.Builtin{
 	Name:     "current-package",
 	MinArity: 0,
 	MaxArity: 0,
-	Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
-		return sx.CurrentPackage(), nil
+	Fn0: func(env *sxeval.Environment, _ *sxeval.Frame) (sx.Object, error) {
+		return env.World().CurrentPackage(), nil
 	},
 }
 
@@ -33,9 +36,9 @@ var PackageList = sxeval.Builtin{
 	Name:     "package-list",
 	MinArity: 0,
 	MaxArity: 0,
-	Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
+	Fn0: func(env *sxeval.Environment, _ *sxeval.Frame) (sx.Object, error) {
 		var lb sx.ListBuilder
-		for pkg := range sx.AllPackages() {
+		for pkg := range env.World().AllPackages() {
 			lb.Add(pkg)
 		}
 		return lb.List(), nil
@@ -47,12 +50,12 @@ var FindPackage = sxeval.Builtin{
 	Name:     "find-package",
 	MinArity: 1,
 	MaxArity: 1,
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		s, err := GetString(arg, 0)
 		if err != nil {
 			return nil, err
 		}
-		return sx.FindPackage(s.GetValue()), nil
+		return env.World().FindPackage(s.GetValue()), nil
 	},
 }
 
@@ -62,8 +65,8 @@ var PackageSymbols = sxeval.Builtin{
 	Name:     "package-symbols",
 	MinArity: 0,
 	MaxArity: 1,
-	Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
-		return packageSymbols(sx.CurrentPackage())
+	Fn0: func(env *sxeval.Environment, _ *sxeval.Frame) (sx.Object, error) {
+		return packageSymbols(env.World().CurrentPackage())
 	},
 	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		pkg, err := GetPackage(arg, 0)
@@ -81,3 +84,414 @@ func packageSymbols(pkg *sx.Package) (sx.Object, error) {
 	}
 	return lb.List(), nil
 }
+
+// InPackage sets the current package of the world.
+var InPackage = sxeval.Builtin{
+	Name:     "in-package",
+	MinArity: 1,
+	MaxArity: 1,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		pkg, err := getPackageDesignator(env, arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err = env.World().SetCurrentPackage(pkg); err != nil {
+			return nil, err
+		}
+		return pkg, nil
+	},
+}
+
+// Export makes the given symbol, or the given list of symbols, external
+// symbols of a package. If no package is specified, the current package is
+// used.
+var Export = sxeval.Builtin{
+	Name:     "export",
+	MinArity: 1,
+	MaxArity: 2,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return packageSymbolsOp(env, arg, nil, (*sx.Package).Export)
+	},
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return packageSymbolsOp(env, arg0, arg1, (*sx.Package).Export)
+	},
+}
+
+// Import makes the given symbol, or the given list of symbols, present in a
+// package. If no package is specified, the current package is used.
+var Import = sxeval.Builtin{
+	Name:     "import",
+	MinArity: 1,
+	MaxArity: 2,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return packageSymbolsOp(env, arg, nil, (*sx.Package).Import)
+	},
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return packageSymbolsOp(env, arg0, arg1, (*sx.Package).Import)
+	},
+}
+
+func packageSymbolsOp(env *sxeval.Environment, arg, pkgArg sx.Object, op func(*sx.Package, ...*sx.Symbol) error) (sx.Object, error) {
+	var syms []*sx.Symbol
+	if sym, isSymbol := sx.GetSymbol(arg); isSymbol {
+		syms = append(syms, sym)
+	} else {
+		lst, err := GetList(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		for node := lst; node != nil; node = node.Tail() {
+			sym, errSym := GetSymbol(node.Car(), 0)
+			if errSym != nil {
+				return nil, errSym
+			}
+			syms = append(syms, sym)
+		}
+	}
+	pkg, err := getOptionalPackage(env, pkgArg, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err = op(pkg, syms...); err != nil {
+		return nil, err
+	}
+	return sx.T, nil
+}
+
+// UsePackage adds the given package, or the given list of packages, to the
+// packages used by a package. If no package is specified, the current package
+// is used.
+var UsePackage = sxeval.Builtin{
+	Name:     "use-package",
+	MinArity: 1,
+	MaxArity: 2,
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return usePackage(env, arg, nil)
+	},
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return usePackage(env, arg0, arg1)
+	},
+}
+
+func usePackage(env *sxeval.Environment, arg, pkgArg sx.Object) (sx.Object, error) {
+	var others []*sx.Package
+	if lst, isPair := sx.GetPair(arg); isPair {
+		for node := lst; node != nil; node = node.Tail() {
+			other, err := getPackageDesignator(env, node.Car(), 0)
+			if err != nil {
+				return nil, err
+			}
+			others = append(others, other)
+		}
+	} else {
+		other, err := getPackageDesignator(env, arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		others = append(others, other)
+	}
+	pkg, err := getOptionalPackage(env, pkgArg, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err = pkg.UsePackage(others...); err != nil {
+		return nil, err
+	}
+	return sx.T, nil
+}
+
+// getPackageDesignator returns the package denoted by the given argument. It
+// is either a package, or the name of a package, given as a string or as a
+// symbol.
+func getPackageDesignator(env *sxeval.Environment, arg sx.Object, pos int) (*sx.Package, error) {
+	if pkg, isPackage := sx.GetPackage(arg); isPackage {
+		return pkg, nil
+	}
+	name, isName := getName(arg)
+	if !isName {
+		return nil, fmt.Errorf("argument %d is not a package designator, but %T/%v", pos+1, arg, arg)
+	}
+	if pkg := env.World().FindPackage(name); pkg != nil {
+		return pkg, nil
+	}
+	return nil, fmt.Errorf("package %s not found", name)
+}
+
+// getOptionalPackage returns the package denoted by the given argument, or the
+// current package, if the argument is missing.
+func getOptionalPackage(env *sxeval.Environment, arg sx.Object, pos int) (*sx.Package, error) {
+	if arg == nil {
+		return env.World().CurrentPackage(), nil
+	}
+	return getPackageDesignator(env, arg, pos)
+}
+
+// getName returns the name given as a string or as a symbol.
+func getName(obj sx.Object) (string, bool) {
+	if s, isString := sx.GetString(obj); isString {
+		return s.GetValue(), true
+	}
+	if sym, isSymbol := sx.GetSymbol(obj); isSymbol {
+		return sym.GetValue(), true
+	}
+	return "", false
+}
+
+const defpackageName = "defpackage"
+
+// Keywords of the options of defpackage.
+var (
+	kwUse        = sx.KeywordPackage().MakeSymbol("use")
+	kwExport     = sx.KeywordPackage().MakeSymbol("export")
+	kwImportFrom = sx.KeywordPackage().MakeSymbol("import-from")
+	kwShadow     = sx.KeywordPackage().MakeSymbol("shadow")
+)
+
+// DefPackageS parses a (defpackage name option...) form. An option is one of
+// (:use pkg...), (:export name...), (:import-from pkg name...), and
+// (:shadow name...). Packages and symbols are specified by their names, given
+// as strings or as symbols. If no :use option is given, the new package uses
+// the package INIT.
+var DefPackageS = sxeval.Special{
+	Name: defpackageName,
+	Fn: func(_ *sxeval.ParseEnvironment, args *sx.Pair, _ *sxeval.Frame) (sxeval.Expr, error) {
+		if args == nil {
+			return nil, fmt.Errorf("need at least one argument")
+		}
+		name, isName := getName(args.Car())
+		if !isName {
+			return nil, fmt.Errorf("argument 1 must be a string or a symbol, but is: %T/%v", args.Car(), args.Car())
+		}
+		dpe := DefPackageExpr{Name: name}
+		hasUse := false
+		for node := args.Tail(); node != nil; node = node.Tail() {
+			opt, isPair := sx.GetPair(node.Car())
+			if !isPair {
+				return nil, fmt.Errorf("option must be a non-empty list, but is: %T/%v", node.Car(), node.Car())
+			}
+			names, err := getNames(opt.Tail())
+			if err != nil {
+				return nil, err
+			}
+			switch opt.Car() {
+			case kwUse:
+				dpe.Uses = append(dpe.Uses, names...)
+				hasUse = true
+			case kwExport:
+				dpe.Exports = append(dpe.Exports, names...)
+			case kwImportFrom:
+				if len(names) == 0 {
+					return nil, fmt.Errorf("package name missing in %v", opt)
+				}
+				dpe.Imports = append(dpe.Imports, names)
+			case kwShadow:
+				dpe.Shadows = append(dpe.Shadows, names...)
+			default:
+				return nil, fmt.Errorf("unknown option %v", opt.Car())
+			}
+		}
+		if !hasUse {
+			dpe.Uses = []string{sx.InitName}
+		}
+		return &dpe, nil
+	},
+	Walk: walkQuoted,
+}
+
+func getNames(lst *sx.Pair) ([]string, error) {
+	var result []string
+	for node := lst; node != nil; node = node.Tail() {
+		name, isName := getName(node.Car())
+		if !isName {
+			return nil, fmt.Errorf("name must be a string or a symbol, but is: %T/%v", node.Car(), node.Car())
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// DefPackageExpr stores data for a defpackage statement.
+type DefPackageExpr struct {
+	Name    string
+	Uses    []string
+	Exports []string
+	Imports [][]string // first element is the name of the package
+	Shadows []string
+}
+
+// IsPure signals an expression that has no side effects.
+func (*DefPackageExpr) IsPure() bool { return false }
+
+// Unparse the expression as an sx.Object
+func (dpe *DefPackageExpr) Unparse() sx.Object {
+	var lb sx.ListBuilder
+	lb.Add(sx.MakeSymbol(defpackageName))
+	lb.Add(sx.MakeString(dpe.Name))
+	if len(dpe.Shadows) > 0 {
+		lb.Add(makeNamesOption(kwShadow, dpe.Shadows))
+	}
+	lb.Add(makeNamesOption(kwUse, dpe.Uses))
+	for _, names := range dpe.Imports {
+		lb.Add(makeNamesOption(kwImportFrom, names))
+	}
+	if len(dpe.Exports) > 0 {
+		lb.Add(makeNamesOption(kwExport, dpe.Exports))
+	}
+	return lb.List()
+}
+
+func makeNamesOption(kw *sx.Symbol, names []string) *sx.Pair {
+	var lb sx.ListBuilder
+	lb.Add(kw)
+	for _, name := range names {
+		lb.Add(sx.MakeString(name))
+	}
+	return lb.List()
+}
+
+// Encode the expression for an image.
+func (dpe *DefPackageExpr) Encode(*sxeval.ImageEncoder) (string, sx.Vector, error) {
+	imports := make(sx.Vector, len(dpe.Imports))
+	for i, names := range dpe.Imports {
+		imports[i] = encodeNames(names)
+	}
+	return defpackageName, sx.Vector{
+		sx.MakeString(dpe.Name),
+		encodeNames(dpe.Uses),
+		encodeNames(dpe.Exports),
+		sx.MakeList(imports...),
+		encodeNames(dpe.Shadows),
+	}, nil
+}
+
+func decodeDefPackageExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	name, err := dec.DecodeString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	uses, err := decodeNames(dec, args[1])
+	if err != nil {
+		return nil, err
+	}
+	exports, err := decodeNames(dec, args[2])
+	if err != nil {
+		return nil, err
+	}
+	elems, err := dec.Elems(args[3])
+	if err != nil {
+		return nil, err
+	}
+	var imports [][]string
+	for _, elem := range elems {
+		names, err2 := decodeNames(dec, elem)
+		if err2 != nil {
+			return nil, err2
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("package name of import missing: %v", elem)
+		}
+		imports = append(imports, names)
+	}
+	shadows, err := decodeNames(dec, args[4])
+	if err != nil {
+		return nil, err
+	}
+	return &DefPackageExpr{Name: name, Uses: uses, Exports: exports, Imports: imports, Shadows: shadows}, nil
+}
+
+func encodeNames(names []string) *sx.Pair {
+	var lb sx.ListBuilder
+	for _, name := range names {
+		lb.Add(sx.MakeString(name))
+	}
+	return lb.List()
+}
+
+func decodeNames(dec *sxeval.ImageDecoder, obj sx.Object) ([]string, error) {
+	elems, err := dec.Elems(obj)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, elem := range elems {
+		name, err2 := dec.DecodeString(elem)
+		if err2 != nil {
+			return nil, err2
+		}
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+// Compute the expression in a frame and return the result.
+func (dpe *DefPackageExpr) Compute(env *sxeval.Environment, _ *sxeval.Frame) (sx.Object, error) {
+	world := env.World()
+	pkg := world.FindPackage(dpe.Name)
+	if pkg == nil {
+		var err error
+		if pkg, err = world.MakePackage(dpe.Name); err != nil {
+			return nil, err
+		}
+	}
+	pkg.Shadow(dpe.Shadows...)
+
+	uses := make([]*sx.Package, 0, len(dpe.Uses))
+	for _, name := range dpe.Uses {
+		used := world.FindPackage(name)
+		if used == nil {
+			return nil, fmt.Errorf("package %s not found", name)
+		}
+		uses = append(uses, used)
+	}
+	if err := pkg.UsePackage(uses...); err != nil {
+		return nil, err
+	}
+
+	for _, names := range dpe.Imports {
+		from := world.FindPackage(names[0])
+		if from == nil {
+			return nil, fmt.Errorf("package %s not found", names[0])
+		}
+		for _, name := range names[1:] {
+			sym := from.FindSymbol(name)
+			if sym == nil {
+				return nil, fmt.Errorf("symbol %s not found in %v", name, from)
+			}
+			if err := pkg.Import(sym); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	syms := make([]*sx.Symbol, 0, len(dpe.Exports))
+	for _, name := range dpe.Exports {
+		syms = append(syms, pkg.MakeSymbol(name))
+	}
+	if err := pkg.Export(syms...); err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// Print the expression on the given writer.
+func (dpe *DefPackageExpr) Print(w io.Writer) (int, error) {
+	length, err := fmt.Fprintf(w, "{DEFPACKAGE %q", dpe.Name)
+	if err != nil {
+		return length, err
+	}
+	for node := dpe.Unparse().(*sx.Pair).Tail().Tail(); node != nil; node = node.Tail() {
+		l, err2 := io.WriteString(w, " ")
+		length += l
+		if err2 != nil {
+			return length, err2
+		}
+		l, err2 = sx.Print(w, node.Car())
+		length += l
+		if err2 != nil {
+			return length, err2
+		}
+	}
+	l, err := io.WriteString(w, "}")
+	length += l
+	return length, err
+}
@@ -15,9 +15,13 @@ package sxbuiltins_test
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
 )
 
 func TestPackage(t *testing.T) {
@@ -57,3 +61,119 @@ var tcsPackage = tTestCases{
 	{name: "package-symbols-1-2-eq",
 		src: "(= (length (package-symbols)) (length (package-symbols (current-package))))", exp: "T"},
 }
+
+func TestPackageWorld(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	pkg := w.MustMakePackage("builtin-world")
+	if err := w.SetCurrentPackage(pkg); err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(createBinding()).SetWorld(w)
+	for _, tc := range []struct {
+		src string
+		exp sx.Object
+	}{
+		{"(current-package)", pkg},
+		{`(find-package "builtin-world")`, pkg},
+		{`(find-package "roundtrip")`, sx.Nil()},
+	} {
+		obj, err := sxreader.MakeReader(strings.NewReader(tc.src)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := env.Eval(obj, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tc.exp.IsEqual(res) {
+			t.Errorf("%s: expected %v, but got %v", tc.src, tc.exp, res)
+		}
+	}
+}
+
+func TestPackageSystem(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		src     string
+		exp     string
+		withErr bool
+	}{
+		{src: `(defpackage "geo" (:export area circle))`, exp: "#<package:geo>"},
+		{src: `(in-package "geo")`, exp: "#<package:geo>"},
+		{src: "(defun area (r) (* r r))", exp: "#<lambda:geo:area>"},
+		{src: "(defvar secret 7)", exp: "7"},
+		{src: "'(area secret)", exp: "(area secret)"},
+		{src: "(in-package 'INIT)", exp: "#<package:INIT>"},
+		{src: "(geo:area 3)", exp: "9"},
+		{src: "geo::secret", exp: "7"},
+		{src: "'(geo:area geo::secret)", exp: "(geo:area geo::secret)"},
+		{src: "geo:secret", exp: "symbol secret is not external in #<package:geo>", withErr: true},
+
+		{src: `(defpackage app (:use "INIT" "geo") (:import-from "geo" secret))`, exp: "#<package:app>"},
+		{src: "(in-package (find-package \"app\"))", exp: "#<package:app>"},
+		{src: "(area secret)", exp: "49"},
+		{src: "(export 'result)", exp: "T"},
+		{src: "(defvar result (area 2))", exp: "4"},
+		{src: "(in-package \"INIT\")", exp: "#<package:INIT>"},
+		{src: "app:result", exp: "4"},
+		{src: "(use-package \"app\")", exp: "T"},
+		{src: "result", exp: "4"},
+
+		{src: `(defpackage other (:export area))`, exp: "#<package:other>"},
+		{src: "(use-package 'other \"app\")", exp: "use of #<package:other> conflicts with geo:area in #<package:app>", withErr: true},
+		{src: "(import 'other:area \"app\")", exp: "import of other:area conflicts with geo:area in #<package:app>", withErr: true},
+		{src: `(defpackage shadowing (:shadow area) (:use "geo" "other"))`, exp: "#<package:shadowing>"},
+		{src: "(== 'shadowing::area 'geo:area)", exp: "()"},
+
+		{src: `(in-package "unknown")`, exp: "package unknown not found", withErr: true},
+		{src: "(in-package 7)", exp: "argument 1 is not a package designator, but sx.Int64/7", withErr: true},
+		{src: "(export 7)", exp: "argument 1 is not a list, but sx.Int64/7", withErr: true},
+		{src: "(defpackage)", exp: "need at least one argument", withErr: true},
+		{src: "(defpackage p (:unknown))", exp: "unknown option :unknown", withErr: true},
+		{src: "(defpackage p (:use 7))", exp: "name must be a string or a symbol, but is: sx.Int64/7", withErr: true},
+		{src: "(defpackage p (:import-from))", exp: "package name missing in (:import-from)", withErr: true},
+	}
+
+	w := sx.MakeWorld()
+	var sb strings.Builder
+	for _, tc := range testcases {
+		sb.WriteString(tc.src)
+		sb.WriteByte('\n')
+	}
+	rd := sxreader.MakeReader(strings.NewReader(sb.String())).SetWorld(w)
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := root.ExportBindings(); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	env := sxeval.MakeEnvironment(root.MakeChildBinding("package-system", 16)).SetWorld(w)
+	for _, tc := range testcases {
+		var got string
+		obj, err := rd.Read()
+		if err == nil {
+			var res sx.Object
+			if res, err = env.Eval(obj, nil); err == nil {
+				var out strings.Builder
+				_, _ = sx.Print(sx.WorldWriter{Writer: &out, World: w}, res)
+				got = out.String()
+			}
+		}
+		if err != nil {
+			if !tc.withErr {
+				t.Errorf("%s: unexpected error: %v", tc.src, err)
+				continue
+			}
+			got = err.Error()
+		} else if tc.withErr {
+			t.Errorf("%s: error expected, but got %v", tc.src, got)
+			continue
+		}
+		if !strings.HasSuffix(got, tc.exp) {
+			t.Errorf("%s: expected %q, but got %q", tc.src, tc.exp, got)
+		}
+	}
+}
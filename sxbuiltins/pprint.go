@@ -16,134 +16,76 @@ package sxbuiltins
 // Provides some function to pretty-print objects.
 
 import (
+	"fmt"
 	"io"
-	"os"
+	"strings"
 
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxpretty"
 )
 
-// Pretty writes the first argument to stdout.
-var Pretty = sxeval.Builtin{
-	Name:     "pp",
-	MinArity: 1,
-	MaxArity: 1,
-	TestPure: nil,
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
-		_, err := Print(os.Stdout, arg)
-		return sx.Nil(), err
-	},
-}
-
-// Print object to given writer in a pretty way.
-func Print(w io.Writer, obj sx.Object) (int, error) {
-	written, err := doPrint(w, obj, 0)
-	if err != nil {
-		return written, err
-	}
-	n, err := w.Write(bEOL)
-	return written + n, err
-}
-
-func doPrint(w io.Writer, obj sx.Object, indent int) (int, error) {
-	if sx.IsNil(obj) {
-		return w.Write(bNil)
-	}
-	if pair, isPair := sx.GetPair(obj); isPair {
-		return doPrintList(w, pair, indent)
-	}
-
-	return sx.Print(w, obj)
-}
-
-func doPrintList(w io.Writer, lst *sx.Pair, indent int) (int, error) {
-	written, errWrite := w.Write(bOpen)
-	if errWrite != nil {
-		return written, errWrite
-	}
-
-	pos := 0
-	mustIndent := false
-	for node := lst; ; pos++ {
-		if mustIndent {
-			n, err := writeIndent(w, indent+4)
-			written += n
+// Pretty writes the first argument in a pretty way, optionally respecting
+// the given positive line width as second argument. If the width is (), the
+// default width is used. The optional third argument specifies the port: T writes
+// to the output of the environment, () returns the result as a string.
+var Pretty = MakePretty(nil)
+
+// MakePretty creates a builtin "pp" that writes to the given writer, instead
+// of the output of the environment. If the writer is nil, the builtin writes
+// to the output of the environment.
+func MakePretty(w io.Writer) sxeval.Builtin {
+	pretty := func(env *sxeval.Environment, args sx.Vector) (sx.Object, error) {
+		width := sxpretty.DefaultWidth
+		if len(args) > 1 && !sx.IsNil(args[1]) {
+			num, err := GetNumber(args[1], 1)
 			if err != nil {
-				return written, err
+				return nil, err
 			}
-			mustIndent = false
-		}
-
-		n, err := doPrint(w, node.Car(), indent+1)
-		written += n
-		if err != nil {
-			return written, err
-		}
-
-		cdr := node.Cdr()
-		if sx.IsNil(cdr) {
-			break
+			if num.(sx.Int64) <= 0 {
+				return nil, fmt.Errorf("width must be positive, but got %v", num)
+			}
+			width = int(num.(sx.Int64))
 		}
-
-		if next, isPair := sx.GetPair(cdr); isPair {
-			contBytes, mi := calcContinuation(next, pos)
-			nContBytes, errContBytes := w.Write(contBytes)
-			written += nContBytes
-			if errContBytes != nil {
-				return written, errContBytes
+		toString := false
+		if len(args) > 2 {
+			if port := args[2]; sx.IsNil(port) {
+				toString = true
+			} else if !sx.T.IsEqual(port) {
+				return nil, fmt.Errorf("argument 3 must be T or (), but is: %T/%v", port, port)
 			}
-			node = next
-			mustIndent = mi
-			continue
 		}
-
-		n, err = w.Write(bDot)
-		written += n
-		if err != nil {
-			return written, err
+		if toString {
+			var sb strings.Builder
+			if _, err := sxpretty.MakePrinter(&sb).SetWidth(width).Print(args[0]); err != nil {
+				return nil, err
+			}
+			result := strings.TrimSuffix(sb.String(), "\n")
+			if err := AllocString(env, len(result)); err != nil {
+				return nil, err
+			}
+			return sx.MakeString(result), nil
 		}
-
-		n, err = doPrint(w, cdr, indent)
-		written += n
-		if err != nil {
-			return written, err
+		out := w
+		if out == nil {
+			out = env.Output()
 		}
-		break
+		_, err := sxpretty.MakePrinter(out).SetWidth(width).Print(args[0])
+		return sx.Nil(), err
 	}
-	n, errWrite := w.Write(bClose)
-	return written + n, errWrite
-}
-
-func calcContinuation(next *sx.Pair, pos int) ([]byte, bool) {
-	if pos == 0 && next.Car().IsAtom() {
-		return bSpace, false
+	return sxeval.Builtin{
+		Name:     "pp",
+		MinArity: 1,
+		MaxArity: 3,
+		TestPure: nil,
+		Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+			return pretty(env, sx.Vector{arg})
+		},
+		Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+			return pretty(env, args)
+		},
 	}
-	return bEOL, true
 }
 
-var (
-	bEOL   = []byte{'\n'}
-	bNil   = []byte{'(', ')'}
-	bOpen  = []byte{'('}
-	bSpace = []byte{' '}
-	bDot   = []byte{' ', '.', ' '}
-	bClose = []byte{')'}
-)
-
-// 80 spaces
-const spaces = "                                                                                "
-
-func writeIndent(w io.Writer, indent int) (int, error) {
-	var written int
-	for indent > len(spaces) {
-		n, err := io.WriteString(w, spaces)
-		written += n
-		if err != nil {
-			return written, err
-		}
-		indent -= len(spaces)
-	}
-	n, err := io.WriteString(w, spaces[:indent])
-	written += n
-	return written, err
-}
+// Print object to given writer in a pretty way.
+func Print(w io.Writer, obj sx.Object) (int, error) { return sxpretty.Print(w, obj) }
@@ -0,0 +1,100 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins_test
+
+import (
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+func TestPretty(t *testing.T) {
+	t.Parallel()
+	var sb strings.Builder
+	pp := sxbuiltins.MakePretty(&sb)
+	root := sxeval.MakeRootBinding(1)
+	env := sxeval.MakeEnvironment(root)
+	lst := sx.MakeList(sx.MakeSymbol("list"), sx.MakeSymbol("alpha"), sx.MakeSymbol("beta"))
+
+	if _, err := env.Apply(&pp, sx.Vector{lst}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Apply(&pp, sx.Vector{lst, sx.Int64(10)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Apply(&pp, sx.Vector{lst, sx.MakeString("10")}, nil); err == nil {
+		t.Error("error expected for width")
+	}
+	for _, width := range []sx.Int64{0, -1} {
+		_, err := env.Apply(&pp, sx.Vector{lst, width}, nil)
+		if exp := "width must be positive, but got " + width.String(); err == nil || !strings.Contains(err.Error(), exp) {
+			t.Errorf("error %q expected for width %v, but got %v", exp, width, err)
+		}
+	}
+	exp := "(list alpha beta)\n(list alpha\n      beta)\n"
+	if got := sb.String(); got != exp {
+		t.Errorf("expected %q, but got %q", exp, got)
+	}
+}
+
+func TestPrettyPort(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(8)
+	if err := sxeval.BindBuiltins(root, &sxbuiltins.Pretty, &sxbuiltins.List); err != nil {
+		t.Fatal(err)
+	}
+	if err := sxeval.BindSpecials(root, &sxbuiltins.QuoteS); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	var sb strings.Builder
+	env := sxeval.MakeEnvironment(root).SetOutput(&sb)
+	testcases := []struct {
+		src string
+		exp string
+	}{
+		{"(pp '(list alpha beta))", "()"},
+		{"(pp '(list alpha beta) 10 T)", "()"},
+		{"(pp '(list alpha beta) () ())", `"(list alpha beta)"`},
+		{"(pp '(list alpha beta) 10 ())", `"(list alpha\n      beta)"`},
+	}
+	for _, tc := range testcases {
+		obj, err := sxreader.MakeReader(strings.NewReader(tc.src)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := env.Eval(obj, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tc.src, err)
+			continue
+		}
+		if got := res.String(); got != tc.exp {
+			t.Errorf("%s should result in %s, but got %s", tc.src, tc.exp, got)
+		}
+	}
+	if exp, got := "(list alpha beta)\n(list alpha\n      beta)\n", sb.String(); got != exp {
+		t.Errorf("expected output %q, but got %q", exp, got)
+	}
+	obj, err := sxreader.MakeReader(strings.NewReader("(pp 1 () 'port)")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = env.Eval(obj, nil); err == nil {
+		t.Error("error expected for port")
+	}
+}
@@ -28,9 +28,11 @@ var prelude string
 
 // LoadPrelude reads and evaluates the standard prelude. In addition some symbols
 // (NIL, T, UNDEFINED) are bound, as well as needed builtins and special forms.
+// All symbols are interned in the package INIT. They are not exported.
 func LoadPrelude(root *sxeval.Binding) error {
+	initPkg := sx.InitPackage()
 	var val sx.Object
-	sym, val := sx.MakeSymbol("UNDEFINED"), sx.MakeUndefined()
+	sym, val := initPkg.MakeSymbol("UNDEFINED"), sx.MakeUndefined()
 	if !sym.IsFrozen() {
 		if err := sym.Bind(val); err != nil {
 			return err
@@ -41,7 +43,7 @@ func LoadPrelude(root *sxeval.Binding) error {
 		return err
 	}
 	if !sym.IsFrozen() {
-		sym, val = sx.MakeSymbol("NIL"), sx.Nil()
+		sym, val = initPkg.MakeSymbol("NIL"), sx.Nil()
 		if err := sym.Bind(val); err != nil {
 			return err
 		}
@@ -58,8 +60,10 @@ func LoadPrelude(root *sxeval.Binding) error {
 		return err
 	}
 
-	rd := sxreader.MakeReader(strings.NewReader(prelude))
-	env := sxeval.MakeEnvironment(root)
+	// A new world has INIT as its current package.
+	world := sx.MakeWorld()
+	rd := sxreader.MakeReader(strings.NewReader(prelude)).SetWorld(world)
+	env := sxeval.MakeEnvironment(root).SetWorld(world)
 	for {
 		form, err := rd.Read()
 		if err != nil {
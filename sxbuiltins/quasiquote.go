@@ -38,6 +38,17 @@ var QuasiquoteS = sxeval.Special{
 		qqp := qqParser{pe: pe}
 		return qqp.parseQQ(args.Car(), frame)
 	},
+	Walk: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (*sx.Pair, error) {
+		if args == nil {
+			return args, nil
+		}
+		qqp := qqParser{pe: pe}
+		obj, err := qqp.walkQQ(args.Car(), frame)
+		if err != nil {
+			return nil, err
+		}
+		return args.Tail().Cons(obj), nil
+	},
 }
 
 // UnquoteS parses the unquote symbol (and returns an error, because it is
@@ -66,14 +77,23 @@ func (qqp *qqParser) parse(obj sx.Object, frame *sxeval.Frame) (sxeval.Expr, err
 	return qqp.pe.Parse(obj, frame)
 }
 
+// getSymbol returns the object as a symbol, where aliases are resolved.
+func (qqp *qqParser) getSymbol(obj sx.Object, frame *sxeval.Frame) (*sx.Symbol, bool) {
+	sym, isSymbol := sx.GetSymbol(obj)
+	if isSymbol {
+		sym = qqp.pe.ResolveAlias(sym, frame)
+	}
+	return sym, isSymbol
+}
+
 func (qqp *qqParser) parseQQ(obj sx.Object, frame *sxeval.Frame) (sxeval.Expr, error) {
 	pair, isPair := sx.GetPair(obj)
 	if !isPair || pair == nil {
 		// `basic is the same as (quote basic), for any form basic that is not a list.
-		return sxeval.ObjExpr{Obj: obj}, nil
+		return sxeval.ObjExpr{Obj: qqp.pe.StripAliases(obj)}, nil
 	}
 	first := pair.Car()
-	if sym, isSymbol := sx.GetSymbol(first); isSymbol {
+	if sym, isSymbol := qqp.getSymbol(first, frame); isSymbol {
 		if sx.SymbolUnquote.IsEqual(sym) {
 			form, err := getUnquoteObj(sym, pair)
 			if err != nil {
@@ -107,6 +127,36 @@ func (qqp *qqParser) parseQQ(obj sx.Object, frame *sxeval.Frame) (sxeval.Expr, e
 	return combineArgs(args), nil
 }
 
+// walkQQ expands all macros within the unquoted forms of a quasi-quoted
+// object.
+func (qqp *qqParser) walkQQ(obj sx.Object, frame *sxeval.Frame) (sx.Object, error) {
+	pair, isPair := sx.GetPair(obj)
+	if !isPair || pair == nil {
+		return obj, nil
+	}
+	if sym, isSymbol := qqp.getSymbol(pair.Car(), frame); isSymbol {
+		if sx.SymbolUnquote.IsEqual(sym) || sx.SymbolUnquoteSplicing.IsEqual(sym) {
+			if form, err := getUnquoteObj(sym, pair); err == nil {
+				form, err = qqp.pe.MacroExpandAll(form, frame)
+				if err != nil {
+					return nil, err
+				}
+				return sx.MakeList(pair.Car(), form), nil
+			}
+			return obj, nil
+		}
+	}
+	car, err := qqp.walkQQ(pair.Car(), frame)
+	if err != nil {
+		return nil, err
+	}
+	cdr, err := qqp.walkQQ(pair.Cdr(), frame)
+	if err != nil {
+		return nil, err
+	}
+	return sx.Cons(car, cdr), nil
+}
+
 // combineArgs optimizes some cases for (append ...).
 //
 // (append) --> ()
@@ -252,7 +302,7 @@ func (qqp *qqParser) parseList(lst *sx.Pair, frame *sxeval.Frame) ([]sxeval.Expr
 	numArgs, realArgs := length, length
 	var form sxeval.Expr
 	if prevPair != nil {
-		if sym, isSymbol := sx.GetSymbol(prevPair.Car()); isSymbol {
+		if sym, isSymbol := qqp.getSymbol(prevPair.Car(), frame); isSymbol {
 			if sx.SymbolUnquote.IsEqual(sym) {
 				obj, err := getUnquoteObj(sym, prevPair)
 				if err != nil {
@@ -276,7 +326,7 @@ func (qqp *qqParser) parseList(lst *sx.Pair, frame *sxeval.Frame) ([]sxeval.Expr
 		last := lastPair.Cdr()
 		if !sx.IsNil(last) {
 			// `(x1 x2 x3 ... xn . atom) may be interpreted to mean (append [ x1] [ x2] [ x3] ... [ xn] (quote atom))
-			form = sxeval.ObjExpr{Obj: last}
+			form = sxeval.ObjExpr{Obj: qqp.pe.StripAliases(last)}
 			numArgs++
 		}
 	}
@@ -287,7 +337,7 @@ func (qqp *qqParser) parseList(lst *sx.Pair, frame *sxeval.Frame) ([]sxeval.Expr
 		elem := node.Car()
 		node = node.Tail()
 		if elemList, isPair := sx.GetPair(elem); isPair && elemList != nil {
-			if sym, isSymbol := sx.GetSymbol(elemList.Car()); isSymbol {
+			if sym, isSymbol := qqp.getSymbol(elemList.Car(), frame); isSymbol {
 				if sx.SymbolUnquote.IsEqual(sym) {
 					// -- [,form] is interpreted as (list form)
 					obj, err := getUnquoteObj(sym, elemList)
@@ -374,6 +424,28 @@ func (mle MakeListExpr) IsPure() bool { return mle.Elem.IsPure() }
 // Unparse the expression as an sx.Object
 func (mle MakeListExpr) Unparse() sx.Object { return sx.MakeList(mle.Elem.Unparse()) }
 
+// Children returns the element.
+func (mle MakeListExpr) Children() []sxeval.Expr { return []sxeval.Expr{mle.Elem} }
+
+// WithChildren returns an expression with the given element.
+func (MakeListExpr) WithChildren(children []sxeval.Expr) sxeval.Expr {
+	return MakeListExpr{Elem: children[0]}
+}
+
+// makeListName is the tag of a MakeListExpr in an image.
+const makeListName = "make-list"
+
+// Encode the expression for an image.
+func (mle MakeListExpr) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	elem, err := enc.EncodeExpr(mle.Elem)
+	return makeListName, sx.Vector{elem}, err
+}
+
+func decodeMakeListExpr(dec *sxeval.ImageDecoder, args sx.Vector) (sxeval.Expr, error) {
+	elem, err := dec.DecodeExpr(args[0])
+	return MakeListExpr{Elem: elem}, err
+}
+
 // Improve the expression into a possible simpler one.
 func (mle MakeListExpr) Improve(imp *sxeval.Improver) (sxeval.Expr, error) {
 	expr, err := imp.Improve(mle.Elem)
@@ -26,13 +26,20 @@ import (
 // QuoteS parses the quote syntax.
 var QuoteS = sxeval.Special{
 	Name: sx.SymbolQuote.String(),
-	Fn: func(_ *sxeval.ParseEnvironment, args *sx.Pair, _ *sxeval.Frame) (sxeval.Expr, error) {
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, _ *sxeval.Frame) (sxeval.Expr, error) {
 		if sx.IsNil(args) {
 			return nil, sxeval.ErrNoArgs
 		}
 		if args.Tail() != nil {
 			return nil, fmt.Errorf("more than one argument: %v", args)
 		}
-		return sxeval.ObjExpr{Obj: args.Car()}, nil
+		return sxeval.ObjExpr{Obj: pe.StripAliases(args.Car())}, nil
 	},
+	Walk: walkQuoted,
+}
+
+// walkQuoted returns the arguments of a special form unchanged, because
+// they are not evaluated.
+func walkQuoted(_ *sxeval.ParseEnvironment, args *sx.Pair, _ *sxeval.Frame) (*sx.Pair, error) {
+	return args, nil
 }
@@ -0,0 +1,344 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins
+
+// Contains a configuration to compute untrusted code.
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+// Group is a set of builtins that belong together. Groups can be combined.
+type Group uint
+
+// Constants for all groups.
+const (
+	GroupData    Group = 1 << iota // numbers, symbols, vectors, sequences
+	GroupList                      // lists and higher-order functions
+	GroupString                    // strings
+	GroupEval                      // evaluation and introspection
+	GroupPackage                   // packages
+	GroupIO                        // input / output
+
+	GroupNone Group = 0
+	GroupAll        = GroupData | GroupList | GroupString | GroupEval | GroupPackage | GroupIO
+)
+
+// coreSpecials are the special forms that are always bound in a sandbox.
+var coreSpecials = []*sxeval.Special{
+	&QuoteS, &QuasiquoteS, // quote, quasiquote
+	&UnquoteS, &UnquoteSplicingS, // unquote, unquote-splicing
+	&DefVarS,          // defvar
+	&DefunS, &LambdaS, // defun, lambda
+	&DefDynS, &DynLambdaS, // defdyn, dyn-lambda
+	&DefMacroS,       //  defmacro
+	&LetS, &LetStarS, // let, let*
+	&SetXS,            // set!
+	&IfS,              // if
+	&BeginS, &Begin1S, // begin, begin1
+	&AndS, &OrS, // and, or
+	&DefineSyntaxS, &SyntaxRulesS, // define-syntax, syntax-rules
+	&CatchS, &TryS, &UnwindProtectS, // catch, try, unwind-protect
+	&LetECS, // let/ec
+}
+
+// coreBuiltins are the builtins that are always bound in a sandbox.
+var coreBuiltins = []*sxeval.Builtin{
+	&Equal, &Identical, // =, ==
+	&Not,           // not
+	&NullP,         // null?
+	&CallableP,     // callable?
+	&Error,         // error
+	&NotBoundError, // not-bound-error
+	&Throw, &Raise, // throw, raise
+	&CallWithEscapeContinuation, &CallEC, // call-with-escape-continuation, call/ec
+	&ConditionP, &ConditionKind, // condition?, condition-kind
+	&ConditionMessage, &ConditionIrritants, // condition-message, condition-irritants
+}
+
+// groupBuiltins lists all builtins of a group.
+var groupBuiltins = map[Group][]*sxeval.Builtin{
+	GroupData: {
+		&SymbolP, &KeywordP, // symbol?, keyword?
+		&MakeSymbol, &GenSym, // make-symbol, gensym
+		&NumberP,         // number?
+		&Add, &Sub, &Mul, // +, -, *
+		&Div, &Mod, // div, mod
+		&NumLess, &NumLessEqual, // <, <=
+		&NumGreater, &NumGreaterEqual, // >, >=
+		&Vector, &VectorP, // vector, vector?
+		&VectorSetBang,        // vset!
+		&Length, &LengthEqual, // length, length=
+		&LengthLess, &LengthGreater, // length<, length>
+		&Nth,           // nth
+		&Sequence2List, // seq->list
+	},
+	GroupList: {
+		&Cons,          // cons
+		&PairP, &ListP, // pair?, list?
+		&Car, &Cdr, // car, cdr
+		&Caar, &Cadr, &Cdar, &Cddr,
+		&Caaar, &Caadr, &Cadar, &Caddr,
+		&Cdaar, &Cdadr, &Cddar, &Cdddr,
+		&Caaaar, &Caaadr, &Caadar, &Caaddr,
+		&Cadaar, &Cadadr, &Caddar, &Cadddr,
+		&Cdaaar, &Cdaadr, &Cdadar, &Cdaddr,
+		&Cddaar, &Cddadr, &Cdddar, &Cddddr,
+		&Last,            // last
+		&List, &ListStar, // list, list*
+		&Append,    // append
+		&Reverse,   // reverse
+		&Assoc,     // assoc
+		&All, &Any, // all, any
+		&Map,                // map
+		&Apply,              // apply
+		&Fold, &FoldReverse, // fold, fold-reverse
+		&List2Vector, // list->vector
+	},
+	GroupString: {
+		&ToString, &Concat, // ->string, concat
+		&NumberToString, // number->string
+	},
+	GroupEval: {
+		&SymbolValue, &FrozenSymbolValue, // symbol-value, frozen-symbol-value
+		&SetSymbolValue, &FreezeSymbolValue, // set-symbol-value, freeze-symbol-value
+		&DefinedP, &SymbolBoundP, // defined?, symbol-bound?
+		&ResolveSymbol,              // resolve-symbol
+		&Macroexpand0,               // macroexpand-0
+		&Macroexpand,                // macroexpand
+		&MacroexpandAll,             // macroexpand-all
+		&CurrentFrame, &ParentFrame, // current-frame, parent-frame
+		&Bindings, &FrameLookup, // bindings, frame-lookup
+		&ParseExpression, &UnparseExpression, // parse-expression, unparse-expression
+		&ConditionStack,           // condition-stack
+		&ExecuteExpression, &Eval, // execute-expression, eval
+	},
+	GroupPackage: {
+		&SymbolPackage,             // symbol-package
+		&CurrentPackage,            // current-package
+		&PackageList, &FindPackage, // package-list, find-package
+		&PackageSymbols,  // package-symbols
+		&InPackage,       // in-package
+		&Export, &Import, // export, import
+		&UsePackage, // use-package
+	},
+	GroupIO: {
+		&Pretty, // pp
+	},
+}
+
+// groupSpecials lists all special forms of a group.
+var groupSpecials = map[Group][]*sxeval.Special{
+	GroupPackage: {
+		&DefPackageS, // defpackage
+	},
+}
+
+// dangerousBuiltins are not bound by default, even if their group is enabled.
+// Builtins that change packages are dangerous, because the packages INIT and
+// KEYWORD are shared by all worlds.
+var dangerousBuiltins = map[*sxeval.Builtin]bool{
+	&Eval:              true,
+	&ExecuteExpression: true,
+	&SetSymbolValue:    true,
+	&FreezeSymbolValue: true,
+	&CurrentFrame:      true,
+	&InPackage:         true,
+	&Export:            true,
+	&Import:            true,
+	&UsePackage:        true,
+}
+
+// dangerousSpecials are not bound by default, even if their group is enabled.
+var dangerousSpecials = map[*sxeval.Special]bool{
+	&DefPackageS: true,
+}
+
+// Default limits of a sandbox.
+const (
+	DefaultSandboxSteps   = 10_000_000
+	DefaultSandboxNesting = 1_000
+	DefaultSandboxMemory  = 64 << 20
+	DefaultSandboxTimeout = 10 * time.Second
+)
+
+// Sandbox is a configuration to compute untrusted code. It specifies the
+// builtins that are available, as well as limits for computation steps,
+// nesting, memory allocation, and computing time.
+type Sandbox struct {
+	groups  Group
+	allowed map[string]bool
+	steps   int
+	nesting int
+	memory  int
+	timeout time.Duration
+	out     io.Writer
+	world   *sx.World
+}
+
+// MakeSandbox creates a new sandbox configuration. By default, the groups
+// GroupData, GroupList, and GroupString are enabled, and the default limits
+// are used.
+func MakeSandbox() *Sandbox {
+	return &Sandbox{
+		groups:  GroupData | GroupList | GroupString,
+		allowed: map[string]bool{},
+		steps:   DefaultSandboxSteps,
+		nesting: DefaultSandboxNesting,
+		memory:  DefaultSandboxMemory,
+		timeout: DefaultSandboxTimeout,
+		out:     io.Discard,
+		world:   sx.MakeWorld(),
+	}
+}
+
+// SetGroups sets the groups of builtins that are available.
+func (sb *Sandbox) SetGroups(groups Group) *Sandbox {
+	sb.groups = groups
+	return sb
+}
+
+// Allow enables dangerous builtins and special forms, like `eval`,
+// `execute-expression`, `set-symbol-value`, `freeze-symbol-value`,
+// `current-frame`, `in-package`, `export`, `import`, `use-package`, and
+// `defpackage`. They are only available, if their group is enabled too.
+func (sb *Sandbox) Allow(names ...string) *Sandbox {
+	for _, name := range names {
+		sb.allowed[name] = true
+	}
+	return sb
+}
+
+// SetStepsLimit sets the maximum number of computation steps.
+func (sb *Sandbox) SetStepsLimit(steps int) *Sandbox {
+	sb.steps = steps
+	return sb
+}
+
+// SetNestingLimit sets the maximum nesting of computations. It is also used
+// as the maximum nesting of objects read by the reader.
+func (sb *Sandbox) SetNestingLimit(nesting int) *Sandbox {
+	sb.nesting = nesting
+	return sb
+}
+
+// SetMemoryLimit sets the maximum number of bytes allocated by builtins.
+func (sb *Sandbox) SetMemoryLimit(size int) *Sandbox {
+	sb.memory = size
+	return sb
+}
+
+// SetTimeout sets the maximum duration of a computation. A value of 0
+// signals no limit.
+func (sb *Sandbox) SetTimeout(timeout time.Duration) *Sandbox {
+	sb.timeout = timeout
+	return sb
+}
+
+// SetOutput sets the writer, where builtins like `pp` write their output. By
+// default, the output is discarded.
+func (sb *Sandbox) SetOutput(w io.Writer) *Sandbox {
+	sb.out = w
+	return sb
+}
+
+// RootBinding creates a new, frozen root binding with all enabled builtins.
+// The prelude is always loaded, together with the builtins it needs. The root
+// binding is isolated, i.e. symbol values are not shared with other bindings.
+//
+// If `defpackage` is allowed, all symbols of the root binding are exported
+// from the package INIT, so that they are accessible in new packages.
+func (sb *Sandbox) RootBinding() (*sxeval.Binding, error) {
+	root := sxeval.MakeIsolatedRootBinding(len(coreSpecials) + len(coreBuiltins) + 128)
+	if err := LoadPrelude(root); err != nil {
+		return nil, err
+	}
+	if err := sxeval.BindSpecials(root, coreSpecials...); err != nil {
+		return nil, err
+	}
+	if err := sxeval.BindBuiltins(root, coreBuiltins...); err != nil {
+		return nil, err
+	}
+	export := false
+	for group := GroupData; group <= GroupIO; group <<= 1 {
+		if sb.groups&group == 0 {
+			continue
+		}
+		for _, sp := range groupSpecials[group] {
+			if dangerousSpecials[sp] && !sb.allowed[sp.Name] {
+				continue
+			}
+			if err := sxeval.BindSpecials(root, sp); err != nil {
+				return nil, err
+			}
+			export = export || sp == &DefPackageS
+		}
+		for _, b := range groupBuiltins[group] {
+			if dangerousBuiltins[b] && !sb.allowed[b.Name] {
+				continue
+			}
+			if err := sxeval.BindBuiltins(root, b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if export {
+		if err := root.ExportBindings(); err != nil {
+			return nil, err
+		}
+	}
+	root.Freeze()
+	return root, nil
+}
+
+// World returns the world of packages of the sandbox. Each sandbox has its
+// own world, so that changing the current package or creating a package
+// does not affect other computations.
+func (sb *Sandbox) World() *sx.World { return sb.world }
+
+// MakeReader creates a reader that respects the nesting limit of the sandbox,
+// and that uses the world of the sandbox.
+func (sb *Sandbox) MakeReader(r io.Reader) *sxreader.Reader {
+	return sxreader.MakeReader(r).SetWorld(sb.world).SetNestingLimit(uint(max(sb.nesting, 0)))
+}
+
+// MakeEnvironment creates an environment that respects the limits of the
+// sandbox, and that uses the world of the sandbox. The given binding should
+// be a descendant of the root binding created by the sandbox.
+func (sb *Sandbox) MakeEnvironment(bind *sxeval.Binding) *sxeval.Environment {
+	handler := sxeval.MakeStepsLimitHandler(sb.steps,
+		sxeval.MakeNestingLimitHandler(sb.nesting, sxeval.DefaultHandler{}))
+	return sxeval.MakeEnvironment(bind).
+		SetComputeHandler(handler).
+		SetAllocHandler(sxeval.MakeMemoryLimitHandler(sb.memory)).
+		SetOutput(sb.out).
+		SetWorld(sb.world)
+}
+
+// Eval parses and runs the given object in the environment, respecting the
+// timeout of the sandbox.
+func (sb *Sandbox) Eval(ctx context.Context, env *sxeval.Environment, obj sx.Object) (sx.Object, error) {
+	if sb.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sb.timeout)
+		defer cancel()
+	}
+	return env.EvalContext(ctx, obj, nil)
+}
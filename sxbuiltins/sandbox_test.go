@@ -0,0 +1,209 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+)
+
+func TestSandbox(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		name  string
+		sb    *sxbuiltins.Sandbox
+		src   string
+		exp   string
+		check func(error) bool
+	}{
+		{name: "compute", sb: sxbuiltins.MakeSandbox(),
+			src: "(defun f (n) (if (= n 0) 1 (* n (f (- n 1))))) (f 10)", exp: "3628800"},
+		{name: "cond", sb: sxbuiltins.MakeSandbox(),
+			src: "(cond ((= 1 2) 'a) (T 'b))", exp: "b"},
+		{name: "no-eval", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(eval '(+ 1 2))", check: isNotBound},
+		{name: "allowed-eval", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll).Allow("eval"),
+			src: "(eval '(+ 1 2))", exp: "3"},
+		{name: "allowed-eval-no-group", sb: sxbuiltins.MakeSandbox().Allow("eval"),
+			src: "(eval '(+ 1 2))", check: isNotBound},
+		{name: "no-set-symbol-value", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(set-symbol-value 'a 1)", check: isNotBound},
+		{name: "no-current-frame", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(current-frame)", check: isNotBound},
+		{name: "no-string", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupData),
+			src: `(concat "a" "b")`, check: isNotBound},
+		{name: "no-io", sb: sxbuiltins.MakeSandbox(),
+			src: "(pp 1)", check: isNotBound},
+		{name: "io-pp", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(pp '(a b) () ())", exp: `"(a b)"`},
+		{name: "io-pp-discard", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(pp '(a b))", exp: "()"},
+		{name: "no-in-package", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(in-package (current-package))", check: isNotBound},
+		{name: "no-defpackage", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src: "(defpackage sandbox)", check: isNotBound},
+		{name: "steps", sb: sxbuiltins.MakeSandbox().SetStepsLimit(1000),
+			src:   "(defun f (n) (f (+ n 1))) (f 0)",
+			check: func(err error) bool { var e sxeval.ErrStepsLimit; return errors.As(err, &e) }},
+		{name: "nesting", sb: sxbuiltins.MakeSandbox().SetNestingLimit(100),
+			src:   "(defun f (n) (+ 1 (f n))) (f 0)",
+			check: func(err error) bool { var e sxeval.ErrNestingLimit; return errors.As(err, &e) }},
+		{name: "memory", sb: sxbuiltins.MakeSandbox().SetMemoryLimit(1000),
+			src:   "(defun f (l) (f (cons 1 l))) (f ())",
+			check: func(err error) bool { var e sxeval.ErrMemoryLimit; return errors.As(err, &e) }},
+		{name: "timeout", sb: sxbuiltins.MakeSandbox().SetStepsLimit(math.MaxInt).SetTimeout(10 * time.Millisecond),
+			src:   "(defun f (n) (f (+ n 1))) (f 0)",
+			check: func(err error) bool { return errors.Is(err, context.DeadlineExceeded) }},
+		{name: "expand-loop", sb: sxbuiltins.MakeSandbox(),
+			src:   "(define-syntax loop (syntax-rules () ((_ x) (loop x)))) (loop 1)",
+			check: func(err error) bool { var e sxeval.ErrExpandLimit; return errors.As(err, &e) }},
+		{name: "expand-nested", sb: sxbuiltins.MakeSandbox(),
+			src:   "(define-syntax loop (syntax-rules () ((_ x) (if x (loop x) ())))) (loop 1)",
+			check: func(err error) bool { var e sxeval.ErrExpandLimit; return errors.As(err, &e) }},
+		{name: "expand-macroexpand", sb: sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll),
+			src:   "(defmacro loop (x) (list 'loop x)) (macroexpand '(loop 1))",
+			check: func(err error) bool { var e sxeval.ErrExpandLimit; return errors.As(err, &e) }},
+		{name: "read-nesting", sb: sxbuiltins.MakeSandbox().SetNestingLimit(3),
+			src:   "'((((a))))",
+			check: func(err error) bool { return err != nil }},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			root, err := tc.sb.RootBinding()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !root.IsFrozen() {
+				t.Error("root binding must be frozen")
+			}
+			env := tc.sb.MakeEnvironment(root.MakeChildBinding(tc.name, 8))
+			var res sx.Object
+			objs, err := tc.sb.MakeReader(strings.NewReader(tc.src)).ReadAll()
+			for _, obj := range objs {
+				if res, err = tc.sb.Eval(context.Background(), env, obj); err != nil {
+					break
+				}
+			}
+			if tc.check != nil {
+				if !tc.check(err) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.String(); got != tc.exp {
+				t.Errorf("expected %q, but got %q", tc.exp, got)
+			}
+		})
+	}
+}
+
+func isNotBound(err error) bool {
+	var e sxeval.NotBoundError
+	return errors.As(err, &e)
+}
+
+// TestSandboxAll checks that a sandbox with all groups and all dangerous
+// builtins provides the same builtins as BindAll.
+func TestSandboxAll(t *testing.T) {
+	t.Parallel()
+	root, err := sxbuiltins.MakeSandbox().
+		SetGroups(sxbuiltins.GroupAll).
+		Allow("eval", "execute-expression", "set-symbol-value", "freeze-symbol-value", "current-frame",
+			"in-package", "export", "import", "use-package", "defpackage").
+		RootBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := sxeval.MakeRootBinding(256)
+	if err = sxbuiltins.BindAll(all); err != nil {
+		t.Fatal(err)
+	}
+	for node := all.Bindings(); node != nil; node = node.Tail() {
+		sym := node.Car().(*sx.Pair).Car().(*sx.Symbol)
+		if _, found := root.Lookup(sym); !found {
+			t.Errorf("symbol %v not bound in sandbox", sym)
+		}
+	}
+}
+
+func TestSandboxOutput(t *testing.T) {
+	t.Parallel()
+	var sb strings.Builder
+	box := sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupIO).SetOutput(&sb)
+	root, err := box.RootBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := box.MakeReader(strings.NewReader("(pp '(a b))")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = box.Eval(context.Background(), box.MakeEnvironment(root), obj); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := sb.String(), "(a b)\n"; got != exp {
+		t.Errorf("expected output %q, but got %q", exp, got)
+	}
+}
+
+func TestSandboxWorld(t *testing.T) {
+	t.Parallel()
+	current := sx.DefaultWorld().CurrentPackage()
+	box := sxbuiltins.MakeSandbox().SetGroups(sxbuiltins.GroupAll).Allow("in-package", "defpackage")
+	root, err := box.RootBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := box.MakeEnvironment(root.MakeChildBinding("world", 8))
+	src := `(defpackage "sandbox-world") (in-package (find-package "sandbox-world")) (defvar x 1) 'x`
+	rd := box.MakeReader(strings.NewReader(src))
+	var res sx.Object
+	for {
+		obj, err2 := rd.Read()
+		if err2 == io.EOF {
+			break
+		}
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+		if res, err = box.Eval(context.Background(), env, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg := box.World().FindPackage("sandbox-world")
+	if pkg == nil || box.World().CurrentPackage() != pkg {
+		t.Errorf("package of sandbox must be current in its world, but got %v", box.World().CurrentPackage())
+	}
+	if got := sx.DefaultWorld().CurrentPackage(); got != current {
+		t.Errorf("current package of default world changed from %v to %v", current, got)
+	}
+	if sx.DefaultWorld().FindPackage("sandbox-world") != nil {
+		t.Error("package of sandbox must not be created in default world")
+	}
+	if sym, isSymbol := sx.GetSymbol(res); !isSymbol || sym.Package() != pkg {
+		t.Errorf("symbol must be read in package of sandbox, but got %v", res)
+	}
+}
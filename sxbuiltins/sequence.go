@@ -42,12 +42,12 @@ var LengthLess = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		seq, err := GetSequence(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		seq, err := GetSequence(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		n, err := GetNumber(args[1], 1)
+		n, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -61,12 +61,12 @@ var LengthGreater = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		seq, err := GetSequence(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		seq, err := GetSequence(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		n, err := GetNumber(args[1], 1)
+		n, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -80,12 +80,12 @@ var LengthEqual = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		seq, err := GetSequence(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		seq, err := GetSequence(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		n, err := GetNumber(args[1], 1)
+		n, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -99,12 +99,12 @@ var Nth = sxeval.Builtin{
 	MinArity: 2,
 	MaxArity: 2,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		seq, err := GetSequence(args[0], 0)
+	Fn2: func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		seq, err := GetSequence(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		n, err := GetNumber(args[1], 1)
+		n, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
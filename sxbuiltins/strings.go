@@ -46,19 +46,22 @@ var Concat = sxeval.Builtin{
 	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		return GetString(arg, 0)
 	},
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		s, err := GetString(args[0], 0)
-		if err != nil {
-			return nil, err
-		}
-		var sb strings.Builder
-		sb.WriteString(s.GetValue())
-		for i := 1; i < len(args); i++ {
-			s, err = GetString(args[i], i)
+	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+		size := 0
+		for i, arg := range args {
+			s, err := GetString(arg, i)
 			if err != nil {
 				return nil, err
 			}
-			sb.WriteString(s.GetValue())
+			size += len(s.GetValue())
+		}
+		if err := AllocString(env, size); err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		sb.Grow(size)
+		for _, arg := range args {
+			sb.WriteString(arg.(sx.String).GetValue())
 		}
 		return sx.MakeString(sb.String()), nil
 	},
@@ -54,4 +54,8 @@ var tcsStrings = tTestCases{
 	},
 	{name: "concat-1", src: `(concat "a")`, exp: `"a"`},
 	{name: "concat-3", src: `(concat "3" " " "4")`, exp: `"3 4"`},
+
+	{name: "raw-string", src: `(concat #r"\d" #r#""x""#)`, exp: `"\\d\"x\""`},
+	{name: "interpolate-none", src: `#i"a\$b"`, exp: `"a$b"`},
+	{name: "interpolate", src: `(let ((n 3)) #i"n=${n}, sq=${(* n n)}, ${'(a b)}")`, exp: `"n=3, sq=9, (a b)"`},
 }
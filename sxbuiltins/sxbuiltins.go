@@ -16,6 +16,7 @@ package sxbuiltins
 
 import (
 	"fmt"
+	"unsafe"
 
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
@@ -117,6 +118,27 @@ func GetPackage(arg sx.Object, pos int) (*sx.Package, error) {
 	return nil, fmt.Errorf("argument %d is not a package, but %T/%v", pos+1, arg, arg)
 }
 
+// ----- Allocation
+
+// Estimated sizes of allocated objects.
+const (
+	sizePair   = int(unsafe.Sizeof(sx.Pair{}))
+	sizeObject = int(unsafe.Sizeof(sx.Object(nil)))
+	sizeString = int(unsafe.Sizeof(sx.String{}))
+)
+
+// AllocPairs charges the memory of the given number of pairs (list elements)
+// against the allocation budget of the environment.
+func AllocPairs(env *sxeval.Environment, n int) error { return env.Alloc(n * sizePair) }
+
+// AllocVector charges the memory of a vector with the given number of
+// elements against the allocation budget of the environment.
+func AllocVector(env *sxeval.Environment, n int) error { return env.Alloc(n * sizeObject) }
+
+// AllocString charges the memory of a string with the given number of bytes
+// against the allocation budget of the environment.
+func AllocString(env *sxeval.Environment, n int) error { return env.Alloc(sizeString + n) }
+
 // ----- BindAll
 
 // BindAll binds all builtins / spacial forms to the given binding.
@@ -133,6 +155,10 @@ func BindAll(bind *sxeval.Binding) error {
 		&IfS,              // if
 		&BeginS, &Begin1S, // begin, begin1
 		&AndS, &OrS, // and, or
+		&DefineSyntaxS, &SyntaxRulesS, // define-syntax, syntax-rules
+		&CatchS, &TryS, &UnwindProtectS, // catch, try, unwind-protect
+		&LetECS,      // let/ec
+		&DefPackageS, // defpackage
 	)
 	if err != nil {
 		return err
@@ -141,6 +167,7 @@ func BindAll(bind *sxeval.Binding) error {
 		&Equal, &Identical, // =, ==
 		&SymbolP, &KeywordP, // symbol?, keyword?
 		&SymbolPackage, &SymbolValue, // symbol-package, symbol-value
+		&MakeSymbol, &GenSym, // make-symbol, gensym
 		&FreezeSymbolValue, &FrozenSymbolValue, // freeze-symbol-value, frozen-symbol-value
 		&SetSymbolValue, // set-symbol-value (temp)
 		&NullP,          // null?
@@ -167,6 +194,7 @@ func BindAll(bind *sxeval.Binding) error {
 		&NumberP,         // number?
 		&Add, &Sub, &Mul, // +, -, *
 		&Div, &Mod, // div, mod
+		&NumberToString,         // number->string
 		&NumLess, &NumLessEqual, // <, <=
 		&NumGreater, &NumGreaterEqual, // >, >=
 		&ToString, &Concat, // ->string, concat
@@ -179,6 +207,8 @@ func BindAll(bind *sxeval.Binding) error {
 		&Sequence2List,     // seq->list
 		&CallableP,         // callable?
 		&Macroexpand0,      // macroexpand-0
+		&Macroexpand,       // macroexpand
+		&MacroexpandAll,    // macroexpand-all
 		&DefinedP,          // defined?
 		&CurrentFrame,      // current-frame
 		&ParentFrame,       // parent-frame
@@ -194,9 +224,43 @@ func BindAll(bind *sxeval.Binding) error {
 		&ExecuteExpression, // execute-expression
 		&Eval,              // eval
 
+		&Throw, &Raise, // throw, raise
+		&CallWithEscapeContinuation, &CallEC, // call-with-escape-continuation, call/ec
+		&ConditionP, &ConditionKind, // condition?, condition-kind
+		&ConditionMessage, &ConditionIrritants, // condition-message, condition-irritants
+		&ConditionStack, // condition-stack
+
 		&CurrentPackage,            // current-package
 		&PackageList, &FindPackage, // package-list, find-package
-		&PackageSymbols, // package-symbols
+		&PackageSymbols,  // package-symbols
+		&InPackage,       // in-package
+		&Export, &Import, // export, import
+		&UsePackage, // use-package
 	)
 	return err
 }
+
+// AddImageDecoders adds the decoders of all expressions and procedures of
+// this package to the given registry, so that images can be written and read.
+func AddImageDecoders(reg *sxeval.ImageRegistry) {
+	reg.AddExprDecoder(lambdaName, 7, decodeLambdaExpr)
+	reg.AddExprDecoder(letName, 5, decodeLetExpr)
+	reg.AddExprDecoder(letStarName, 5, decodeLetStarExpr)
+	reg.AddExprDecoder(beginName, 2, decodeBeginExpr)
+	reg.AddExprDecoder(begin1Name, 2, decodeBegin1Expr)
+	reg.AddExprDecoder(andName, 2, decodeAndExpr)
+	reg.AddExprDecoder(orName, 2, decodeOrExpr)
+	reg.AddExprDecoder(catchName, 2, decodeCatchExpr)
+	reg.AddExprDecoder(letECName, 2, decodeLetECExpr)
+	reg.AddExprDecoder(tryName, 3, decodeTryExpr)
+	reg.AddExprDecoder(ifName, 3, decodeIfExpr)
+	reg.AddExprDecoder(makeListName, 1, decodeMakeListExpr)
+	reg.AddExprDecoder(defvarName, 2, decodeDefineExpr)
+	reg.AddExprDecoder(setXName, 2, decodeSetXExpr)
+	reg.AddExprDecoder(defpackageName, 5, decodeDefPackageExpr)
+
+	reg.AddObjectDecoder(lambdaName, 7, decodeLexLambda)
+	reg.AddObjectDecoder(dynLambdaName, 5, decodeDynLambda)
+	reg.AddObjectDecoder(macroName, 6, decodeMacro)
+	reg.AddObjectDecoder(syntaxRulesName, 4, decodeSyntaxRules)
+}
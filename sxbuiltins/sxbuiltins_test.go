@@ -15,6 +15,7 @@ package sxbuiltins_test
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -44,50 +45,61 @@ func (tcs tTestCases) Run(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Helper()
-			rd := sxreader.MakeReader(strings.NewReader(tc.src))
+			tc.run(t, root, false)
+			tc.run(t, root, true)
+		})
+	}
 
-			var sb strings.Builder
-			bind := root.MakeChildBinding(tc.name, 0)
-			env := sxeval.MakeEnvironment(bind)
-			for {
-				obj, err := rd.Read()
-				if err != nil {
-					if err == io.EOF {
-						break
-					}
-					if tc.withErr {
-						sb.WriteString(fmt.Errorf("{[{%w}]}", err).Error())
-						continue
-					}
-					t.Errorf("Error %v while reading %s", err, tc.src)
-					return
-				}
-				res, err := env.Eval(obj, nil)
-				if size := env.Size(); size > 0 {
-					t.Error("stack not empty, size:", size)
-				}
-				if err != nil {
-					if tc.withErr {
-						sb.WriteString(fmt.Errorf("{[{%w}]}", err).Error())
-						continue
-					}
-					t.Errorf("unexpected error: %v", fmt.Errorf("%w", err))
-					return
-				} else if tc.withErr {
-					t.Errorf("should fail, but got: %v", res)
-					return
-				}
-				if sb.Len() > 0 {
-					sb.WriteByte(' ')
-				}
-				_, _ = sx.Print(&sb, res)
+}
+
+// run the test case with the tree walker, or compiled for the virtual machine.
+func (tc *tTestCase) run(t *testing.T, root *sxeval.Binding, compile bool) {
+	t.Helper()
+	mode := "walk"
+	if compile {
+		mode = "vm"
+	}
+	rd := sxreader.MakeReader(strings.NewReader(tc.src))
+
+	var sb strings.Builder
+	bind := root.MakeChildBinding(tc.name, 0)
+	env := sxeval.MakeEnvironment(bind).SetCompile(compile)
+	for {
+		obj, err := rd.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
 			}
-			if got := sb.String(); got != tc.exp {
-				t.Errorf("%s should result in %q, but got %q", tc.src, tc.exp, got)
+			if tc.withErr {
+				sb.WriteString(fmt.Errorf("{[{%w}]}", err).Error())
+				continue
 			}
-		})
+			t.Errorf("%s: Error %v while reading %s", mode, err, tc.src)
+			return
+		}
+		res, err := env.Eval(obj, nil)
+		if size := env.Size(); size > 0 {
+			t.Error(mode, ": stack not empty, size:", size)
+		}
+		if err != nil {
+			if tc.withErr {
+				sb.WriteString(fmt.Errorf("{[{%w}]}", err).Error())
+				continue
+			}
+			t.Errorf("%s: unexpected error: %v", mode, fmt.Errorf("%w", err))
+			return
+		} else if tc.withErr {
+			t.Errorf("%s: should fail, but got: %v", mode, res)
+			return
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		_, _ = sx.Print(&sb, res)
+	}
+	if got := sb.String(); got != tc.exp {
+		t.Errorf("%s: %s should result in %q, but got %q", mode, tc.src, tc.exp, got)
 	}
-
 }
 
 func createBinding() *sxeval.Binding {
@@ -160,3 +172,57 @@ func TestIsPure(t *testing.T) {
 		}
 	}
 }
+
+func TestMemoryLimit(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	_ = sxbuiltins.BindAll(root)
+	root.Freeze()
+
+	testcases := []struct {
+		name   string
+		src    string
+		limit  int
+		mustOK bool
+	}{
+		{"list-ok", "(defun f (x) (list x x x)) (f 1)", 1000, true},
+		{"list-fail", "(defun f (x) (list x x x)) (f 1)", 10, false},
+		{"cons-loop", "(defun f (l) (f (cons 1 l))) (f ())", 10_000, false},
+		{"append-loop", "(defun f (l) (f (append l l (list 1)))) (f ())", 10_000, false},
+		{"map", "(defun f (l) (f (map (lambda (x) x) (cons 1 l)))) (f ())", 10_000, false},
+		{"concat-loop", `(defun f (s) (f (concat s s))) (f "abc")`, 100_000, false},
+		{"vector", "(defun f (l) (f (list->vector (seq->list (vector l l l))))) (f 1)", 10_000, false},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			bind := root.MakeChildBinding(tc.name, 2)
+			handler := sxeval.MakeMemoryLimitHandler(tc.limit)
+			env := sxeval.MakeEnvironment(bind).SetAllocHandler(handler)
+			objs, err := sxreader.MakeReader(strings.NewReader(tc.src)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, obj := range objs {
+				if _, err = env.Eval(obj, nil); err != nil {
+					break
+				}
+			}
+			if tc.mustOK {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if handler.Allocated() == 0 {
+					t.Error("allocation expected")
+				}
+				return
+			}
+			var memErr sxeval.ErrMemoryLimit
+			if !errors.As(err, &memErr) {
+				t.Errorf("memory limit error expected, but got %v", err)
+			}
+			if handler.Allocated() > tc.limit {
+				t.Errorf("limit %d exceeded: %d", tc.limit, handler.Allocated())
+			}
+		})
+	}
+}
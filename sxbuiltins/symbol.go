@@ -14,6 +14,8 @@
 package sxbuiltins
 
 import (
+	"fmt"
+
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxeval"
 )
@@ -45,7 +47,8 @@ var KeywordP = sxeval.Builtin{
 	},
 }
 
-// SymbolPackage returns the package that defined the symbol.
+// SymbolPackage returns the package that defined the symbol, or nil for an
+// uninterned symbol.
 var SymbolPackage = sxeval.Builtin{
 	Name:     "symbol-package",
 	MinArity: 1,
@@ -56,7 +59,47 @@ var SymbolPackage = sxeval.Builtin{
 		if err != nil {
 			return nil, err
 		}
-		return sym.Package(), nil
+		if pkg := sym.Package(); pkg != nil {
+			return pkg, nil
+		}
+		return sx.Nil(), nil
+	},
+}
+
+// MakeSymbol creates a new uninterned symbol with the given name.
+var MakeSymbol = sxeval.Builtin{
+	Name:     "make-symbol",
+	MinArity: 1,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		s, err := GetString(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		if sym := sx.MakeUninternedSymbol(s.GetValue()); sym != nil {
+			return sym, nil
+		}
+		return nil, fmt.Errorf("symbol name must not be empty")
+	},
+}
+
+// GenSym creates a new uninterned symbol with a unique name. An optional
+// string argument specifies the prefix of the name, which defaults to "g".
+var GenSym = sxeval.Builtin{
+	Name:     "gensym",
+	MinArity: 0,
+	MaxArity: 1,
+	TestPure: nil,
+	Fn0: func(*sxeval.Environment, *sxeval.Frame) (sx.Object, error) {
+		return sx.GenSym(""), nil
+	},
+	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		s, err := GetString(arg, 0)
+		if err != nil {
+			return nil, err
+		}
+		return sx.GenSym(s.GetValue()), nil
 	},
 }
 
@@ -65,12 +108,12 @@ var SymbolValue = sxeval.Builtin{
 	Name:     "symbol-value",
 	MinArity: 1,
 	MaxArity: 1,
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		sym, err := GetSymbol(arg, 0)
 		if err != nil {
 			return nil, err
 		}
-		if val, found := sym.Bound(); found {
+		if val, found := env.SymbolValue(sym); found {
 			return val, nil
 		}
 		return sx.MakeUndefined(), nil
@@ -85,13 +128,13 @@ var SetSymbolValue = sxeval.Builtin{
 	Name:     "set-symbol-value",
 	MinArity: 2,
 	MaxArity: 2,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		sym, err := GetSymbol(args[0], 0)
+	Fn2: func(env *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		sym, err := GetSymbol(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		val := args[1]
-		if err = sym.Bind(val); err != nil {
+		val := arg1
+		if err = env.SetSymbolValue(sym, val); err != nil {
 			return nil, err
 		}
 		return val, nil
@@ -103,12 +146,12 @@ var FreezeSymbolValue = sxeval.Builtin{
 	Name:     "freeze-symbol-value",
 	MinArity: 1,
 	MaxArity: 1,
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		sym, err := GetSymbol(arg, 0)
 		if err != nil {
 			return nil, err
 		}
-		sym.Freeze()
+		env.FreezeSymbolValue(sym)
 		return sx.Nil(), nil
 	},
 }
@@ -118,11 +161,11 @@ var FrozenSymbolValue = sxeval.Builtin{
 	Name:     "frozen-symbol-value",
 	MinArity: 1,
 	MaxArity: 1,
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
 		sym, err := GetSymbol(arg, 0)
 		if err != nil {
 			return nil, err
 		}
-		return sx.MakeBoolean(sym.IsFrozen()), nil
+		return sx.MakeBoolean(env.IsSymbolFrozen(sym)), nil
 	},
 }
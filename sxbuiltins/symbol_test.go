@@ -61,6 +61,36 @@ var tcsSymbol = tTestCases{
 		src: "(symbol-package 'symbol-package)",
 		exp: sx.CurrentPackage().String()},
 
+	{name: "symbol-package-uninterned", src: "(symbol-package (make-symbol \"a\"))", exp: "()"},
+
+	{name: "err-make-symbol-0",
+		src:     "(make-symbol)",
+		exp:     "{[{make-symbol: exactly 1 arguments required, but none given}]}",
+		withErr: true},
+	{name: "err-make-symbol-sym",
+		src:     "(make-symbol 'a)",
+		exp:     "{[{make-symbol: argument 1 is not a string, but *sx.Symbol/a}]}",
+		withErr: true},
+	{name: "err-make-symbol-empty",
+		src:     "(make-symbol \"\")",
+		exp:     "{[{make-symbol: symbol name must not be empty}]}",
+		withErr: true},
+	{name: "make-symbol", src: "(make-symbol \"a b\")", exp: "#:|a b|"},
+	{name: "make-symbol-not-identical", src: "(== (make-symbol \"a\") (make-symbol \"a\"))", exp: "()"},
+	{name: "make-symbol-not-interned", src: "(== (make-symbol \"a\") 'a)", exp: "()"},
+
+	{name: "err-gensym-sym",
+		src:     "(gensym 'a)",
+		exp:     "{[{gensym: argument 1 is not a string, but *sx.Symbol/a}]}",
+		withErr: true},
+	{name: "gensym", src: "(symbol? (gensym))", exp: "T"},
+	{name: "gensym-not-identical", src: "(== (gensym) (gensym))", exp: "()"},
+	{name: "gensym-prefix", src: "(symbol-package (gensym \"tmp\"))", exp: "()"},
+	{name: "gensym-hygiene",
+		src: "(defmacro swap! (a b) (let ((tmp (gensym \"tmp\"))) `(let ((,tmp ,a)) (set! ,a ,b) (set! ,b ,tmp))))" +
+			" (defvar tmp 1) (defvar y 2) (swap! tmp y) (list tmp y)",
+		exp: "#<macro:swap!> 1 2 1 (2 1)"},
+
 	{name: "err-symbol-value-0",
 		src:     "(symbol-value)",
 		exp:     "{[{symbol-value: exactly 1 arguments required, but none given}]}",
@@ -0,0 +1,599 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins
+
+// Contains pattern-based, hygienic macros.
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxeval"
+)
+
+// Names of symbols with a special meaning within patterns and templates.
+const (
+	ellipsisName = "..."
+	wildcardName = "_"
+)
+
+// DefineSyntaxS parses a (define-syntax name (syntax-rules ...)) form.
+var DefineSyntaxS = sxeval.Special{
+	Name: "define-syntax",
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+		sym, val, err := parseSymValue(pe, args, frame)
+		if err != nil {
+			return nil, err
+		}
+		if args.Tail().Tail() != nil {
+			return nil, fmt.Errorf("more than two arguments")
+		}
+		if coe, isConst := sxeval.GetConstExpr(val); isConst {
+			if sr, isSyntaxRules := coe.ConstObject().(*SyntaxRules); isSyntaxRules {
+				sr.Name = sym.GetValue()
+				return &DefineExpr{Sym: sym, Val: val}, nil
+			}
+		}
+		return nil, fmt.Errorf("argument 2 must be a syntax-rules form, but is: %v", args.Tail().Car())
+	},
+}
+
+// SyntaxRulesS parses a (syntax-rules (literal ...) (pattern template) ...)
+// form. It results in a macro that transforms a form by matching it against
+// the patterns. The template of the first matching pattern produces the
+// expansion of the macro.
+var SyntaxRulesS = sxeval.Special{
+	Name: "syntax-rules",
+	Fn: func(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+		if args == nil {
+			return nil, sxeval.ErrNoArgs
+		}
+		lits, err := GetList(args.Car(), 0)
+		if err != nil {
+			return nil, err
+		}
+		sr := SyntaxRules{Name: "syntax-rules", Frame: frame}
+		for node := lits; node != nil; node = node.Tail() {
+			lit, isSymbol := sx.GetSymbol(node.Car())
+			if !isSymbol {
+				return nil, fmt.Errorf("literal must be a symbol, but is: %T/%v", node.Car(), node.Car())
+			}
+			sr.Literals = append(sr.Literals, pe.ResolveAlias(lit, frame))
+		}
+		for node := args.Tail(); node != nil; node = node.Tail() {
+			rule, isPair := sx.GetPair(node.Car())
+			if !isPair || rule == nil || rule.Tail() == nil || rule.Tail().Tail() != nil {
+				return nil, fmt.Errorf("rule must be a list (pattern template), but is: %v", node.Car())
+			}
+			pattern, isPair := sx.GetPair(rule.Car())
+			if !isPair || pattern == nil {
+				return nil, fmt.Errorf("pattern must be a non-empty list, but is: %v", rule.Car())
+			}
+			if err = sr.checkPattern(pattern.Cdr(), map[*sx.Symbol]bool{}); err != nil {
+				return nil, err
+			}
+			sr.Rules = append(sr.Rules, SyntaxRule{Pattern: pattern, Template: rule.Tail().Car()})
+		}
+		return sxeval.ObjExpr{Obj: &sr}, nil
+	},
+	Walk: walkQuoted,
+}
+
+// SyntaxRules represents a pattern-based macro.
+//
+// Symbols introduced by a template are renamed by aliases, when the macro is
+// expanded. Therefore, they cannot capture symbols given with the macro
+// call, and they refer to their bindings where the macro was defined.
+type SyntaxRules struct {
+	Name     string
+	Frame    *sxeval.Frame
+	Literals []*sx.Symbol
+	Rules    []SyntaxRule
+}
+
+// SyntaxRule is one rule of a pattern-based macro. The first element of the
+// pattern is ignored, because it is the name of the macro.
+type SyntaxRule struct {
+	Pattern  *sx.Pair
+	Template sx.Object
+}
+
+// IsNil returns true if the object must be treated like a sx.Nil() object.
+func (sr *SyntaxRules) IsNil() bool { return sr == nil }
+
+// IsAtom returns true if the object is atomic.
+func (sr *SyntaxRules) IsAtom() bool { return sr == nil }
+
+// IsTrue returns true if the macro can be interpreted as a "true" value.
+func (sr *SyntaxRules) IsTrue() bool { return sr != nil }
+
+// IsEqual returns true if the other object has the same content.
+func (sr *SyntaxRules) IsEqual(other sx.Object) bool { return sr == other }
+
+// String returns a string representation.
+func (sr *SyntaxRules) String() string { return "#<syntax-rules:" + sr.Name + ">" }
+
+// GoString returns a string representation to be used in Go code.
+func (sr *SyntaxRules) GoString() string { return sr.String() }
+
+// syntaxRulesName is the tag of syntax rules in an image.
+const syntaxRulesName = "syntax-rules"
+
+// Encode the macro for an image. Each rule is encoded as a list of its
+// pattern and its template.
+func (sr *SyntaxRules) Encode(enc *sxeval.ImageEncoder) (string, sx.Vector, error) {
+	frame, err := enc.EncodeObject(sr.Frame)
+	if err != nil {
+		return "", nil, err
+	}
+	lits, err := enc.EncodeSymbols(sr.Literals...)
+	if err != nil {
+		return "", nil, err
+	}
+	rules := make(sx.Vector, len(sr.Rules))
+	for i, rule := range sr.Rules {
+		pattern, err2 := enc.EncodeObject(rule.Pattern)
+		if err2 != nil {
+			return "", nil, err2
+		}
+		template, err2 := enc.EncodeObject(rule.Template)
+		if err2 != nil {
+			return "", nil, err2
+		}
+		rules[i] = sx.MakeList(pattern, template)
+	}
+	return syntaxRulesName, sx.Vector{sx.MakeString(sr.Name), frame, sx.MakeList(lits...), sx.MakeList(rules...)}, nil
+}
+
+func decodeSyntaxRules(dec *sxeval.ImageDecoder, args sx.Vector) (sx.Object, error) {
+	sr := &SyntaxRules{}
+	dec.Define(sr)
+	name, err := dec.DecodeString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	frame, err := dec.DecodeFrame(args[1])
+	if err != nil {
+		return nil, err
+	}
+	elems, err := dec.Elems(args[2])
+	if err != nil {
+		return nil, err
+	}
+	lits, err := dec.DecodeSymbols(elems...)
+	if err != nil {
+		return nil, err
+	}
+	if elems, err = dec.Elems(args[3]); err != nil {
+		return nil, err
+	}
+	rules := make([]SyntaxRule, len(elems))
+	for i, elem := range elems {
+		vals, err2 := dec.Elems(elem)
+		if err2 != nil {
+			return nil, err2
+		}
+		if len(vals) != 2 {
+			return nil, fmt.Errorf("invalid syntax rule: %v", elem)
+		}
+		obj, err2 := dec.DecodeObject(vals[0])
+		if err2 != nil {
+			return nil, err2
+		}
+		pattern, isPair := sx.GetPair(obj)
+		if !isPair || pattern == nil {
+			return nil, fmt.Errorf("invalid pattern of syntax rule: %v", obj)
+		}
+		template, err2 := dec.DecodeObject(vals[1])
+		if err2 != nil {
+			return nil, err2
+		}
+		rules[i] = SyntaxRule{Pattern: pattern, Template: template}
+	}
+	sr.Name, sr.Frame, sr.Literals, sr.Rules = name, frame, lits, rules
+	return sr, nil
+}
+
+// Parse transforms a macro call into its expanded form and parses it again.
+func (sr *SyntaxRules) Parse(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sxeval.Expr, error) {
+	form, err := sr.Expand(pe, args, frame)
+	if err != nil {
+		return nil, err
+	}
+	return sxeval.NilExpr, pe.ParseAgain(form)
+}
+
+// Expand the macro call with the given arguments.
+func (sr *SyntaxRules) Expand(pe *sxeval.ParseEnvironment, args *sx.Pair, frame *sxeval.Frame) (sx.Object, error) {
+	for _, rule := range sr.Rules {
+		m := syntaxMatcher{sr: sr, pe: pe, frame: frame, vars: map[*sx.Symbol]*syntaxBinding{}}
+		if m.match(rule.Pattern.Cdr(), args) {
+			ex := syntaxExpander{sr: sr, pe: pe, aliases: map[*sx.Symbol]*sx.Symbol{}}
+			form, err := ex.expand(rule.Template, m.vars)
+			if err != nil {
+				return nil, sxeval.CallError{Name: sr.Name, Err: err}
+			}
+			return form, nil
+		}
+	}
+	return nil, sxeval.CallError{Name: sr.Name, Err: fmt.Errorf("no pattern matches %v", args)}
+}
+
+func (sr *SyntaxRules) isLiteral(sym *sx.Symbol) bool { return slices.Contains(sr.Literals, sym) }
+
+// checkPattern checks that each pattern variable occurs only once, and that
+// an ellipsis follows a sub-pattern.
+func (sr *SyntaxRules) checkPattern(pattern sx.Object, seen map[*sx.Symbol]bool) error {
+	switch p := pattern.(type) {
+	case *sx.Symbol:
+		if p.GetValue() == ellipsisName {
+			return errMisplacedEllipsis
+		}
+		if p.GetValue() != wildcardName && !sr.isLiteral(p) {
+			if seen[p] {
+				return fmt.Errorf("pattern variable %v used more than once", p)
+			}
+			seen[p] = true
+		}
+	case *sx.Pair:
+		elems, tail := splitPattern(p)
+		return sr.checkPatternElems(elems, tail, seen)
+	case sx.Vector:
+		return sr.checkPatternElems(p, nil, seen)
+	}
+	return nil
+}
+
+func (sr *SyntaxRules) checkPatternElems(elems []sx.Object, tail sx.Object, seen map[*sx.Symbol]bool) error {
+	hasEllipsis := false
+	for i, elem := range elems {
+		if isEllipsis(elem) {
+			if i == 0 || hasEllipsis {
+				return errMisplacedEllipsis
+			}
+			hasEllipsis = true
+			continue
+		}
+		if err := sr.checkPattern(elem, seen); err != nil {
+			return err
+		}
+	}
+	if tail != nil {
+		return sr.checkPattern(tail, seen)
+	}
+	return nil
+}
+
+var errMisplacedEllipsis = errors.New("misplaced ellipsis in pattern")
+
+// splitPattern returns the elements of the (possibly improper) list and its
+// final non-nil tail.
+func splitPattern(lst *sx.Pair) ([]sx.Object, sx.Object) {
+	var elems []sx.Object
+	var obj sx.Object = lst
+	for {
+		pair, isPair := sx.GetPair(obj)
+		if !isPair {
+			return elems, obj
+		}
+		if pair == nil {
+			return elems, nil
+		}
+		elems = append(elems, pair.Car())
+		obj = pair.Cdr()
+	}
+}
+
+func isEllipsis(obj sx.Object) bool {
+	sym, isSymbol := sx.GetSymbol(obj)
+	return isSymbol && sym.GetValue() == ellipsisName
+}
+
+// syntaxBinding stores the object that is matched by a pattern variable. If
+// the variable is followed by an ellipsis, it stores all matches.
+type syntaxBinding struct {
+	obj   sx.Object
+	items []*syntaxBinding
+	seq   bool
+}
+
+type syntaxMatcher struct {
+	sr    *SyntaxRules
+	pe    *sxeval.ParseEnvironment
+	frame *sxeval.Frame
+	vars  map[*sx.Symbol]*syntaxBinding
+}
+
+func (m *syntaxMatcher) match(pattern, form sx.Object) bool {
+	switch p := pattern.(type) {
+	case *sx.Symbol:
+		if p.GetValue() == wildcardName {
+			return true
+		}
+		if m.sr.isLiteral(p) {
+			sym, isSymbol := sx.GetSymbol(form)
+			return isSymbol && m.pe.ResolveAlias(sym, m.frame) == p
+		}
+		m.vars[p] = &syntaxBinding{obj: form}
+		return true
+	case *sx.Pair:
+		if p == nil {
+			return sx.IsNil(form)
+		}
+		lst, isPair := sx.GetPair(form)
+		if !isPair {
+			return false
+		}
+		pElems, pTail := splitPattern(p)
+		var fElems []sx.Object
+		var fTail sx.Object
+		if lst != nil {
+			fElems, fTail = splitPattern(lst)
+		}
+		return m.matchElems(pElems, pTail, fElems, fTail)
+	case sx.Vector:
+		v, isVector := sx.GetVector(form)
+		return isVector && m.matchElems(p, nil, v, nil)
+	}
+	if pattern == nil {
+		return sx.IsNil(form)
+	}
+	return pattern.IsEqual(form)
+}
+
+func (m *syntaxMatcher) matchElems(pElems []sx.Object, pTail sx.Object, fElems []sx.Object, fTail sx.Object) bool {
+	pos := slices.IndexFunc(pElems, isEllipsis)
+	if pos < 0 {
+		if len(fElems) < len(pElems) || (pTail == nil && len(fElems) > len(pElems)) {
+			return false
+		}
+		for i, p := range pElems {
+			if !m.match(p, fElems[i]) {
+				return false
+			}
+		}
+		return m.matchTail(pTail, fElems[len(pElems):], fTail)
+	}
+
+	before, rep, after := pElems[:pos-1], pElems[pos-1], pElems[pos+1:]
+	numRep := len(fElems) - len(before) - len(after)
+	if numRep < 0 {
+		return false
+	}
+	for i, p := range before {
+		if !m.match(p, fElems[i]) {
+			return false
+		}
+	}
+	vars := patternVars(rep, m.sr, nil)
+	seqs := make(map[*sx.Symbol]*syntaxBinding, len(vars))
+	for _, v := range vars {
+		seqs[v] = &syntaxBinding{seq: true}
+	}
+	for _, f := range fElems[len(before) : len(before)+numRep] {
+		sub := syntaxMatcher{sr: m.sr, pe: m.pe, frame: m.frame, vars: map[*sx.Symbol]*syntaxBinding{}}
+		if !sub.match(rep, f) {
+			return false
+		}
+		for _, v := range vars {
+			seqs[v].items = append(seqs[v].items, sub.vars[v])
+		}
+	}
+	for v, b := range seqs {
+		m.vars[v] = b
+	}
+	rest := fElems[len(before)+numRep:]
+	for i, p := range after {
+		if !m.match(p, rest[i]) {
+			return false
+		}
+	}
+	if pTail == nil {
+		return sx.IsNil(fTail)
+	}
+	return m.match(pTail, fTail)
+}
+
+// matchTail matches the tail of a pattern against the remaining elements and
+// the tail of a form.
+func (m *syntaxMatcher) matchTail(pTail sx.Object, fElems []sx.Object, fTail sx.Object) bool {
+	if pTail == nil {
+		return fTail == nil || sx.IsNil(fTail)
+	}
+	var rest sx.Object = sx.Nil()
+	if fTail != nil {
+		rest = fTail
+	}
+	for i := len(fElems) - 1; i >= 0; i-- {
+		rest = sx.Cons(fElems[i], rest)
+	}
+	return m.match(pTail, rest)
+}
+
+// patternVars returns all pattern variables of the given pattern.
+func patternVars(pattern sx.Object, sr *SyntaxRules, result []*sx.Symbol) []*sx.Symbol {
+	switch p := pattern.(type) {
+	case *sx.Symbol:
+		if p.GetValue() != ellipsisName && p.GetValue() != wildcardName && !sr.isLiteral(p) {
+			result = append(result, p)
+		}
+	case *sx.Pair:
+		if p != nil {
+			elems, tail := splitPattern(p)
+			for _, elem := range elems {
+				result = patternVars(elem, sr, result)
+			}
+			if tail != nil {
+				result = patternVars(tail, sr, result)
+			}
+		}
+	case sx.Vector:
+		for _, elem := range p {
+			result = patternVars(elem, sr, result)
+		}
+	}
+	return result
+}
+
+type syntaxExpander struct {
+	sr      *SyntaxRules
+	pe      *sxeval.ParseEnvironment
+	aliases map[*sx.Symbol]*sx.Symbol
+	literal bool // ellipsis is escaped
+}
+
+func (ex *syntaxExpander) expand(tmpl sx.Object, vars map[*sx.Symbol]*syntaxBinding) (sx.Object, error) {
+	switch t := tmpl.(type) {
+	case *sx.Symbol:
+		if b, found := vars[t]; found {
+			if b.seq {
+				return nil, fmt.Errorf("pattern variable %v must be followed by an ellipsis", t)
+			}
+			return b.obj, nil
+		}
+		if alias, found := ex.aliases[t]; found {
+			return alias, nil
+		}
+		alias := ex.pe.MakeAlias(t, ex.sr.Frame)
+		ex.aliases[t] = alias
+		return alias, nil
+	case *sx.Pair:
+		if t == nil {
+			return t, nil
+		}
+		elems, tail := splitPattern(t)
+		if !ex.literal && len(elems) == 2 && tail == nil && isEllipsis(elems[0]) {
+			// (... template) escapes the ellipsis within template.
+			literal := *ex
+			literal.literal = true
+			return literal.expand(elems[1], vars)
+		}
+		result, err := ex.expandElems(elems, vars)
+		if err != nil {
+			return nil, err
+		}
+		var last sx.Object = sx.Nil()
+		if tail != nil {
+			if last, err = ex.expand(tail, vars); err != nil {
+				return nil, err
+			}
+		}
+		for i := len(result) - 1; i >= 0; i-- {
+			last = sx.Cons(result[i], last)
+		}
+		return last, nil
+	case sx.Vector:
+		result, err := ex.expandElems(t, vars)
+		if err != nil {
+			return nil, err
+		}
+		return sx.Vector(result), nil
+	}
+	return tmpl, nil
+}
+
+func (ex *syntaxExpander) expandElems(elems []sx.Object, vars map[*sx.Symbol]*syntaxBinding) ([]sx.Object, error) {
+	var result []sx.Object
+	for i := 0; i < len(elems); i++ {
+		elem := elems[i]
+		depth := 0
+		for !ex.literal && i+1 < len(elems) && isEllipsis(elems[i+1]) {
+			depth++
+			i++
+		}
+		if depth == 0 {
+			obj, err := ex.expand(elem, vars)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, obj)
+			continue
+		}
+		objs, err := ex.expandEllipsis(elem, vars, depth)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, objs...)
+	}
+	return result, nil
+}
+
+// expandEllipsis expands a template that is followed by the given number of
+// ellipses.
+func (ex *syntaxExpander) expandEllipsis(tmpl sx.Object, vars map[*sx.Symbol]*syntaxBinding, depth int) ([]sx.Object, error) {
+	if depth == 0 {
+		obj, err := ex.expand(tmpl, vars)
+		if err != nil {
+			return nil, err
+		}
+		return []sx.Object{obj}, nil
+	}
+	var seqVars []*sx.Symbol
+	length := -1
+	for _, v := range templateSymbols(tmpl, nil) {
+		if b, found := vars[v]; found && b.seq && !slices.Contains(seqVars, v) {
+			if length >= 0 && length != len(b.items) {
+				return nil, fmt.Errorf("pattern variables of %v matched different number of elements", tmpl)
+			}
+			length = len(b.items)
+			seqVars = append(seqVars, v)
+		}
+	}
+	if len(seqVars) == 0 {
+		return nil, fmt.Errorf("no pattern variable with ellipsis in %v", tmpl)
+	}
+	var result []sx.Object
+	for i := range length {
+		sub := make(map[*sx.Symbol]*syntaxBinding, len(vars))
+		for v, b := range vars {
+			sub[v] = b
+		}
+		for _, v := range seqVars {
+			sub[v] = vars[v].items[i]
+		}
+		objs, err := ex.expandEllipsis(tmpl, sub, depth-1)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, objs...)
+	}
+	return result, nil
+}
+
+// templateSymbols returns all symbols of the given template.
+func templateSymbols(tmpl sx.Object, result []*sx.Symbol) []*sx.Symbol {
+	switch t := tmpl.(type) {
+	case *sx.Symbol:
+		result = append(result, t)
+	case *sx.Pair:
+		for node := sx.Object(t); ; {
+			pair, isPair := sx.GetPair(node)
+			if !isPair {
+				return templateSymbols(node, result)
+			}
+			if pair == nil {
+				break
+			}
+			result = templateSymbols(pair.Car(), result)
+			node = pair.Cdr()
+		}
+	case sx.Vector:
+		for _, elem := range t {
+			result = templateSymbols(elem, result)
+		}
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxbuiltins_test
+
+import (
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+func TestSyntaxRules(t *testing.T) {
+	t.Parallel()
+	tcsSyntaxRules.Run(t)
+}
+
+func TestSyntaxRulesNoMatch(t *testing.T) {
+	t.Parallel()
+	env := sxeval.MakeEnvironment(createBinding().MakeChildBinding("no-match", 0))
+	rd := sxreader.MakeReader(strings.NewReader("(define-syntax first (syntax-rules () ((_ a b) a))) (first 1)"))
+	def, err := rd.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = env.Eval(def, nil); err != nil {
+		t.Fatal(err)
+	}
+	call, err := rd.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = env.Eval(call, nil)
+	if exp := "first: no pattern matches (1)"; err == nil || err.Error() != exp {
+		t.Errorf("expected error %q, but got %v", exp, err)
+	}
+}
+
+var tcsSyntaxRules = tTestCases{
+	{name: "err-syntax-rules-0",
+		src:     "(syntax-rules)",
+		exp:     "{[{syntax-rules: no arguments given}]}",
+		withErr: true},
+	{name: "err-syntax-rules-literal",
+		src:     "(syntax-rules (1))",
+		exp:     "{[{syntax-rules: literal must be a symbol, but is: sx.Int64/1}]}",
+		withErr: true},
+	{name: "err-syntax-rules-rule",
+		src:     "(syntax-rules () (a))",
+		exp:     "{[{syntax-rules: rule must be a list (pattern template), but is: (a)}]}",
+		withErr: true},
+	{name: "err-syntax-rules-ellipsis",
+		src:     "(syntax-rules () ((_ ... a) a))",
+		exp:     "{[{syntax-rules: misplaced ellipsis in pattern}]}",
+		withErr: true},
+	{name: "err-syntax-rules-twice",
+		src:     "(syntax-rules () ((_ a a) a))",
+		exp:     "{[{syntax-rules: pattern variable a used more than once}]}",
+		withErr: true},
+	{name: "syntax-rules-simple", src: "(syntax-rules () ((_ a) a))", exp: "#<syntax-rules:syntax-rules>"},
+	{name: "err-define-syntax-no-rules",
+		src:     "(define-syntax m 1)",
+		exp:     "{[{define-syntax: argument 2 must be a syntax-rules form, but is: 1}]}",
+		withErr: true},
+	{name: "define-syntax-simple",
+		src: "(define-syntax first (syntax-rules () ((_ a b) a))) (first 1 2)",
+		exp: "#<syntax-rules:first> 1"},
+	{name: "define-syntax-swap",
+		src: "(define-syntax swap! (syntax-rules () ((_ a b) (let ((tmp a)) (set! a b) (set! b tmp)))))" +
+			" (defvar tmp 1) (defvar y 2) (swap! tmp y) (list tmp y)",
+		exp: "#<syntax-rules:swap!> 1 2 1 (2 1)"},
+	{name: "define-syntax-my-or",
+		src: "(define-syntax my-or (syntax-rules () ((_) ()) ((_ e) e) ((_ e r ...) (let ((t e)) (if t t (my-or r ...))))))" +
+			" (defvar t 5) (list (my-or) (my-or 1) (my-or () t) (let ((if list)) (my-or () 7)))",
+		exp: "#<syntax-rules:my-or> 5 (() 1 5 7)"},
+	{name: "define-syntax-ellipsis",
+		src: "(define-syntax my-list (syntax-rules () ((_ (a b) ...) (list (cons a b) ...)))) (my-list (1 2) (3 4))",
+		exp: "#<syntax-rules:my-list> ((1 . 2) (3 . 4))"},
+	{name: "define-syntax-nested-ellipsis",
+		src: "(define-syntax flat (syntax-rules () ((_ (a ...) ...) '(a ... ...)))) (flat (1 2) () (3))",
+		exp: "#<syntax-rules:flat> (1 2 3)"},
+	{name: "define-syntax-nested-ellipsis-list",
+		src: "(define-syntax nest (syntax-rules () ((_ (a b ...) ...) '((b ... a) ...)))) (nest (1 2 3) (4) (5 6))",
+		exp: "#<syntax-rules:nest> ((2 3 1) (4) (6 5))"},
+	{name: "define-syntax-literal",
+		src: "(define-syntax my-cond (syntax-rules (else) ((_ (else e)) e) ((_ (c e) r ...) (if c e (my-cond r ...)))))" +
+			" (list (my-cond (() 1) (else 2)) (my-cond (3 4) (else 5)))",
+		exp: "#<syntax-rules:my-cond> (2 4)"},
+	{name: "define-syntax-dotted",
+		src: "(define-syntax rest (syntax-rules () ((_ a . b) 'b))) (rest 1 2 3)",
+		exp: "#<syntax-rules:rest> (2 3)"},
+	{name: "define-syntax-escape",
+		src: "(define-syntax esc (syntax-rules () ((_ a) '(a (... ...))))) (esc 1)",
+		exp: "#<syntax-rules:esc> (1 ...)"},
+	{name: "define-syntax-wildcard",
+		src: "(define-syntax second (syntax-rules () ((_ _ b) b))) (second 1 2)",
+		exp: "#<syntax-rules:second> 2"},
+	{name: "define-syntax-global",
+		src: "(defun helper (x) (+ x 1)) (define-syntax inc (syntax-rules () ((_ a) (helper a)))) (let ((helper 3)) (inc helper))",
+		exp: "#<lambda:helper> #<syntax-rules:inc> 4"},
+}
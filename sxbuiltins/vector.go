@@ -28,10 +28,16 @@ var Vector = sxeval.Builtin{
 	MaxArity: -1,
 	TestPure: sxeval.AssertPure,
 	Fn0:      func(_ *sxeval.Environment, _ *sxeval.Frame) (sx.Object, error) { return sx.Nil(), nil },
-	Fn1: func(_ *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+	Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		if err := AllocVector(env, 1); err != nil {
+			return nil, err
+		}
 		return sx.Vector{arg}, nil
 	},
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+	Fn: func(env *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+		if err := AllocVector(env, len(args)); err != nil {
+			return nil, err
+		}
 		return slices.Clone(args), nil
 	},
 }
@@ -54,12 +60,12 @@ var VectorSetBang = sxeval.Builtin{
 	MinArity: 3,
 	MaxArity: 3,
 	TestPure: sxeval.AssertPure,
-	Fn: func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
-		v, err := GetVector(args[0], 0)
+	Fn3: func(_ *sxeval.Environment, arg0, arg1, arg2 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		v, err := GetVector(arg0, 0)
 		if err != nil {
 			return nil, err
 		}
-		num, err := GetNumber(args[1], 1)
+		num, err := GetNumber(arg1, 1)
 		if err != nil {
 			return nil, err
 		}
@@ -71,7 +77,7 @@ var VectorSetBang = sxeval.Builtin{
 			return nil, fmt.Errorf("vector index out of range: %v", pos)
 		}
 
-		v[pos] = args[2]
+		v[pos] = arg2
 		return v, nil
 	},
 }
@@ -16,6 +16,8 @@ package sxeval
 import (
 	"fmt"
 	"maps"
+	"sync"
+	"sync/atomic"
 
 	"t73f.de/r/sx"
 )
@@ -30,25 +32,56 @@ import (
 // of a call, in contrast to `Frame`. It can be frozen, so it cannot be
 // updated any more.
 type Binding struct {
-	mso    mapSymObj
-	name   string
-	parent *Binding
-	frozen bool
+	mso     mapSymObj
+	name    string
+	parent  *Binding
+	frozen  bool
+	symbols *symbolTable   // symbol values of an isolated root binding
+	version *atomic.Uint64 // counts the changes of the root binding and its descendants
 }
 
 type mapSymObj = map[*sx.Symbol]sx.Object
 
 func makeBinding(name string, parent *Binding, sizeHint int) *Binding {
-	return &Binding{
+	b := &Binding{
 		mso:    make(mapSymObj, sizeHint),
 		parent: parent,
 		name:   name,
 	}
+	if parent != nil {
+		b.symbols = parent.symbols
+		b.version = parent.version
+	} else {
+		b.version = new(atomic.Uint64)
+	}
+	return b
 }
 
 // MakeRootBinding creates a new root binding.
 func MakeRootBinding(sizeHint int) *Binding { return makeBinding("root", nil, sizeHint) }
 
+// MakeIsolatedRootBinding creates a new root binding, where symbol values are
+// stored in a table that is shared only by the root binding and its
+// descendants. Symbol values that are set within an environment of such a
+// binding are not visible to other bindings. Only frozen symbol values, like
+// the value of `T`, are shared globally.
+func MakeIsolatedRootBinding(sizeHint int) *Binding {
+	b := MakeRootBinding(sizeHint)
+	b.symbols = &symbolTable{values: map[*sx.Symbol]symbolEntry{}}
+	return b
+}
+
+// IsIsolated returns true, if symbol values are stored in a table of the
+// root binding, instead of the global symbol value.
+func (b *Binding) IsIsolated() bool { return b.symbolTable() != nil }
+
+func (b *Binding) symbolTable() *symbolTable {
+	if b == nil {
+		return nil
+	}
+	return b.symbols
+}
+
 // MakeChildBinding creates a new binding with a given parent.
 func (b *Binding) MakeChildBinding(name string, sizeHint int) *Binding {
 	return makeBinding(name, b, sizeHint)
@@ -103,9 +136,21 @@ func (b *Binding) Bind(sym *sx.Symbol, obj sx.Object) error {
 		return ErrBindingFrozen{Binding: b}
 	}
 	b.mso[sym] = obj
+	b.version.Add(1)
 	return nil
 }
 
+// chainVersion returns a value that changes, if the binding or one of its
+// parent bindings is changed. It is used to invalidate the cached results of
+// global symbol lookups. All bindings with the same root binding share this
+// value, so that it can be retrieved in constant time.
+func (b *Binding) chainVersion() uint64 {
+	if b == nil {
+		return 0
+	}
+	return b.version.Load()
+}
+
 // ErrBindingFrozen is returned when trying to update a frozen binding.
 type ErrBindingFrozen struct{ Binding *Binding }
 
@@ -155,8 +200,72 @@ func (b *Binding) Bindings() *sx.Pair {
 	return result.List()
 }
 
+// ExportBindings exports all symbols bound in the binding from their
+// package, so that they are accessible in packages using it. Uninterned
+// symbols are ignored.
+func (b *Binding) ExportBindings() error {
+	syms := map[*sx.Package][]*sx.Symbol{}
+	for sym := range b.mso {
+		if pkg := sym.Package(); pkg != nil {
+			syms[pkg] = append(syms[pkg], sym)
+		}
+	}
+	for pkg, pkgSyms := range syms {
+		if err := pkg.Export(pkgSyms...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Freeze sets the binding in a read-only state.
 func (b *Binding) Freeze() { b.frozen = true }
 
 // IsFrozen returns true if binding is frozen.
 func (b *Binding) IsFrozen() bool { return b.frozen }
+
+// ----- Symbol values of isolated bindings
+
+// symbolTable stores the symbol values of an isolated root binding. It may
+// be used concurrently.
+type symbolTable struct {
+	mx     sync.RWMutex
+	values map[*sx.Symbol]symbolEntry
+}
+
+type symbolEntry struct {
+	obj    sx.Object
+	frozen bool
+}
+
+func (st *symbolTable) lookup(sym *sx.Symbol) (symbolEntry, bool) {
+	st.mx.RLock()
+	entry, found := st.values[sym]
+	st.mx.RUnlock()
+	return entry, found
+}
+
+func (st *symbolTable) bind(sym *sx.Symbol, obj sx.Object) error {
+	st.mx.Lock()
+	defer st.mx.Unlock()
+	if entry, found := st.values[sym]; found && entry.frozen {
+		return sx.ErrSymbolFrozen{Symbol: sym}
+	}
+	st.values[sym] = symbolEntry{obj: obj}
+	return nil
+}
+
+func (st *symbolTable) size() int {
+	st.mx.RLock()
+	size := len(st.values)
+	st.mx.RUnlock()
+	return size
+}
+
+func (st *symbolTable) freeze(sym *sx.Symbol) {
+	st.mx.Lock()
+	entry := st.values[sym]
+	entry.frozen = true
+	st.values[sym] = entry
+	st.mx.Unlock()
+}
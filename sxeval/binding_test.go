@@ -14,6 +14,7 @@
 package sxeval_test
 
 import (
+	"sync"
 	"testing"
 
 	"t73f.de/r/sx"
@@ -137,3 +138,178 @@ func checkBindingEqual(t *testing.T, bind1, bind2 *sxeval.Binding) {
 		t.Error("equal bindings differ")
 	}
 }
+
+func TestIsolatedSymbolValues(t *testing.T) {
+	t.Parallel()
+	sym := sx.MakeSymbol("isolated-value")
+	if err := sym.Bind(sx.MakeString("global")); err != nil {
+		t.Fatal(err)
+	}
+
+	if sxeval.MakeRootBinding(1).IsIsolated() {
+		t.Error("root binding must not be isolated")
+	}
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			root := sxeval.MakeIsolatedRootBinding(1)
+			env := sxeval.MakeEnvironment(root.MakeChildBinding("child", 1))
+			if !root.IsIsolated() {
+				t.Error("root binding must be isolated")
+			}
+			if val, found := env.SymbolValue(sym); found {
+				t.Errorf("global value must not be visible, but got %v", val)
+			}
+			if val, found := env.SymbolValue(sx.T); !found || val != sx.T {
+				t.Errorf("frozen value of T expected, but got %v/%v", val, found)
+			}
+			val := sx.Int64(i)
+			if err := env.SetSymbolValue(sym, val); err != nil {
+				t.Error(err)
+				return
+			}
+			if got, _ := env.Resolve(sym, nil); got != val {
+				t.Errorf("expected %v, but got %v", val, got)
+			}
+			env.FreezeSymbolValue(sym)
+			if !env.IsSymbolFrozen(sym) {
+				t.Error("symbol value must be frozen")
+			}
+			if err := env.SetSymbolValue(sym, sx.Nil()); err == nil {
+				t.Error("frozen symbol value must not be updated")
+			}
+			if err := env.SetSymbolValue(sx.T, sx.Nil()); err == nil {
+				t.Error("frozen global symbol value must not be updated")
+			}
+		})
+	}
+	wg.Wait()
+
+	if val, _ := sym.Bound(); val.String() != `"global"` || sym.IsFrozen() {
+		t.Errorf("global symbol value changed: %v, frozen: %v", val, sym.IsFrozen())
+	}
+}
+
+func TestGlobalLookupCache(t *testing.T) {
+	t.Parallel()
+	sym := sx.MakeSymbol("cached-global")
+	root := sxeval.MakeIsolatedRootBinding(1)
+	child := root.MakeChildBinding("child", 1)
+	env := sxeval.MakeEnvironment(child)
+	if err := env.SetSymbolValue(sym, sx.Int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.Parse(sym, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(env *sxeval.Environment, exp int64) {
+		t.Helper()
+		for range 2 { // second run uses the cache
+			obj, errRun := env.Run(expr, nil)
+			if errRun != nil {
+				t.Fatal(errRun)
+			}
+			if !obj.IsEqual(sx.Int64(exp)) {
+				t.Errorf("%v expected, but got %v", exp, obj)
+			}
+		}
+	}
+	check(env, 1)
+	if err = env.SetSymbolValue(sym, sx.Int64(2)); err != nil {
+		t.Fatal(err)
+	}
+	check(env, 2)
+	if err = root.Bind(sym, sx.Int64(3)); err != nil {
+		t.Fatal(err)
+	}
+	check(env, 3)
+	if err = child.Bind(sym, sx.Int64(4)); err != nil {
+		t.Fatal(err)
+	}
+	check(env, 4)
+	check(sxeval.MakeEnvironment(root), 3)
+	check(env, 4)
+	if err = child.Bind(sym, sx.Int64(5)); err != nil {
+		t.Fatal(err)
+	}
+	check(env, 5)
+}
+
+// TestGlobalLookupCacheUnrelated checks that changing an unrelated binding
+// does not invalidate the cached result of a global lookup, but changing a
+// binding with the same root binding does.
+func TestGlobalLookupCacheUnrelated(t *testing.T) {
+	t.Parallel()
+	sym := sx.MakeSymbol("cached-unrelated")
+	root := sxeval.MakeRootBinding(1)
+	if err := root.Bind(sym, sx.Int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(root.MakeChildBinding("child", 1))
+	expr, err := env.Parse(sym, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sxeval.IsGlobalCacheValid(expr, env) {
+		t.Error("cache must not be valid before the first computation")
+	}
+
+	testcases := []struct {
+		name  string
+		bind  *sxeval.Binding
+		valid bool
+	}{
+		{"other", sxeval.MakeRootBinding(1), true},
+		{"other-child", sxeval.MakeRootBinding(1).MakeChildBinding("other-child", 1), true},
+		{"sibling", root.MakeChildBinding("sibling", 1), false},
+		{"root", root, false},
+	}
+	for _, tc := range testcases {
+		if obj, errCompute := expr.Compute(env, nil); errCompute != nil || !obj.IsEqual(sx.Int64(1)) {
+			t.Fatalf("%s: 1 expected, but got %v/%v", tc.name, obj, errCompute)
+		}
+		if !sxeval.IsGlobalCacheValid(expr, env) {
+			t.Errorf("%s: cache must be valid after computation", tc.name)
+		}
+		if errBind := tc.bind.Bind(sx.MakeSymbol("cached-other"), sx.Int64(2)); errBind != nil {
+			t.Fatal(errBind)
+		}
+		if got := sxeval.IsGlobalCacheValid(expr, env); got != tc.valid {
+			t.Errorf("%s: cache validity %v expected, but got %v", tc.name, tc.valid, got)
+		}
+	}
+}
+
+func TestExportBindings(t *testing.T) {
+	t.Parallel()
+	initPkg := sx.InitPackage()
+	bi := &sxeval.Builtin{Name: "export-bindings-builtin"}
+	sp := &sxeval.Special{Name: "export-bindings-special"}
+	root := sxeval.MakeRootBinding(2)
+	if err := sxeval.BindBuiltins(root, bi); err != nil {
+		t.Fatal(err)
+	}
+	if err := sxeval.BindSpecials(root, sp); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{bi.Name, sp.Name} {
+		sym := initPkg.FindSymbol(name)
+		if sym == nil {
+			t.Errorf("symbol %q must be interned in %v", name, initPkg)
+			continue
+		}
+		if initPkg.IsExternal(sym) {
+			t.Errorf("symbol %v must not be exported before ExportBindings", sym)
+		}
+	}
+	if err := root.ExportBindings(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{bi.Name, sp.Name} {
+		if sym := initPkg.FindSymbol(name); !initPkg.IsExternal(sym) {
+			t.Errorf("symbol %v must be exported by ExportBindings", sym)
+		}
+	}
+}
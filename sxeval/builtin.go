@@ -38,6 +38,12 @@ type Builtin struct {
 	// The actual builtin function, with one argument.
 	Fn1 func(*Environment, sx.Object, *Frame) (sx.Object, error)
 
+	// The actual builtin function, with two arguments. If nil, Fn is used.
+	Fn2 func(*Environment, sx.Object, sx.Object, *Frame) (sx.Object, error)
+
+	// The actual builtin function, with three arguments. If nil, Fn is used.
+	Fn3 func(*Environment, sx.Object, sx.Object, sx.Object, *Frame) (sx.Object, error)
+
 	// The actual builtin function, with any number of arguments.
 	//
 	// The function is alowed to read each single element of the vector, but it
@@ -53,12 +59,15 @@ type Builtin struct {
 // AssertPure is a TestPure function that alsways returns true.
 func AssertPure(sx.Vector) bool { return true }
 
-// Bind the builtin to a given environment.
+// Bind the builtin to a given environment. The symbol of the builtin is
+// interned in the package INIT.
 func (b *Builtin) Bind(bind *Binding) error {
-	return bind.Bind(sx.MakeSymbol(b.Name), b)
+	return bind.Bind(sx.InitPackage().MakeSymbol(b.Name), b)
 }
 
-// BindBuiltins will bind many builtins to an environment.
+// BindBuiltins will bind many builtins to an environment. The symbols of the
+// builtins are interned in the package INIT, but they are not exported. Use
+// Binding.ExportBindings to make them accessible in packages that use INIT.
 func BindBuiltins(bind *Binding, bs ...*Builtin) error {
 	for _, b := range bs {
 		if err := b.Bind(bind); err != nil {
@@ -124,7 +133,7 @@ func (b *Builtin) ExecuteCall(env *Environment, args sx.Vector, frame *Frame) (o
 	case 1:
 		obj, err = b.Fn1(env, args[0], frame)
 	default:
-		obj, err = b.Fn(env, args, frame)
+		obj, err = b.callN(env, args, frame)
 	}
 	if err == nil {
 		return obj, nil
@@ -132,6 +141,21 @@ func (b *Builtin) ExecuteCall(env *Environment, args sx.Vector, frame *Frame) (o
 	return nil, b.handleCallError(err)
 }
 
+// callN calls the builtin function with at least two arguments.
+func (b *Builtin) callN(env *Environment, args sx.Vector, frame *Frame) (sx.Object, error) {
+	switch len(args) {
+	case 2:
+		if fn2 := b.Fn2; fn2 != nil {
+			return fn2(env, args[0], args[1], frame)
+		}
+	case 3:
+		if fn3 := b.Fn3; fn3 != nil {
+			return fn3(env, args[0], args[1], args[2], frame)
+		}
+	}
+	return b.Fn(env, args, frame)
+}
+
 // checkCallArity check the builtin function to match allowed number of args.
 func (b *Builtin) checkCallArity(nargs int, argsFn func() []sx.Object) error {
 	if minArity, maxArity := b.MinArity, b.MaxArity; minArity == maxArity {
@@ -158,6 +182,9 @@ func (b *Builtin) checkCallArity(nargs int, argsFn func() []sx.Object) error {
 }
 
 func (b *Builtin) handleCallError(err error) error {
+	if _, isControl := err.(ControlError); isControl {
+		return err
+	}
 	if !b.NoCallError {
 		var callError CallError
 		if !errors.As(err, &callError) {
@@ -167,7 +194,7 @@ func (b *Builtin) handleCallError(err error) error {
 	return err
 }
 
-// ----- builtinCallExpr, builtinCall0Expr, BuiltinCall1Expr
+// ----- builtinCallExpr, builtinCall0Expr, BuiltinCall1Expr, builtinCall2Expr, builtinCall3Expr
 
 // builtinCallExpr calls a builtin and returns the resulting object.
 // It is an optimization of `CallExpr.`
@@ -218,6 +245,14 @@ func (bce *builtinCallExpr) Improve(imp *Improver) (Expr, error) {
 		return imp.Improve(&builtinCall0Expr{bce.Proc})
 	case 1:
 		return imp.Improve(&BuiltinCall1Expr{bce.Proc, bce.Args[0]})
+	case 2:
+		if bce.Proc.Fn2 != nil {
+			return imp.Improve(&builtinCall2Expr{bce.Proc, bce.Args[0], bce.Args[1]})
+		}
+	case 3:
+		if bce.Proc.Fn3 != nil {
+			return imp.Improve(&builtinCall3Expr{bce.Proc, bce.Args[0], bce.Args[1], bce.Args[2]})
+		}
 	}
 	return bce, nil
 }
@@ -228,7 +263,7 @@ func (bce *builtinCallExpr) Compute(env *Environment, frame *Frame) (sx.Object,
 	if err := computeArgs(env, args, frame); err != nil {
 		return nil, err
 	}
-	obj, err := bce.Proc.Fn(env, env.Args(len(args)), frame)
+	obj, err := bce.Proc.callN(env, env.Args(len(args)), frame)
 	env.Kill(len(args))
 	if err != nil {
 		return nil, bce.Proc.handleCallError(err)
@@ -314,3 +349,99 @@ func (bce *BuiltinCall1Expr) Print(w io.Writer) (int, error) {
 	ce := CallExpr{ObjExpr{bce.Proc}, []Expr{bce.Arg}}
 	return ce.doPrint(w, "{BCALL-1 ")
 }
+
+// builtinCall2Expr calls a builtin with two args and returns the resulting
+// object. It is an optimization of `CallExpr`.
+type builtinCall2Expr struct {
+	Proc       *Builtin
+	Arg0, Arg1 Expr
+}
+
+func (bce *builtinCall2Expr) String() string {
+	return fmt.Sprintf("%v %v %v", bce.Proc, bce.Arg0, bce.Arg1)
+}
+
+// IsPure signals an expression that has no side effects.
+func (bce *builtinCall2Expr) IsPure() bool {
+	return bce.Arg0.IsPure() && bce.Arg1.IsPure() &&
+		bce.Proc.IsPure(sx.Vector{sx.MakeUndefined(), sx.MakeUndefined()})
+}
+
+// Unparse the expression back into a form object.
+func (bce *builtinCall2Expr) Unparse() sx.Object {
+	ce := CallExpr{Proc: ObjExpr{bce.Proc}, Args: []Expr{bce.Arg0, bce.Arg1}}
+	return ce.Unparse()
+}
+
+// Compute the value of this expression in the given environment.
+func (bce *builtinCall2Expr) Compute(env *Environment, frame *Frame) (sx.Object, error) {
+	val0, err := env.Execute(bce.Arg0, frame)
+	if err != nil {
+		return nil, err
+	}
+	val1, err := env.Execute(bce.Arg1, frame)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := bce.Proc.Fn2(env, val0, val1, frame)
+	if err != nil {
+		return nil, bce.Proc.handleCallError(err)
+	}
+	return obj, nil
+}
+
+// Print the expression to a io.Writer.
+func (bce *builtinCall2Expr) Print(w io.Writer) (int, error) {
+	ce := CallExpr{ObjExpr{bce.Proc}, []Expr{bce.Arg0, bce.Arg1}}
+	return ce.doPrint(w, "{BCALL-2 ")
+}
+
+// builtinCall3Expr calls a builtin with three args and returns the resulting
+// object. It is an optimization of `CallExpr`.
+type builtinCall3Expr struct {
+	Proc             *Builtin
+	Arg0, Arg1, Arg2 Expr
+}
+
+func (bce *builtinCall3Expr) String() string {
+	return fmt.Sprintf("%v %v %v %v", bce.Proc, bce.Arg0, bce.Arg1, bce.Arg2)
+}
+
+// IsPure signals an expression that has no side effects.
+func (bce *builtinCall3Expr) IsPure() bool {
+	return bce.Arg0.IsPure() && bce.Arg1.IsPure() && bce.Arg2.IsPure() &&
+		bce.Proc.IsPure(sx.Vector{sx.MakeUndefined(), sx.MakeUndefined(), sx.MakeUndefined()})
+}
+
+// Unparse the expression back into a form object.
+func (bce *builtinCall3Expr) Unparse() sx.Object {
+	ce := CallExpr{Proc: ObjExpr{bce.Proc}, Args: []Expr{bce.Arg0, bce.Arg1, bce.Arg2}}
+	return ce.Unparse()
+}
+
+// Compute the value of this expression in the given environment.
+func (bce *builtinCall3Expr) Compute(env *Environment, frame *Frame) (sx.Object, error) {
+	val0, err := env.Execute(bce.Arg0, frame)
+	if err != nil {
+		return nil, err
+	}
+	val1, err := env.Execute(bce.Arg1, frame)
+	if err != nil {
+		return nil, err
+	}
+	val2, err := env.Execute(bce.Arg2, frame)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := bce.Proc.Fn3(env, val0, val1, val2, frame)
+	if err != nil {
+		return nil, bce.Proc.handleCallError(err)
+	}
+	return obj, nil
+}
+
+// Print the expression to a io.Writer.
+func (bce *builtinCall3Expr) Print(w io.Writer) (int, error) {
+	ce := CallExpr{ObjExpr{bce.Proc}, []Expr{bce.Arg0, bce.Arg1, bce.Arg2}}
+	return ce.doPrint(w, "{BCALL-3 ")
+}
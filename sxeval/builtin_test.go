@@ -14,6 +14,8 @@
 package sxeval_test
 
 import (
+	"fmt"
+	"io"
 	"strings"
 	"testing"
 
@@ -94,3 +96,131 @@ func TestBuiltinSimple(t *testing.T) {
 		args = append(args, sx.Nil())
 	}
 }
+
+func TestBuiltinFn2Fn3(t *testing.T) {
+	t.Parallel()
+	list2 := func(_ *sxeval.Environment, arg0, arg1 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return sx.MakeList(sx.MakeString("fn2"), arg0, arg1), nil
+	}
+	list3 := func(_ *sxeval.Environment, arg0, arg1, arg2 sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+		return sx.MakeList(sx.MakeString("fn3"), arg0, arg1, arg2), nil
+	}
+	listN := func(_ *sxeval.Environment, args sx.Vector, _ *sxeval.Frame) (sx.Object, error) {
+		return sx.MakeList(args...).Cons(sx.MakeString("fn")), nil
+	}
+	builtins := []*sxeval.Builtin{
+		{Name: "b23", MinArity: 2, MaxArity: -1, Fn2: list2, Fn3: list3, Fn: listN},
+		{Name: "b2", MinArity: 2, MaxArity: -1, Fn2: list2, Fn: listN},
+		{Name: "bn", MinArity: 2, MaxArity: -1, Fn: listN},
+		{Name: "only2", MinArity: 2, MaxArity: 2, Fn2: list2},
+		{Name: "only3", MinArity: 3, MaxArity: 3, Fn3: list3},
+	}
+	root := sxeval.MakeRootBinding(len(builtins))
+	if err := sxeval.BindBuiltins(root, builtins...); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+
+	testcases := []struct {
+		name string
+		args sx.Vector
+		exp  string
+		op   string
+	}{
+		{"b23", sx.Vector{sx.Int64(1), sx.Int64(2)}, `("fn2" 1 2)`, "BCALL-2"},
+		{"b23", sx.Vector{sx.Int64(1), sx.Int64(2), sx.Int64(3)}, `("fn3" 1 2 3)`, "BCALL-3"},
+		{"b23", sx.Vector{sx.Int64(1), sx.Int64(2), sx.Int64(3), sx.Int64(4)}, `("fn" 1 2 3 4)`, "BCALL"},
+		{"b2", sx.Vector{sx.Int64(1), sx.Int64(2)}, `("fn2" 1 2)`, "BCALL-2"},
+		{"b2", sx.Vector{sx.Int64(1), sx.Int64(2), sx.Int64(3)}, `("fn" 1 2 3)`, "BCALL"},
+		{"bn", sx.Vector{sx.Int64(1), sx.Int64(2)}, `("fn" 1 2)`, "BCALL"},
+		{"bn", sx.Vector{sx.Int64(1), sx.Int64(2), sx.Int64(3)}, `("fn" 1 2 3)`, "BCALL"},
+		{"only2", sx.Vector{sx.Int64(1), sx.Int64(2)}, `("fn2" 1 2)`, "BCALL-2"},
+		{"only3", sx.Vector{sx.Int64(1), sx.Int64(2), sx.Int64(3)}, `("fn3" 1 2 3)`, "BCALL-3"},
+	}
+	for _, tc := range testcases {
+		for _, compile := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/%d/%v", tc.name, len(tc.args), compile), func(t *testing.T) {
+				env := sxeval.MakeEnvironment(root).SetCompile(compile)
+				obj := sx.MakeList(tc.args...).Cons(sx.MakeSymbol(tc.name))
+				expr, err := env.Parse(obj, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var sb strings.Builder
+				if ce, isCompiled := expr.(*sxeval.CompiledExpr); isCompiled {
+					_, err = ce.Disassemble(&sb)
+				} else {
+					_, err = expr.Print(&sb)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := sb.String(); !strings.Contains(got, tc.op+" ") {
+					t.Errorf("%s expected, but got %s", tc.op, got)
+				}
+				res, err := env.Run(expr, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := res.String(); got != tc.exp {
+					t.Errorf("%s expected, but got %s", tc.exp, got)
+				}
+
+				b, _ := root.Lookup(sx.MakeSymbol(tc.name))
+				res, err = b.(*sxeval.Builtin).ExecuteCall(env, tc.args, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := res.String(); got != tc.exp {
+					t.Errorf("ExecuteCall: %s expected, but got %s", tc.exp, got)
+				}
+			})
+		}
+	}
+}
+
+func TestBuiltinFoldEnvironment(t *testing.T) {
+	t.Parallel()
+	var gotWorld *sx.World
+	var gotOut io.Writer
+	b := &sxeval.Builtin{
+		Name:     "fold-environment",
+		MinArity: 1,
+		MaxArity: 1,
+		TestPure: sxeval.AssertPure,
+		Fn1: func(env *sxeval.Environment, arg sx.Object, _ *sxeval.Frame) (sx.Object, error) {
+			gotWorld, gotOut = env.World(), env.Output()
+			if err := env.Alloc(1024); err != nil {
+				return nil, err
+			}
+			return arg, nil
+		},
+	}
+	root := sxeval.MakeRootBinding(1)
+	if err := sxeval.BindBuiltins(root, b); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+
+	world := sx.MakeWorld()
+	var out strings.Builder
+	env := sxeval.MakeEnvironment(root).SetWorld(world).SetOutput(&out)
+	expr, err := env.Parse(sx.MakeList(sx.InitPackage().MakeSymbol(b.Name), sx.Int64(1)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, isConst := sxeval.GetConstExpr(expr); !isConst {
+		t.Errorf("call must be folded, but got %v", expr)
+	}
+	if gotWorld != world || gotOut != &out {
+		t.Errorf("folding must use world and output of the environment, but got %v/%v", gotWorld, gotOut)
+	}
+
+	env = sxeval.MakeEnvironment(root).SetAllocHandler(sxeval.MakeMemoryLimitHandler(16))
+	if expr, err = env.Parse(sx.MakeList(sx.InitPackage().MakeSymbol(b.Name), sx.Int64(1)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, isConst := sxeval.GetConstExpr(expr); isConst {
+		t.Errorf("call must not be folded, if the allocation handler fails, but got %v", expr)
+	}
+}
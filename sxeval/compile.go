@@ -0,0 +1,267 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+import "t73f.de/r/sx"
+
+// Compilable is an additional interface for `Expr` that can be lowered into
+// the instructions of the virtual machine.
+type Compilable interface {
+	// Compile the expression by emitting instructions. If the boolean
+	// argument is true, the expression is in tail position: its code must
+	// return the value of the expression.
+	Compile(*Compiler, bool) error
+}
+
+// Compiler guides the compile operation. It collects the instructions of
+// one compiled expression.
+type Compiler struct {
+	code  []instruction
+	level *level
+}
+
+// level is an expression that the tree walker would compute with its own
+// call of `Environment.Execute`. Levels are used to report errors.
+type level struct {
+	expr   Expr
+	parent *level
+}
+
+// Label is the position of a jump instruction, whose target is set later.
+type Label int
+
+// Compile the given expression in the environment. The result is an
+// expression that is computed by the virtual machine, or the given
+// expression, if it cannot be compiled. Only improved expressions should be
+// compiled.
+func (env *Environment) Compile(expr Expr) (Expr, error) {
+	var c Compiler
+	return c.Lower(expr)
+}
+
+// Lower compiles the given expression into a new, independent expression,
+// e.g. for the body of a lambda. If the expression cannot be compiled, it is
+// returned, where its sub-expressions are possibly compiled.
+func (c *Compiler) Lower(expr Expr) (Expr, error) {
+	if _, isConst := GetConstExpr(expr); isConst {
+		return expr, nil
+	}
+	sub := Compiler{level: &level{expr: expr}}
+	if err := sub.compile(expr, true); err != nil {
+		return expr, err
+	}
+	if code := sub.code; len(code) == 1 && code[0].op == opExprTail {
+		return code[0].expr, nil
+	}
+	return &CompiledExpr{source: expr, code: sub.code}, nil
+}
+
+// Compile the given expression, which the tree walker would compute with
+// `Environment.Execute`. Do not call `expr.Compile()` directly.
+func (c *Compiler) Compile(expr Expr, tail bool) error {
+	saved := c.level
+	c.level = &level{expr: expr, parent: saved}
+	err := c.compile(expr, tail)
+	c.level = saved
+	return err
+}
+
+// CompileTCO compiles the given expression, which the tree walker would
+// compute with `Environment.ExecuteTCO`.
+func (c *Compiler) CompileTCO(expr Expr, tail bool) error {
+	saved := c.level
+	c.level = &level{expr: expr, parent: saved.parent}
+	err := c.compile(expr, tail)
+	c.level = saved
+	return err
+}
+
+func (c *Compiler) compile(expr Expr, tail bool) error {
+	if cexpr, ok := expr.(Compilable); ok {
+		return cexpr.Compile(c, tail)
+	}
+	c.EmitExpr(expr, tail)
+	return nil
+}
+
+// CompileSlice compiles the given expressions, so that their values are
+// pushed onto the stack.
+func (c *Compiler) CompileSlice(exprs []Expr) error {
+	for _, expr := range exprs {
+		if err := c.Compile(expr, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) emit(ins instruction) int {
+	ins.level = c.level
+	c.code = append(c.code, ins)
+	return len(c.code) - 1
+}
+
+// emitValue emits an instruction that pushes a value. In tail position, the
+// value is returned.
+func (c *Compiler) emitValue(ins instruction, tail bool) {
+	c.emit(ins)
+	if tail {
+		c.EmitReturn()
+	}
+}
+
+// EmitExpr emits an instruction that computes the given expression with the
+// tree-walking evaluator. It is the fallback for expressions that are not
+// compilable.
+func (c *Compiler) EmitExpr(expr Expr, tail bool) {
+	if tail {
+		c.emit(instruction{op: opExprTail, expr: expr})
+	} else {
+		c.emit(instruction{op: opExpr, expr: expr})
+	}
+}
+
+// EmitConst emits an instruction that pushes the given object.
+func (c *Compiler) EmitConst(obj sx.Object, tail bool) {
+	c.emitValue(instruction{op: opConst, obj: obj}, tail)
+}
+
+// EmitPop emits an instruction that removes the top value of the stack.
+func (c *Compiler) EmitPop() { c.emit(instruction{op: opPop}) }
+
+// EmitReturn emits an instruction that returns the top value of the stack.
+func (c *Compiler) EmitReturn() { c.emit(instruction{op: opReturn}) }
+
+// EmitJump emits an unconditional jump. Its target must be set via SetLabel.
+func (c *Compiler) EmitJump() Label { return Label(c.emit(instruction{op: opJump})) }
+
+// EmitJumpFalse emits a jump that is taken, if the top value of the stack is
+// false. The value is removed in any case.
+func (c *Compiler) EmitJumpFalse() Label { return Label(c.emit(instruction{op: opJumpFalse})) }
+
+// EmitJumpFalseKeep emits a jump that is taken, if the top value of the
+// stack is false. The value is only removed, if the jump is not taken.
+func (c *Compiler) EmitJumpFalseKeep() Label { return Label(c.emit(instruction{op: opJumpFalseKeep})) }
+
+// EmitJumpTrueKeep emits a jump that is taken, if the top value of the stack
+// is true. The value is only removed, if the jump is not taken.
+func (c *Compiler) EmitJumpTrueKeep() Label { return Label(c.emit(instruction{op: opJumpTrueKeep})) }
+
+// SetLabel sets the target of the given jump to the next instruction.
+func (c *Compiler) SetLabel(l Label) { c.code[l].n = len(c.code) }
+
+// EmitLet emits an instruction that creates a child frame with the given
+// name and layout. The first n slots are bound to the n values on top of
+// the stack, which are removed. The current frame is saved.
+func (c *Compiler) EmitLet(name string, layout *FrameLayout, n int) {
+	c.emit(instruction{op: opLet, name: name, layout: layout, n: n})
+}
+
+// EmitBind emits an instruction that binds the symbol in the current frame to
+// the top value of the stack, which is removed.
+func (c *Compiler) EmitBind(sym *sx.Symbol) { c.emit(instruction{op: opBind, sym: sym}) }
+
+// EmitPopFrame emits an instruction that restores the frame, which was saved
+// by the last EmitLet.
+func (c *Compiler) EmitPopFrame() { c.emit(instruction{op: opPopFrame}) }
+
+// ----- Compile methods of the basic expressions
+
+// Compile the expression.
+func (nilExpr) Compile(c *Compiler, tail bool) error {
+	c.EmitConst(sx.Nil(), tail)
+	return nil
+}
+
+// Compile the expression.
+func (oe ObjExpr) Compile(c *Compiler, tail bool) error {
+	c.EmitConst(oe.Obj, tail)
+	return nil
+}
+
+// Compile the expression.
+func (use UnboundSymbolExpr) Compile(c *Compiler, tail bool) error {
+	c.emitValue(instruction{op: opResolve, sym: use.sym}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (fse *frameSymbolExpr) Compile(c *Compiler, tail bool) error {
+	c.emitValue(instruction{op: opLookup, sym: fse.sym, n: fse.lvl, slot: fse.slot}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (ese *envSymbolExpr) Compile(c *Compiler, tail bool) error {
+	c.emitValue(instruction{op: opGlobal, sym: ese.sym, expr: ese}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (ce *CallExpr) Compile(c *Compiler, tail bool) error {
+	if err := c.CompileSlice(ce.Args); err != nil {
+		return err
+	}
+	if err := c.Compile(ce.Proc, false); err != nil {
+		return err
+	}
+	if tail {
+		c.emit(instruction{op: opCallTail, n: len(ce.Args)})
+	} else {
+		c.emit(instruction{op: opCall, n: len(ce.Args)})
+	}
+	return nil
+}
+
+// Compile the expression.
+func (bce *builtinCallExpr) Compile(c *Compiler, tail bool) error {
+	if err := c.CompileSlice(bce.Args); err != nil {
+		return err
+	}
+	c.emitValue(instruction{op: opBuiltin, n: len(bce.Args), b: bce.Proc}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (bce *builtinCall0Expr) Compile(c *Compiler, tail bool) error {
+	c.emitValue(instruction{op: opBuiltin0, b: bce.Proc}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (bce *BuiltinCall1Expr) Compile(c *Compiler, tail bool) error {
+	if err := c.Compile(bce.Arg, false); err != nil {
+		return err
+	}
+	c.emitValue(instruction{op: opBuiltin1, b: bce.Proc}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (bce *builtinCall2Expr) Compile(c *Compiler, tail bool) error {
+	if err := c.CompileSlice([]Expr{bce.Arg0, bce.Arg1}); err != nil {
+		return err
+	}
+	c.emitValue(instruction{op: opBuiltin2, b: bce.Proc}, tail)
+	return nil
+}
+
+// Compile the expression.
+func (bce *builtinCall3Expr) Compile(c *Compiler, tail bool) error {
+	if err := c.CompileSlice([]Expr{bce.Arg0, bce.Arg1, bce.Arg2}); err != nil {
+		return err
+	}
+	c.emitValue(instruction{op: opBuiltin3, b: bce.Proc}, tail)
+	return nil
+}
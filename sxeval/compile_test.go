@@ -0,0 +1,236 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+func TestCompileSameAsWalk(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+
+	testcases := []struct {
+		name string
+		src  string
+	}{
+		{"values", "1 () 'a (list 1 2) (if 1 2 3) (begin 1 2)"},
+		{"let", "(let ((a 1) (b 2)) (let* ((c (+ a b)) (d (* c 2))) (list a b c d)))"},
+		{"let-non-tail", "(list (let ((a 1)) a) (let* ((a 1) (a (+ a 1))) a) 3)"},
+		{"and-or", "(list (and) (and 1 2) (and 1 () 2) (or) (or () 2) (or () ()))"},
+		{"lambda", "((lambda (x . r) (list x r)) 1 2 3)"},
+		{"closure", "(defun adder (n) (lambda (x) (+ x n))) (map (adder 3) '(1 2 3))"},
+		{"set", "(defvar v 1) (set! v (+ v 1)) (let ((w 1)) (set! w (+ w v)) w)"},
+		{"catch", "(catch 'a (list 1 (throw 'a 2)))"},
+		{"call/ec", "(list 1 (call/ec (lambda (k) (list 2 (k 3)))) 4)"},
+		{"tco", "(defun loop (n) (if (= n 0) 'done (loop (- n 1)))) (loop 100000)"},
+		{"err-builtin", "(defun f (x) (+ x 'a)) (list 1 (f 2))"},
+		{"err-nested", "(defun g (x) (raise 'k \"msg\" x)) (defun f (x) (if (car (g x)) 1 2)) (begin (f 3) 4)"},
+		{"err-let", "(let ((a 1)) (let* ((b (+ a 1)) (c (car b))) c))"},
+		{"err-unbound", "(and 1 (or () (list undefined-sym)))"},
+		{"err-arity", "(defun h (a b) a) (list (h 1))"},
+		{"err-arity-tail", "(defun h (a b) a) (h 1 2 3)"},
+		{"err-not-callable", "(list (1 2))"},
+		{"err-not-callable-tail", "((lambda (x) (x)) 1)"},
+		{"err-set", "(defvar v 1) (set! v (car v))"},
+		{"err-map", "(map (lambda (x) (car x)) '(1 2))"},
+		{"err-if-branch", "(defun f (x) (list (if x (car x) 0))) (f 1)"},
+		{"deep", "(defun d (n) (if (= n 0) () (cons n (d (- n 1))))) (d 5)"},
+		{"err-deep", "(defun d (n) (if (= n 0) (car n) (list (d (- n 1))))) (d 3)"},
+		{"throw-deep", "(defun d (n) (if (= n 0) (throw 'x 42) (list (d (- n 1))))) (catch 'x (d 5))"},
+		{"err-deep-expr-tail", "(defun c (n) (if n (catch 'y (car n)) 0)) (defun e (n) (list (c n))) (list (e ()) (e 1))"},
+		{"err-deep-call-tail", "(defun nc (n) (catch 'y (car n))) (defun tc (n) (if n (nc n) 0)) (list (list (tc ())) (list (tc 1)))"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			walk := evalForCompare(t, root, tc.src, false)
+			vm := evalForCompare(t, root, tc.src, true)
+			if walk != vm {
+				t.Errorf("different results\nwalk: %s\nvm:   %s", walk, vm)
+			}
+		})
+	}
+}
+
+// evalForCompare evaluates the source and returns the results, including
+// error messages and the call stacks of errors.
+func evalForCompare(t *testing.T, root *sxeval.Binding, src string, compile bool) string {
+	t.Helper()
+	objs, err := sxreader.MakeReader(strings.NewReader(src)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(root.MakeChildBinding("compare", 8)).SetCompile(compile)
+	var sb strings.Builder
+	for _, obj := range objs {
+		res, errEval := env.Eval(obj, nil)
+		if size := env.Size(); size > 0 {
+			t.Errorf("compile=%v: stack not empty, size: %d", compile, size)
+		}
+		if errEval != nil {
+			fmt.Fprintf(&sb, "{%v", errEval)
+			var execErr sxeval.ExecuteError
+			if errors.As(errEval, &execErr) {
+				for _, state := range execErr.CallStack {
+					fmt.Fprintf(&sb, " [%v]", state.Expr.Unparse())
+				}
+			}
+			sb.WriteString("} ")
+			continue
+		}
+		_, _ = sx.Print(&sb, res)
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
+
+func TestCompileDisassemble(t *testing.T) {
+	t.Parallel()
+	root := createBindingForTCO(false)
+	env := sxeval.MakeEnvironment(root)
+	obj, err := sxreader.MakeReader(strings.NewReader("(lambda (n) (if (= n 0) 1 (* n (fac (- n 1)))))")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.Parse(obj, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err = env.Compile(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := env.Run(expr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ll, isLambda := val.(*sxbuiltins.LexLambda)
+	if !isLambda {
+		t.Fatalf("lambda expected, but got %T/%v", val, val)
+	}
+	ce, isCompiled := ll.Expr.(*sxeval.CompiledExpr)
+	if !isCompiled {
+		t.Fatalf("compiled body expected, but got %T/%v", ll.Expr, ll.Expr)
+	}
+	var sb strings.Builder
+	if _, err = ce.Disassemble(&sb); err != nil {
+		t.Fatal(err)
+	}
+	exp := `  0 LOOKUP n/0.0
+  1 CONST 0
+  2 BCALL-2 =
+  3 JUMP-FALSE 6
+  4 CONST 1
+  5 RETURN
+  6 LOOKUP n/0.0
+  7 LOOKUP n/0.0
+  8 CONST 1
+  9 BCALL-2 -
+ 10 GLOBAL fac
+ 11 CALL 1
+ 12 BCALL-2 *
+ 13 RETURN
+`
+	if got := sb.String(); got != exp {
+		t.Errorf("expected:\n%s\nbut got:\n%s", exp, got)
+	}
+}
+
+func TestCompileStepsLimit(t *testing.T) {
+	t.Parallel()
+	root := createBindingForTCO(true)
+	obj, err := sxreader.MakeReader(strings.NewReader("(even? 1000000)")).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(root).SetCompile(true).
+		SetComputeHandler(sxeval.MakeStepsLimitHandler(1000, sxeval.DefaultHandler{}))
+	_, err = env.Eval(obj, nil)
+	var stepsErr sxeval.ErrStepsLimit
+	if !errors.As(err, &stepsErr) {
+		t.Errorf("steps limit error expected, but got %v", err)
+	}
+}
+
+func TestCompileDeepRecursion(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	bind := root.MakeChildBinding("deep", 8)
+	const length = 1000000
+	var lb sx.ListBuilder
+	for i := range length {
+		lb.Add(sx.Int64(i))
+	}
+	if err := bind.Bind(sx.MakeSymbol("deep-list"), lb.List()); err != nil {
+		t.Fatal(err)
+	}
+
+	eval := func(env *sxeval.Environment, src string) (sx.Object, error) {
+		t.Helper()
+		objs, err := sxreader.MakeReader(strings.NewReader(src)).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res sx.Object
+		for _, obj := range objs {
+			if res, err = env.Eval(obj, nil); err != nil {
+				break
+			}
+		}
+		if size := env.Size(); size > 0 {
+			t.Errorf("stack not empty, size: %d", size)
+		}
+		return res, err
+	}
+
+	env := sxeval.MakeEnvironment(bind).SetCompile(true)
+	res, err := eval(env, "(defun len (l) (if (null? l) 0 (+ 1 (len (cdr l))))) (len deep-list)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.IsEqual(sx.Int64(length)) {
+		t.Errorf("%d expected, but got %v", length, res)
+	}
+
+	env = sxeval.MakeEnvironment(bind).SetCompile(true).SetMaxControlDepth(1000)
+	_, err = eval(env, "(len deep-list)")
+	var depthErr sxeval.ErrControlDepth
+	if !errors.As(err, &depthErr) {
+		t.Fatalf("control depth error expected, but got %v", err)
+	}
+	var execErr sxeval.ExecuteError
+	if !errors.As(err, &execErr) || len(execErr.CallStack) < 1000 {
+		t.Errorf("call stack of all callers expected, but got %v", err)
+	}
+	res, err = eval(env, "(len '(1 2 3))")
+	if err != nil || !res.IsEqual(sx.Int64(3)) {
+		t.Errorf("3 expected after error, but got %v/%v", res, err)
+	}
+}
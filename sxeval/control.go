@@ -0,0 +1,46 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+import "errors"
+
+// ControlError is an error that transfers the control to an outer
+// expression, e.g. a throw to its catch. It is no error in the usual sense.
+// Therefore, no call stack is recorded and no CallError is added, while it
+// unwinds the computation.
+type ControlError interface {
+	error
+
+	// IsControl is just a marker method.
+	IsControl()
+}
+
+// IsControlError returns true, if the error is a ControlError or wraps one.
+func IsControlError(err error) bool {
+	var ce ControlError
+	return errors.As(err, &ce)
+}
+
+// IsLimitError returns true, if the error signals that a computation was
+// stopped, because a resource limit was exceeded or the context was
+// cancelled. Such errors must not be caught by the computed code.
+func IsLimitError(err error) bool {
+	var nestingErr ErrNestingLimit
+	var stepsErr ErrStepsLimit
+	var memErr ErrMemoryLimit
+	var expandErr ErrExpandLimit
+	var ctxErr ContextError
+	return errors.As(err, &nestingErr) || errors.As(err, &stepsErr) ||
+		errors.As(err, &memErr) || errors.As(err, &expandErr) || errors.As(err, &ctxErr)
+}
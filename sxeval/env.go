@@ -14,11 +14,13 @@
 package sxeval
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"iter"
 	"log/slog"
+	"os"
 	"slices"
 	"strings"
 
@@ -41,8 +43,20 @@ type Environment struct {
 	newFrame *Frame
 
 	handler   ComputeHandler
+	alloc     AllocHandler
 	obParse   ParseObserver
+	obExpand  ExpandObserver
 	obImprove ImproveObserver
+
+	ctx  context.Context
+	done <-chan struct{}
+
+	compile  bool
+	depth    int // current depth of the control stack of the virtual machine
+	maxDepth int
+
+	world *sx.World
+	out   io.Writer
 }
 
 func (env *Environment) String() string {
@@ -81,27 +95,113 @@ func MakeEnvironment(globals *Binding) *Environment {
 	return &Environment{
 		stack:   make([]sx.Object, 0, 1024),
 		globals: globals,
+		world:   sx.DefaultWorld(),
+		out:     os.Stdout,
+	}
+}
+
+// derive returns a new environment with its own stack, that uses the same
+// global binding, world of packages, output, allocation handler, and context.
+// Compute handler and observers are not set.
+func (env *Environment) derive() *Environment {
+	return &Environment{
+		stack:    make([]sx.Object, 0, 64),
+		globals:  env.globals,
+		alloc:    env.alloc,
+		ctx:      env.ctx,
+		done:     env.done,
+		maxDepth: env.maxDepth,
+		world:    env.world,
+		out:      env.out,
 	}
 }
 
+// SetWorld sets the world of packages, which is used by the computation.
+func (env *Environment) SetWorld(world *sx.World) *Environment {
+	env.world = world
+	return env
+}
+
+// World returns the world of packages, which is used by the computation.
+func (env *Environment) World() *sx.World { return env.world }
+
+// SetOutput sets the writer, where builtins write their output. By default,
+// it is the standard output.
+func (env *Environment) SetOutput(w io.Writer) *Environment {
+	env.out = w
+	return env
+}
+
+// Output returns the writer, where builtins write their output.
+func (env *Environment) Output() io.Writer { return env.out }
+
 // SetComputeHandler sets the given compute observer.
 func (env *Environment) SetComputeHandler(handler ComputeHandler) *Environment {
 	env.handler = handler
 	return env
 }
 
+// AllocHandler is notified about memory allocations of builtins. It allows to
+// limit the memory used by a computation.
+type AllocHandler interface {
+	// Alloc is called before the given number of bytes will be allocated.
+	// If an error is returned, the allocation will not take place.
+	Alloc(size int) error
+
+	// Reset internal counter, etc., to bring the handler into a start state.
+	Reset()
+}
+
+// SetAllocHandler sets the given allocation handler.
+func (env *Environment) SetAllocHandler(handler AllocHandler) *Environment {
+	env.alloc = handler
+	return env
+}
+
+// Alloc signals that the given number of bytes will be allocated. Builtins
+// that create new lists, vectors, or strings should call this method before
+// the allocation. If an error is returned, the allocation must not take place.
+func (env *Environment) Alloc(size int) error {
+	if handler := env.alloc; handler != nil {
+		return handler.Alloc(size)
+	}
+	return nil
+}
+
 // SetParseObserver sets the given parsing observer.
 func (env *Environment) SetParseObserver(observe ParseObserver) *Environment {
 	env.obParse = observe
 	return env
 }
 
+// SetExpandObserver sets the given macro expansion observer.
+func (env *Environment) SetExpandObserver(observe ExpandObserver) *Environment {
+	env.obExpand = observe
+	return env
+}
+
 // SetImproveObserver sets the given improve observer.
 func (env *Environment) SetImproveObserver(observe ImproveObserver) *Environment {
 	env.obImprove = observe
 	return env
 }
 
+// SetCompile enables or disables the compilation of parsed expressions for
+// the virtual machine.
+func (env *Environment) SetCompile(compile bool) *Environment {
+	env.compile = compile
+	return env
+}
+
+// SetMaxControlDepth sets the maximum depth of the control stack of the
+// virtual machine. If the depth is exceeded, an ErrControlDepth is returned.
+// A value of zero or less means no limit, i.e. deep recursion is only limited
+// by the available memory.
+func (env *Environment) SetMaxControlDepth(depth int) *Environment {
+	env.maxDepth = depth
+	return env
+}
+
 // Eval parses the given object and runs it in the environment.
 func (env *Environment) Eval(obj sx.Object, frame *Frame) (sx.Object, error) {
 	expr, err := env.Parse(obj, frame)
@@ -125,7 +225,11 @@ func (env *Environment) Parse(obj sx.Object, frame *Frame) (Expr, error) {
 		observer: env.obImprove,
 	}
 	imp.parent = imp
-	return imp.Improve(expr)
+	expr, err = imp.Improve(expr)
+	if err != nil || !env.compile {
+		return expr, err
+	}
+	return env.Compile(expr)
 }
 
 // MakeParseEnvironment builds a parsing environment to parse a form.
@@ -133,17 +237,80 @@ func (env *Environment) MakeParseEnvironment() *ParseEnvironment {
 	return &ParseEnvironment{env: env}
 }
 
+// EvalContext parses the given object and runs it in the environment. The
+// computation is stopped, if the context is cancelled or its deadline is
+// exceeded. This applies to parsing, e.g. to the expansion of macros, too.
+func (env *Environment) EvalContext(ctx context.Context, obj sx.Object, frame *Frame) (sx.Object, error) {
+	oldCtx, oldDone := env.ctx, env.done
+	env.ctx, env.done = ctx, ctx.Done()
+	defer func() { env.ctx, env.done = oldCtx, oldDone }()
+	expr, err := env.Parse(obj, frame)
+	if err != nil {
+		return sx.Nil(), err
+	}
+	return env.Run(expr, frame)
+}
+
 // Run the given expression.
 func (env *Environment) Run(expr Expr, frame *Frame) (sx.Object, error) {
 	if ob := env.handler; ob != nil {
 		ob.Reset()
 	}
+	if handler := env.alloc; handler != nil {
+		handler.Reset()
+	}
 	return env.Execute(expr, frame)
 }
 
+// RunContext runs the given expression, as long as the context is not
+// cancelled and its deadline is not exceeded. Otherwise, an error is returned
+// that wraps a ContextError.
+func (env *Environment) RunContext(ctx context.Context, expr Expr, frame *Frame) (sx.Object, error) {
+	oldCtx, oldDone := env.ctx, env.done
+	env.ctx, env.done = ctx, ctx.Done()
+	defer func() { env.ctx, env.done = oldCtx, oldDone }()
+	return env.Run(expr, frame)
+}
+
+// Context returns the context of the current computation. If no context was
+// given, context.Background() is returned.
+func (env *Environment) Context() context.Context {
+	if ctx := env.ctx; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// CheckContext returns a ContextError, if the context of the current
+// computation is cancelled or its deadline is exceeded. Long-running builtins
+// should call it regularly.
+func (env *Environment) CheckContext() error {
+	if env.done == nil {
+		return nil
+	}
+	select {
+	case <-env.done:
+		return ContextError{err: env.ctx.Err()}
+	default:
+		return nil
+	}
+}
+
+// ContextError signals that a computation was stopped, because its context
+// was cancelled or its deadline was exceeded.
+type ContextError struct{ err error }
+
+func (e ContextError) Error() string { return "computation stopped: " + e.err.Error() }
+func (e ContextError) Unwrap() error { return e.err }
+
 // Execute the given expression.
 func (env *Environment) Execute(expr Expr, frame *Frame) (res sx.Object, err error) {
 	for {
+		if env.done != nil {
+			if err = env.CheckContext(); err != nil {
+				break
+			}
+		}
 		if handler := env.handler; handler != nil {
 			res, err = handler.Compute(env, expr, frame)
 		} else {
@@ -182,6 +349,12 @@ func (env *Environment) ApplyMacro(name string, fn Callable, args sx.Vector, fra
 
 // Apply the given Callable with the arguments.
 func (env *Environment) Apply(fn Callable, args sx.Vector, frame *Frame) (res sx.Object, err error) {
+	if env.done != nil {
+		// Builtins like `map` call Apply often, so this is a safe point.
+		if err = env.CheckContext(); err != nil {
+			return nil, env.addExecuteError(&applyErrExpr{Proc: fn, Args: args}, frame, err)
+		}
+	}
 	if res, err = fn.ExecuteCall(env, args, frame); err == nil {
 		return res, nil
 	}
@@ -221,6 +394,12 @@ func (ce *applyErrExpr) Print(w io.Writer) (int, error) {
 var errExecuteAgain = errors.New("TCO trampoline")
 
 func (env *Environment) addExecuteError(expr Expr, frame *Frame, err error) error {
+	if _, isControl := err.(ControlError); isControl {
+		return err
+	}
+	if compErr, isCompiled := err.(compiledError); isCompiled {
+		return compErr.ExecuteError
+	}
 	var execError ExecuteError
 	if errors.As(err, &execError) {
 		execError.CallStack = append(
@@ -307,9 +486,60 @@ func (env *Environment) Resolve(sym *sx.Symbol, frame *Frame) (sx.Object, bool)
 	if obj, found := env.globals.Resolve(sym); found {
 		return obj, true
 	}
+	return env.SymbolValue(sym)
+}
+
+// SymbolValue returns the value of the symbol. If the global binding is
+// isolated, the value is retrieved from its symbol table. Only frozen global
+// symbol values are used in this case.
+func (env *Environment) SymbolValue(sym *sx.Symbol) (sx.Object, bool) {
+	if st := env.globals.symbolTable(); st != nil {
+		if entry, found := st.lookup(sym); found && entry.obj != nil {
+			return entry.obj, true
+		}
+		if sym.IsFrozen() {
+			return sym.Bound()
+		}
+		return nil, false
+	}
 	return sym.Bound()
 }
 
+// SetSymbolValue sets the value of the symbol. If the global binding is
+// isolated, the value is stored in its symbol table, and not globally.
+func (env *Environment) SetSymbolValue(sym *sx.Symbol, val sx.Object) error {
+	if st := env.globals.symbolTable(); st != nil {
+		if sym.IsFrozen() {
+			return sx.ErrSymbolFrozen{Symbol: sym}
+		}
+		return st.bind(sym, val)
+	}
+	return sym.Bind(val)
+}
+
+// FreezeSymbolValue forbids future updates of the symbol value.
+func (env *Environment) FreezeSymbolValue(sym *sx.Symbol) {
+	if st := env.globals.symbolTable(); st != nil {
+		if !sym.IsFrozen() {
+			st.freeze(sym)
+		}
+		return
+	}
+	sym.Freeze()
+}
+
+// IsSymbolFrozen returns true, if the symbol value cannot be updated.
+func (env *Environment) IsSymbolFrozen(sym *sx.Symbol) bool {
+	if sym.IsFrozen() {
+		return true
+	}
+	if st := env.globals.symbolTable(); st != nil {
+		entry, found := st.lookup(sym)
+		return found && entry.frozen
+	}
+	return false
+}
+
 // MakeNotBoundError builds an error to signal that a symbol was not bound in
 // the environment.
 func (env *Environment) MakeNotBoundError(sym *sx.Symbol, frame *Frame) NotBoundError {
@@ -0,0 +1,171 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+import (
+	"fmt"
+
+	"t73f.de/r/sx"
+)
+
+// Expander is a syntax that transforms its call into another form, i.e. a
+// macro.
+type Expander interface {
+	Syntax
+
+	// Expand the macro call with the given arguments.
+	Expand(*ParseEnvironment, *sx.Pair, *Frame) (sx.Object, error)
+}
+
+// ExpandObserver monitors the expansion of macros.
+type ExpandObserver interface {
+	// AfterExpand is called immediate after the given macro call was
+	// expanded into the given form.
+	AfterExpand(*ParseEnvironment, *sx.Pair, sx.Object, *Frame, error)
+}
+
+// expandLimit is the maximum number of macro expansions of a parse
+// environment. It guards against macros that expand without end, because
+// expanding a macro does not count as a computation step.
+const expandLimit = 1 << 14
+
+// ErrExpandLimit is an error to signal that the number of macro expansions is
+// exceeded.
+type ErrExpandLimit struct{ expansions int }
+
+func (e ErrExpandLimit) Error() string {
+	return fmt.Sprintf("allowed macro expansions exceeded: %d", e.expansions)
+}
+
+// expand the macro call of the given expander.
+func (pe *ParseEnvironment) expand(ex Expander, call *sx.Pair, frame *Frame) (sx.Object, error) {
+	if pe.expansions++; pe.expansions > expandLimit {
+		return nil, ErrExpandLimit{expandLimit}
+	}
+	if err := pe.env.CheckContext(); err != nil {
+		return nil, err
+	}
+	form, err := ex.Expand(pe, call.Tail(), frame)
+	if observer := pe.env.obExpand; observer != nil {
+		observer.AfterExpand(pe, call, form, frame, err)
+	}
+	return form, err
+}
+
+// resolveSyntax returns the syntax that is denoted by the given object,
+// which is typically the first element of a list.
+func (pe *ParseEnvironment) resolveSyntax(obj sx.Object, frame *Frame) (Syntax, bool) {
+	if sym, isSymbol := sx.GetSymbol(obj); isSymbol {
+		if val, found := pe.env.Resolve(pe.resolveAlias(sym, frame)); found {
+			return GetSyntax(val)
+		}
+	}
+	return nil, false
+}
+
+// MacroExpand1 expands the given form once, if it is a macro call. The
+// boolean result signals whether the form was expanded.
+func (pe *ParseEnvironment) MacroExpand1(form sx.Object, frame *Frame) (sx.Object, bool, error) {
+	call, isPair := sx.GetPair(form)
+	if !isPair || call == nil {
+		return form, false, nil
+	}
+	sy, found := pe.resolveSyntax(call.Car(), frame)
+	if !found {
+		return form, false, nil
+	}
+	ex, isExpander := sy.(Expander)
+	if !isExpander {
+		return form, false, nil
+	}
+	result, err := pe.expand(ex, call, frame)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
+
+// MacroExpand expands the given form repeatedly, until it is no macro call.
+// The boolean result signals whether the form was expanded at all.
+func (pe *ParseEnvironment) MacroExpand(form sx.Object, frame *Frame) (sx.Object, bool, error) {
+	expanded := false
+	for {
+		result, again, err := pe.MacroExpand1(form, frame)
+		if err != nil || !again {
+			return result, expanded, err
+		}
+		form, expanded = result, true
+	}
+}
+
+// MacroExpandAll expands all macro calls within the given form. Arguments
+// of special forms are expanded according to their `Walk` function, so that
+// quoted data is left untouched and local bindings shadow global macros.
+func (pe *ParseEnvironment) MacroExpandAll(form sx.Object, frame *Frame) (sx.Object, error) {
+	form, _, err := pe.MacroExpand(form, frame)
+	if err != nil {
+		return nil, err
+	}
+	call, isPair := sx.GetPair(form)
+	if !isPair || call == nil {
+		return form, nil
+	}
+	head := call.Car()
+	if sy, found := pe.resolveSyntax(head, frame); found {
+		sp, isSpecial := sy.(*Special)
+		if !isSpecial {
+			// Unknown syntax: it is not known, which arguments are forms.
+			return form, nil
+		}
+		if sp.Walk != nil {
+			args, err2 := sp.Walk(pe, call.Tail(), frame)
+			if err2 != nil {
+				return nil, CallError{Name: sp.Name, Err: err2}
+			}
+			return sx.Cons(head, args), nil
+		}
+	} else if _, isSymbol := sx.GetSymbol(head); !isSymbol {
+		if head, err = pe.MacroExpandAll(head, frame); err != nil {
+			return nil, err
+		}
+	}
+	args, err := pe.MacroExpandAllList(call.Tail(), frame)
+	if err != nil {
+		return nil, err
+	}
+	return sx.Cons(head, args), nil
+}
+
+// MacroExpandAllList expands all macro calls within the elements of the
+// given list. The tail of an improper list is not expanded.
+func (pe *ParseEnvironment) MacroExpandAllList(lst *sx.Pair, frame *Frame) (*sx.Pair, error) {
+	var lb sx.ListBuilder
+	var node sx.Object = lst
+	for {
+		pair, isPair := sx.GetPair(node)
+		if !isPair {
+			lb.Last().SetCdr(node)
+			return lb.List(), nil
+		}
+		if pair == nil {
+			return lb.List(), nil
+		}
+		obj, err := pe.MacroExpandAll(pair.Car(), frame)
+		if err != nil {
+			return nil, err
+		}
+		lb.Add(obj)
+		node = pair.Cdr()
+	}
+}
@@ -0,0 +1,97 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+type expandLog struct{ steps []string }
+
+func (el *expandLog) AfterExpand(_ *sxeval.ParseEnvironment, call *sx.Pair, form sx.Object, _ *sxeval.Frame, err error) {
+	el.steps = append(el.steps, fmt.Sprintf("%v => %v %v", call, form, err))
+}
+
+func TestMacroExpand(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(128)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	env := sxeval.MakeEnvironment(root.MakeChildBinding("expand", 8))
+	read := func(src string) sx.Object {
+		obj, err := sxreader.MakeReader(strings.NewReader(src)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return obj
+	}
+	for _, src := range []string{
+		"(defmacro inc (var) `(add! ,var 1))",
+		"(defmacro add! (var n) `(set! ,var (+ ,var ,n)))",
+	} {
+		if _, err := env.Eval(read(src), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var log expandLog
+	env.SetExpandObserver(&log)
+	pe := env.MakeParseEnvironment()
+
+	form := read("(inc a)")
+	res, expanded, err := pe.MacroExpand1(form, nil)
+	if err != nil || !expanded || res.String() != "(add! a 1)" {
+		t.Errorf("MacroExpand1: got %v/%v/%v", res, expanded, err)
+	}
+	res, expanded, err = pe.MacroExpand(form, nil)
+	if err != nil || !expanded || res.String() != "(set! a (+ a 1))" {
+		t.Errorf("MacroExpand: got %v/%v/%v", res, expanded, err)
+	}
+	res, expanded, err = pe.MacroExpand(read("(list (inc a))"), nil)
+	if err != nil || expanded || res.String() != "(list (inc a))" {
+		t.Errorf("MacroExpand of non-macro: got %v/%v/%v", res, expanded, err)
+	}
+
+	frame := makeRootFrame(1)
+	frame.Bind(sx.MakeSymbol("inc"), sx.MakeUndefined())
+	res, expanded, err = pe.MacroExpand(form, frame)
+	if err != nil || expanded || res.String() != "(inc a)" {
+		t.Errorf("MacroExpand of shadowed macro: got %v/%v/%v", res, expanded, err)
+	}
+
+	res, err = pe.MacroExpandAll(read("(begin (inc a) '(inc b))"), nil)
+	if err != nil || res.String() != "(begin (set! a (+ a 1)) (quote (inc b)))" {
+		t.Errorf("MacroExpandAll: got %v/%v", res, err)
+	}
+
+	exp := []string{
+		"(inc a) => (add! a 1) <nil>",
+		"(inc a) => (add! a 1) <nil>",
+		"(add! a 1) => (set! a (+ a 1)) <nil>",
+		"(inc a) => (add! a 1) <nil>",
+		"(add! a 1) => (set! a (+ a 1)) <nil>",
+	}
+	if got := strings.Join(log.steps, "\n"); got != strings.Join(exp, "\n") {
+		t.Errorf("expected expansion steps:\n%s\nbut got:\n%s", strings.Join(exp, "\n"), got)
+	}
+}
@@ -0,0 +1,25 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+// IsGlobalCacheValid returns true, if the expression is a global lookup,
+// whose cached result is valid within the given environment.
+func IsGlobalCacheValid(expr Expr, env *Environment) bool {
+	ese, isEnvSymbol := expr.(*envSymbolExpr)
+	if !isEnvSymbol {
+		return false
+	}
+	c := ese.cache.Load()
+	return c != nil && c.globals == env.globals && c.version == env.globals.chainVersion()
+}
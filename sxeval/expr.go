@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync/atomic"
 
 	"t73f.de/r/sx"
 )
@@ -146,7 +147,8 @@ func (use UnboundSymbolExpr) Improve(imp *Improver) (Expr, error) {
 	depth := 0
 	for currFrame := imp.frame; currFrame != nil; currFrame = currFrame.parent {
 		if _, found := currFrame.Lookup(sym); found {
-			return imp.Improve(&frameSymbolExpr{sym: sym, lvl: depth})
+			imp.markUsed(currFrame, sym)
+			return imp.Improve(&frameSymbolExpr{sym: sym, lvl: depth, slot: currFrame.slotOf(sym)})
 		}
 		depth++
 	}
@@ -160,15 +162,15 @@ func (use UnboundSymbolExpr) Improve(imp *Improver) (Expr, error) {
 			if currBinding.frozen {
 				return imp.Improve(ObjExpr{Obj: obj})
 			}
-			return imp.Improve(&envSymbolExpr{sym})
+			return imp.Improve(&envSymbolExpr{sym: sym})
 		}
 	}
 
-	if obj, found := sym.Bound(); found && sym.IsFrozen() {
+	if obj, found := imp.env.SymbolValue(sym); found && imp.env.IsSymbolFrozen(sym) {
 		return imp.Improve(ObjExpr{Obj: obj})
 	}
 
-	return imp.Improve(&envSymbolExpr{sym})
+	return imp.Improve(&envSymbolExpr{sym: sym})
 }
 
 // Compute the expression in a frame and return the result.
@@ -185,10 +187,12 @@ func (use UnboundSymbolExpr) Print(w io.Writer) (int, error) {
 }
 
 // frameSymbolExpr is a special UnboundSymbolExpr that gives an indication
-// about the nesting level of `Frame`s, where the symbol will be bound.
+// about the nesting level of `Frame`s, where the symbol will be bound. If the
+// symbol is stored in a slot of that frame, its index is known too.
 type frameSymbolExpr struct {
-	sym *sx.Symbol
-	lvl int
+	sym  *sx.Symbol
+	lvl  int
+	slot int // -1, if there is no slot
 }
 
 // IsPure signals an expression that has no side effects.
@@ -199,7 +203,7 @@ func (fse *frameSymbolExpr) Unparse() sx.Object { return fse.sym }
 
 // Compute the expression in a frame and return the result.
 func (fse *frameSymbolExpr) Compute(env *Environment, frame *Frame) (sx.Object, error) {
-	if obj, found := frame.lookupN(fse.sym, fse.lvl); found {
+	if obj, found := frame.lookupN(fse.sym, fse.lvl, fse.slot); found {
 		return obj, nil
 	}
 	return nil, env.MakeNotBoundError(fse.sym, frame)
@@ -207,13 +211,27 @@ func (fse *frameSymbolExpr) Compute(env *Environment, frame *Frame) (sx.Object,
 
 // Print the expression on the given writer.
 func (fse frameSymbolExpr) Print(w io.Writer) (int, error) {
+	if fse.slot >= 0 {
+		return fmt.Fprintf(w, "{LOOKUP/%d.%d %v}", fse.lvl, fse.slot, fse.sym)
+	}
 	return fmt.Fprintf(w, "{LOOKUP/%d %v}", fse.lvl, fse.sym)
 }
 
 // envSymbolExpr is a special UnboundSymbolExpr that knows the symbol is not
-// bound in the static environment (frames).
+// bound in the static environment (frames). The result of the last lookup is
+// cached.
 type envSymbolExpr struct {
-	sym *sx.Symbol
+	sym   *sx.Symbol
+	cache atomic.Pointer[globalCache]
+}
+
+// globalCache stores the result of a global lookup. It is valid as long as
+// the same global binding is used and no binding with the same root binding
+// was changed.
+type globalCache struct {
+	globals *Binding
+	version uint64
+	obj     sx.Object // nil, if the symbol value was used
 }
 
 // IsPure signals an expression that has no side effects.
@@ -224,20 +242,32 @@ func (ese *envSymbolExpr) Unparse() sx.Object { return ese.sym }
 
 // Compute the expression in a frame and return the result.
 func (ese *envSymbolExpr) Compute(env *Environment, frame *Frame) (sx.Object, error) {
+	version := env.globals.chainVersion()
+	if c := ese.cache.Load(); c != nil && c.version == version && c.globals == env.globals {
+		if obj := c.obj; obj != nil {
+			return obj, nil
+		}
+		if obj, found := env.SymbolValue(ese.sym); found {
+			return obj, nil
+		}
+	}
+
 	sym := ese.sym
 	for curr := env.globals; curr != nil; curr = curr.parent {
 		if obj, found := curr.Lookup(sym); found {
+			ese.cache.Store(&globalCache{globals: env.globals, version: version, obj: obj})
 			return obj, nil
 		}
 	}
-	if obj, found := sym.Bound(); found {
+	if obj, found := env.SymbolValue(sym); found {
+		ese.cache.Store(&globalCache{globals: env.globals, version: version})
 		return obj, nil
 	}
 	return nil, env.MakeNotBoundError(sym, frame)
 }
 
 // Print the expression on the given writer.
-func (ese envSymbolExpr) Print(w io.Writer) (int, error) {
+func (ese *envSymbolExpr) Print(w io.Writer) (int, error) {
 	return fmt.Fprintf(w, "{DYNAMIC %v}", ese.sym)
 }
 
@@ -251,8 +281,26 @@ type CallExpr struct {
 
 func (ce *CallExpr) String() string { return fmt.Sprintf("%v %v", ce.Proc, ce.Args) }
 
-// IsPure signals an expression that has no side effects.
-func (*CallExpr) IsPure() bool { return false }
+// IsPure signals an expression that has no side effects. This is only known,
+// if the procedure is a constant.
+func (ce *CallExpr) IsPure() bool {
+	objExpr, isObjExpr := ce.Proc.(ObjExpr)
+	if !isObjExpr {
+		return false
+	}
+	proc, isCallable := GetCallable(objExpr.Obj)
+	if !isCallable {
+		return false
+	}
+	args := make(sx.Vector, len(ce.Args))
+	for i, expr := range ce.Args {
+		if !expr.IsPure() {
+			return false
+		}
+		args[i] = sx.MakeUndefined()
+	}
+	return proc.IsPure(args)
+}
 
 // Unparse the expression back into a form object.
 func (ce *CallExpr) Unparse() sx.Object {
@@ -277,8 +325,19 @@ func (ce *CallExpr) Improve(imp *Improver) (Expr, error) {
 		// If call can be folded into a constant value, use that value.
 		if c, isCallable := GetCallable(objExpr.Obj); isCallable {
 			if foldExpr, foldErr := imp.ImproveFoldCall(c, ce.Args); foldErr == nil && foldExpr != nil {
+				ce.Proc = proc
+				imp.Optimized(OptFold, ce, foldExpr)
 				return foldExpr, nil
 			}
+
+			// If the call can be replaced by the body of the procedure, use the body.
+			if inliner, isInliner := c.(Inliner); isInliner {
+				if inlineExpr := inliner.Inline(imp, ce.Args); inlineExpr != nil {
+					ce.Proc = proc
+					imp.Optimized(OptInline, ce, inlineExpr)
+					return inlineExpr, nil
+				}
+			}
 		}
 
 		// If the ce.Proc is a builtin, improve to a BuiltinCallExpr.
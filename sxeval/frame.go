@@ -15,17 +15,55 @@ package sxeval
 
 import (
 	"fmt"
-	"maps"
+	"iter"
 
 	"t73f.de/r/sx"
 )
 
+// FrameLayout maps the symbols of a frame to slot indices. It is computed
+// once for a lambda or a let form and is shared by all frames that are
+// created when the form is computed.
+type FrameLayout struct {
+	syms  []*sx.Symbol
+	index map[*sx.Symbol]int
+}
+
+// MakeFrameLayout creates a new layout for the given symbols. Nil symbols
+// are ignored, a symbol given more than once gets only one slot.
+func MakeFrameLayout(syms ...*sx.Symbol) *FrameLayout {
+	fl := FrameLayout{index: make(map[*sx.Symbol]int, len(syms))}
+	for _, sym := range syms {
+		if sym == nil {
+			continue
+		}
+		if _, found := fl.index[sym]; !found {
+			fl.index[sym] = len(fl.syms)
+			fl.syms = append(fl.syms, sym)
+		}
+	}
+	return &fl
+}
+
+// Size returns the number of slots.
+func (fl *FrameLayout) Size() int { return len(fl.syms) }
+
+// Slot returns the slot index of the given symbol.
+func (fl *FrameLayout) Slot(sym *sx.Symbol) (int, bool) {
+	i, found := fl.index[sym]
+	return i, found
+}
+
 // Frame is a binding specific for a call. It implements the static environment
 // of a call, in constrast to `Binding`.
+//
+// A frame with a layout stores the values of the layout symbols in slots.
+// Other symbols are bound dynamically.
 type Frame struct {
-	mso    mapSymObj  // used if more than one symbol is bound
-	sym    *sx.Symbol // used if zero or one symbol is bound
-	obj    sx.Object  // object bound to sym
+	layout *FrameLayout
+	slots  []sx.Object // values of the layout symbols, nil if unbound
+	mso    mapSymObj   // used if more than one symbol is bound
+	sym    *sx.Symbol  // used if zero or one symbol is bound
+	obj    sx.Object   // object bound to sym
 	name   string
 	parent *Frame
 }
@@ -46,6 +84,32 @@ func (f *Frame) MakeChildFrame(name string, sizeHint int) *Frame {
 	return makeFrame(name, f, sizeHint)
 }
 
+// MakeSlotFrame creates a new frame with a given parent, where the symbols of
+// the layout are stored in slots. All slots are unbound.
+func (f *Frame) MakeSlotFrame(name string, layout *FrameLayout) *Frame {
+	return &Frame{
+		layout: layout,
+		slots:  make([]sx.Object, len(layout.syms)),
+		name:   name,
+		parent: f,
+	}
+}
+
+// SetSlot binds the symbol of the given slot to the object.
+func (f *Frame) SetSlot(slot int, obj sx.Object) {
+	if obj == nil {
+		obj = sx.Nil() // nil marks an unbound slot
+	}
+	f.slots[slot] = obj
+}
+
+// setSlots binds the symbols of the first slots to the given objects.
+func (f *Frame) setSlots(objs []sx.Object) {
+	for i, obj := range objs {
+		f.SetSlot(i, obj)
+	}
+}
+
 // IsNil returns true if the frame is the nil frame.
 func (f *Frame) IsNil() bool { return f == nil }
 
@@ -64,36 +128,15 @@ func (f *Frame) IsEqual(other sx.Object) bool {
 		return sx.IsNil(other)
 	}
 	if of, isFrame := other.(*Frame); isFrame {
-		fmso, ofmso := f.mso, of.mso
-		if fmso != nil && ofmso != nil {
-			return maps.EqualFunc(fmso, ofmso, func(o1, o2 sx.Object) bool { return o1.IsEqual(o2) })
-		}
-		fsym, ofsym := f.sym, of.sym
-		if fmso == nil && ofmso == nil {
-			if fsym == nil {
-				return ofsym == nil
-			}
-			if ofsym == nil {
-				return false
-			}
-			return f.obj.IsEqual(of.obj)
-		}
-
-		if fsym != nil {
-			if len(ofmso) != 1 {
-				return false
-			}
-			obj, found := ofmso[fsym]
-			return found && obj.IsEqual(f.obj)
+		if f.length() != of.length() {
+			return false
 		}
-		if ofsym != nil {
-			if len(fmso) != 1 {
+		for sym, obj := range f.all() {
+			if oobj, found := of.Lookup(sym); !found || !obj.IsEqual(oobj) {
 				return false
 			}
-			obj, found := fmso[ofsym]
-			return found && obj.IsEqual(of.obj)
 		}
-		return len(fmso) == 0 && len(ofmso) == 0
+		return true
 	}
 	return false
 }
@@ -122,19 +165,48 @@ func (f *Frame) Parent() *Frame {
 }
 
 func (f *Frame) length() int {
-	if m := f.mso; m != nil {
-		return len(m)
+	length := 0
+	for range f.all() {
+		length++
 	}
-	if f.sym == nil {
-		return 0
+	return length
+}
+
+// all iterates over all bindings of the frame, slots first.
+func (f *Frame) all() iter.Seq2[*sx.Symbol, sx.Object] {
+	return func(yield func(*sx.Symbol, sx.Object) bool) {
+		if f == nil {
+			return
+		}
+		if layout := f.layout; layout != nil {
+			for i, obj := range f.slots {
+				if obj != nil && !yield(layout.syms[i], obj) {
+					return
+				}
+			}
+		}
+		if m := f.mso; m != nil {
+			for sym, obj := range m {
+				if !yield(sym, obj) {
+					return
+				}
+			}
+		} else if f.sym != nil {
+			yield(f.sym, f.obj)
+		}
 	}
-	return 1
 }
 
 // Bind creates a local mapping with a given symbol and object.
 //
 // A previous mapping will be overwritten.
 func (f *Frame) Bind(sym *sx.Symbol, obj sx.Object) {
+	if layout := f.layout; layout != nil {
+		if i, found := layout.index[sym]; found {
+			f.SetSlot(i, obj)
+			return
+		}
+	}
 	if m := f.mso; m != nil {
 		m[sym] = obj
 	} else if f.sym == nil {
@@ -157,6 +229,14 @@ func (f *Frame) Lookup(sym *sx.Symbol) (sx.Object, bool) {
 	if sym == nil || f == nil {
 		return sx.Nil(), false
 	}
+	if layout := f.layout; layout != nil {
+		if i, found := layout.index[sym]; found {
+			if obj := f.slots[i]; obj != nil {
+				return obj, true
+			}
+			return sx.Nil(), false
+		}
+	}
 	if m := f.mso; m != nil {
 		obj, found := m[sym]
 		return obj, found
@@ -167,14 +247,42 @@ func (f *Frame) Lookup(sym *sx.Symbol) (sx.Object, bool) {
 	return sx.Nil(), false
 }
 
-// lookupN will lookup the symbol in the N-th parent.
-func (f *Frame) lookupN(sym *sx.Symbol, n int) (sx.Object, bool) {
+// lookupN will lookup the symbol in the N-th parent. If the slot is not
+// negative, it is tried first. If there is no N-th parent, the symbol is not
+// found.
+func (f *Frame) lookupN(sym *sx.Symbol, n, slot int) (sx.Object, bool) {
 	for range n {
+		if f == nil {
+			return sx.Nil(), false
+		}
 		f = f.parent
 	}
+	if f == nil {
+		return sx.Nil(), false
+	}
+	if slot >= 0 {
+		if layout := f.layout; layout != nil && slot < len(layout.syms) && layout.syms[slot] == sym {
+			if obj := f.slots[slot]; obj != nil {
+				return obj, true
+			}
+			return sx.Nil(), false
+		}
+	}
 	return f.Lookup(sym)
 }
 
+// slotOf returns the slot index of the symbol, or -1 if there is no slot.
+func (f *Frame) slotOf(sym *sx.Symbol) int {
+	if f != nil {
+		if layout := f.layout; layout != nil {
+			if i, found := layout.index[sym]; found {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
 // Resolve returns the object that is bound to a symbol. It searches in the
 // frame and in all parent frames.
 func (f *Frame) Resolve(sym *sx.Symbol) (sx.Object, bool) {
@@ -199,22 +307,15 @@ func (f *Frame) FindFrame(sym *sx.Symbol) *Frame {
 	return nil
 }
 
-// Bindings returns all bindings as an a-list in some random order.
+// Bindings returns all bindings as an a-list. Bindings stored in slots come
+// first, in the order of the layout, the other ones follow in some random
+// order.
 func (f *Frame) Bindings() *sx.Pair {
-	if f == nil {
-		return sx.Nil()
+	var result sx.ListBuilder
+	for sym, obj := range f.all() {
+		result.Add(sx.Cons(sym, obj))
 	}
-	if m := f.mso; m != nil {
-		var result sx.ListBuilder
-		for sym, obj := range m {
-			result.Add(sx.Cons(sym, obj))
-		}
-		return result.List()
-	}
-	if bsym := f.sym; bsym != nil {
-		return sx.Cons(sx.Cons(bsym, f.obj), sx.Nil())
-	}
-	return nil
+	return result.List()
 }
 
 // GetFrame returns the object as a frame, if possible.
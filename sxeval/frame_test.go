@@ -142,3 +142,53 @@ func checkFrameEqual(t *testing.T, frame1, frame2 *sxeval.Frame) {
 		t.Error("equal bindings differ")
 	}
 }
+
+func TestSlotFrame(t *testing.T) {
+	t.Parallel()
+	sym1, sym2, sym3 := sx.MakeSymbol("sym1"), sx.MakeSymbol("sym2"), sx.MakeSymbol("sym3")
+	layout := sxeval.MakeFrameLayout(sym1, sym2, sym1, nil)
+	if got := layout.Size(); got != 2 {
+		t.Errorf("layout must have 2 slots, but got %d", got)
+	}
+	if slot, found := layout.Slot(sym2); !found || slot != 1 {
+		t.Errorf("sym2 must be in slot 1, but got %d/%v", slot, found)
+	}
+	if _, found := layout.Slot(sym3); found {
+		t.Error("sym3 must not have a slot")
+	}
+
+	frame := makeRootFrame(0).MakeSlotFrame("slot", layout)
+	if val, found := frame.Lookup(sym1); found {
+		t.Errorf("unbound slot must not be found, but got: %v", val)
+	}
+	if got := frame.Bindings(); got != nil {
+		t.Errorf("frame without bindings expected, but got: %v", got)
+	}
+	frame.SetSlot(0, nil)
+	if val, found := frame.Lookup(sym1); !found || !sx.IsNil(val) {
+		t.Errorf("slot bound to nil must be found, but got: %v/%v", val, found)
+	}
+	frame.SetSlot(1, sx.Int64(2))
+	frame.Bind(sym1, sx.Int64(1))
+	frame.Bind(sym3, sx.Int64(3))
+	for i, sym := range []*sx.Symbol{sym1, sym2, sym3} {
+		if val, found := frame.Lookup(sym); !found || !val.IsEqual(sx.Int64(i+1)) {
+			t.Errorf("%v must be bound to %d, but got %v/%v", sym, i+1, val, found)
+		}
+	}
+	if got, exp := frame.Bindings().String(), "((sym1 . 1) (sym2 . 2) (sym3 . 3))"; got != exp {
+		t.Errorf("bindings %v expected, but got %v", exp, got)
+	}
+
+	other := makeRootFrame(3)
+	other.Bind(sym3, sx.Int64(3))
+	other.Bind(sym2, sx.Int64(2))
+	other.Bind(sym1, sx.Int64(1))
+	if !frame.IsEqual(other) || !other.IsEqual(frame) {
+		t.Error("slot frame", frame, "must be equal to", other)
+	}
+	other.Bind(sym2, sx.Int64(4))
+	if frame.IsEqual(other) || other.IsEqual(frame) {
+		t.Error("slot frame", frame, "must not be equal to", other)
+	}
+}
@@ -173,3 +173,39 @@ type ErrStepsLimit struct{ steps int }
 func (e ErrStepsLimit) Error() string {
 	return fmt.Sprintf("allowed compute steps exceeded: %d", e.steps)
 }
+
+// ----- MemoryLimitHandler -------------------------------------------------
+
+// MemoryLimitHandler is an AllocHandler that limits the number of bytes
+// allocated by builtins.
+type MemoryLimitHandler struct {
+	currSize  int
+	limitSize int
+}
+
+// MakeMemoryLimitHandler builds a new MemoryLimitHandler.
+func MakeMemoryLimitHandler(limitSize int) *MemoryLimitHandler {
+	return &MemoryLimitHandler{limitSize: limitSize}
+}
+
+// Alloc checks whether the given number of bytes can be allocated.
+func (h *MemoryLimitHandler) Alloc(size int) error {
+	if size > h.limitSize-h.currSize {
+		return ErrMemoryLimit{h.limitSize}
+	}
+	h.currSize += size
+	return nil
+}
+
+// Reset the handler.
+func (h *MemoryLimitHandler) Reset() { h.currSize = 0 }
+
+// Allocated returns the number of bytes allocated so far.
+func (h *MemoryLimitHandler) Allocated() int { return h.currSize }
+
+// ErrMemoryLimit is an error to signal that the allowed memory is exceeded.
+type ErrMemoryLimit struct{ size int }
+
+func (e ErrMemoryLimit) Error() string {
+	return fmt.Sprintf("allowed memory exceeded: %d bytes", e.size)
+}
@@ -0,0 +1,1377 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxreader"
+)
+
+// An image stores all bindings of a frozen root binding, including the
+// improved expressions of procedures and the frames they refer to. It can be
+// restored by another process without reading, parsing, and improving the
+// forms that created the bindings.
+//
+// An image is an s-expression. Each encoded object is an integer, a string,
+// the empty list, or a list whose first element is a keyword that specifies
+// how the other elements must be decoded. Builtins and special forms are
+// stored by their name. Symbols, packages, frames, and all objects that
+// implement Encodable are stored only once, and are referenced by their
+// index. Therefore, they keep their identity.
+//
+// Symbol values are not stored in an image. Therefore, an isolated root
+// binding can only be stored, if no symbol value was set within it.
+//
+// Packages that are not present when an image is read are created, and their
+// external symbols are exported. Packages that are already present keep their
+// external symbols, only the symbols of the image are interned there.
+
+// imageVersion is the version of the image format. It must be incremented,
+// if the encoding of the predefined objects is changed.
+const imageVersion = 1
+
+// imageNestingLimit is the maximum nesting of lists, when an image is read.
+const imageNestingLimit = 1 << 16
+
+// ErrImageIncompatible is returned, if an image was written with another
+// version of the image format, or with a registry of another fingerprint.
+var ErrImageIncompatible = errors.New("incompatible image")
+
+// Encodable is an expression or an object that can be stored in an image.
+type Encodable interface {
+	// Encode returns the tag of the decoder, together with the encoded
+	// data. The data is given to the decoder as its arguments.
+	Encode(*ImageEncoder) (string, sx.Vector, error)
+}
+
+// ExprDecoder restores an expression from the arguments of its encoded data.
+type ExprDecoder func(*ImageDecoder, sx.Vector) (Expr, error)
+
+// ObjectDecoder restores an object from the arguments of its encoded data.
+type ObjectDecoder func(*ImageDecoder, sx.Vector) (sx.Object, error)
+
+// ImageRegistry contains all builtins, special forms, and decoders that are
+// needed to write and to read an image. An image can only be read with a
+// registry that has the same fingerprint as the one that wrote it.
+type ImageRegistry struct {
+	world    *sx.World
+	builtins map[string]*Builtin
+	specials map[string]*Special
+	exprs    map[string]exprDecoder
+	objects  map[string]objectDecoder
+}
+
+type exprDecoder struct {
+	numArgs int
+	fn      ExprDecoder
+}
+
+type objectDecoder struct {
+	numArgs int
+	fn      ObjectDecoder
+}
+
+// MakeImageRegistry creates a registry of all builtins and special forms
+// that are bound in the given binding or in one of its parents, together with
+// the decoders of the expressions of this package.
+func MakeImageRegistry(bind *Binding) *ImageRegistry {
+	reg := &ImageRegistry{
+		world:    sx.DefaultWorld(),
+		builtins: map[string]*Builtin{},
+		specials: map[string]*Special{},
+		exprs:    map[string]exprDecoder{},
+		objects:  map[string]objectDecoder{},
+	}
+	for curr := bind; curr != nil; curr = curr.parent {
+		for _, obj := range curr.mso {
+			switch o := obj.(type) {
+			case *Builtin:
+				if _, found := reg.builtins[o.Name]; !found {
+					reg.builtins[o.Name] = o
+				}
+			case *Special:
+				if _, found := reg.specials[o.Name]; !found {
+					reg.specials[o.Name] = o
+				}
+			}
+		}
+	}
+
+	reg.AddExprDecoder(tagNil, 0, func(*ImageDecoder, sx.Vector) (Expr, error) { return NilExpr, nil })
+	reg.AddExprDecoder(tagObj, 1, decodeObjExpr)
+	reg.AddExprDecoder(tagUnbound, 1, decodeUnboundSymbolExpr)
+	reg.AddExprDecoder(tagLookup, 3, decodeFrameSymbolExpr)
+	reg.AddExprDecoder(tagGlobal, 1, decodeEnvSymbolExpr)
+	reg.AddExprDecoder(tagCall, 2, decodeCallExpr)
+	reg.AddExprDecoder(tagBCall, 2, decodeBuiltinCallExpr)
+	reg.AddExprDecoder(tagBCall0, 1, decodeBuiltinCall0Expr)
+	reg.AddExprDecoder(tagBCall1, 2, decodeBuiltinCall1Expr)
+	reg.AddExprDecoder(tagBCall2, 3, decodeBuiltinCall2Expr)
+	reg.AddExprDecoder(tagBCall3, 4, decodeBuiltinCall3Expr)
+	reg.AddExprDecoder(tagCompiled, 1, decodeCompiledExpr)
+	return reg
+}
+
+// SetWorld sets the world of packages, where symbols and packages are
+// restored. By default, the default world is used.
+func (reg *ImageRegistry) SetWorld(world *sx.World) *ImageRegistry {
+	reg.world = world
+	return reg
+}
+
+// AddExprDecoder adds a decoder for expressions with the given tag, which
+// need the given number of arguments. If the expression computes some of its
+// parts in a new frame, the decoder must restore them by DecodeChildExpr,
+// so that the levels of frame lookups can be checked.
+func (reg *ImageRegistry) AddExprDecoder(tag string, numArgs int, fn ExprDecoder) {
+	reg.exprs[tag] = exprDecoder{numArgs: numArgs, fn: fn}
+}
+
+// AddObjectDecoder adds a decoder for objects with the given tag, which need
+// the given number of arguments.
+func (reg *ImageRegistry) AddObjectDecoder(tag string, numArgs int, fn ObjectDecoder) {
+	reg.objects[tag] = objectDecoder{numArgs: numArgs, fn: fn}
+}
+
+// Fingerprint returns a hash value of the builtins, the special forms, and
+// the decoders of the registry.
+func (reg *ImageRegistry) Fingerprint() string {
+	lines := make([]string, 0, len(reg.builtins)+len(reg.specials)+len(reg.exprs)+len(reg.objects))
+	for name, b := range reg.builtins {
+		lines = append(lines, fmt.Sprintf("builtin %q %d %d %t %t", name, b.MinArity, b.MaxArity, b.Fn2 != nil, b.Fn3 != nil))
+	}
+	for name := range reg.specials {
+		lines = append(lines, fmt.Sprintf("special %q", name))
+	}
+	for tag, d := range reg.exprs {
+		lines = append(lines, fmt.Sprintf("expr %q %d", tag, d.numArgs))
+	}
+	for tag, d := range reg.objects {
+		lines = append(lines, fmt.Sprintf("object %q %d", tag, d.numArgs))
+	}
+	slices.Sort(lines)
+	h := sha256.New()
+	for _, line := range lines {
+		_, _ = io.WriteString(h, line)
+		_, _ = io.WriteString(h, "\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Keywords of the predefined encodings.
+var (
+	kwImage       = sx.KeywordPackage().MakeSymbol("sx-image")
+	kwRef         = sx.KeywordPackage().MakeSymbol("ref")
+	kwList        = sx.KeywordPackage().MakeSymbol("list")
+	kwDotted      = sx.KeywordPackage().MakeSymbol("dotted")
+	kwVector      = sx.KeywordPackage().MakeSymbol("vector")
+	kwUndefined   = sx.KeywordPackage().MakeSymbol("undefined")
+	kwBuiltin     = sx.KeywordPackage().MakeSymbol("builtin")
+	kwSpecial     = sx.KeywordPackage().MakeSymbol("special")
+	kwExprObj     = sx.KeywordPackage().MakeSymbol("expr")
+	kwSymbol      = sx.KeywordPackage().MakeSymbol("symbol")
+	kwPackage     = sx.KeywordPackage().MakeSymbol("package")
+	kwFrame       = sx.KeywordPackage().MakeSymbol("frame")
+	kwUnboundSlot = sx.KeywordPackage().MakeSymbol("unbound-slot")
+	kwLayout      = sx.KeywordPackage().MakeSymbol("layout")
+)
+
+// ----- Writing an image
+
+// WriteImage writes all bindings of the given frozen root binding as an
+// image. All builtins and special forms that are referenced must be
+// registered, and all decoders that are needed to read the image.
+func WriteImage(w io.Writer, bind *Binding, reg *ImageRegistry) error {
+	if bind.parent != nil || !bind.frozen {
+		return fmt.Errorf("only a frozen root binding can be stored in an image, but got %v", bind)
+	}
+	if st := bind.symbolTable(); st != nil && st.size() > 0 {
+		return fmt.Errorf("symbol values of an isolated binding cannot be stored in an image: %v", bind)
+	}
+	enc := ImageEncoder{reg: reg, refs: map[sx.Object]int{}}
+	syms := slices.SortedFunc(maps.Keys(bind.mso), compareSymbols)
+	bindings := make(sx.Vector, 0, 2*len(syms))
+	for _, sym := range syms {
+		esym, err := enc.EncodeObject(sym)
+		if err != nil {
+			return err
+		}
+		val, err := enc.EncodeObject(bind.mso[sym])
+		if err != nil {
+			return fmt.Errorf("%v: %w", sym, err)
+		}
+		bindings = append(bindings, esym, val)
+	}
+
+	isolated := 0
+	if bind.IsIsolated() {
+		isolated = 1
+	}
+	header := sx.Vector{kwImage, sx.Int64(imageVersion), sx.MakeString(reg.Fingerprint()), sx.MakeString(bind.name), sx.Int64(isolated)}
+	iw := imageWriter{w: bufio.NewWriter(w)}
+	iw.writeString("(")
+	for i, obj := range header {
+		if i > 0 {
+			iw.writeString(" ")
+		}
+		iw.print(obj)
+	}
+	iw.writeList(enc.defs, 1)
+	iw.writeList(bindings, 2)
+	iw.writeString(")\n")
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// compareSymbols orders symbols by the name of their package and by their
+// name, to write deterministic images.
+func compareSymbols(sym1, sym2 *sx.Symbol) int {
+	var pkg1, pkg2 string
+	if pkg := sym1.Package(); pkg != nil {
+		pkg1 = pkg.Name()
+	}
+	if pkg := sym2.Package(); pkg != nil {
+		pkg2 = pkg.Name()
+	}
+	return cmp.Or(cmp.Compare(pkg1, pkg2), cmp.Compare(sym1.GetValue(), sym2.GetValue()))
+}
+
+// imageWriter writes an image, where the elements of the large lists are
+// written on separate lines. The first error stops all writing.
+type imageWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (iw *imageWriter) writeString(s string) {
+	if iw.err == nil {
+		_, iw.err = iw.w.WriteString(s)
+	}
+}
+
+func (iw *imageWriter) print(obj sx.Object) {
+	if iw.err == nil {
+		_, iw.err = sx.Print(iw.w, obj)
+	}
+}
+
+// writeList writes the elements as a list, with the given number of elements
+// on each line.
+func (iw *imageWriter) writeList(elems sx.Vector, perLine int) {
+	iw.writeString("\n (")
+	for i, obj := range elems {
+		if i > 0 {
+			if i%perLine == 0 {
+				iw.writeString("\n  ")
+			} else {
+				iw.writeString(" ")
+			}
+		}
+		iw.print(obj)
+	}
+	iw.writeString(")")
+}
+
+// ImageEncoder encodes objects and expressions to be stored in an image.
+type ImageEncoder struct {
+	reg  *ImageRegistry
+	refs map[sx.Object]int // index of already encoded objects in defs
+	defs sx.Vector
+}
+
+// EncodeObject encodes the given object.
+func (enc *ImageEncoder) EncodeObject(obj sx.Object) (sx.Object, error) {
+	if v, isVector := obj.(sx.Vector); isVector {
+		elems, err := enc.encodeObjects(v)
+		return sx.MakeList(elems...).Cons(kwVector), err
+	}
+	if sx.IsNil(obj) {
+		return sx.Nil(), nil
+	}
+	switch o := obj.(type) {
+	case sx.Int64, sx.String:
+		return o, nil
+	case sx.Undefined:
+		return sx.MakeList(kwUndefined), nil
+	case *sx.Pair:
+		return enc.encodeList(o)
+	case *Builtin:
+		if enc.reg.builtins[o.Name] != o {
+			return nil, fmt.Errorf("builtin %v is not registered", o)
+		}
+		return sx.MakeList(kwBuiltin, sx.MakeString(o.Name)), nil
+	case *Special:
+		if enc.reg.specials[o.Name] != o {
+			return nil, fmt.Errorf("special form %v is not registered", o)
+		}
+		return sx.MakeList(kwSpecial, sx.MakeString(o.Name)), nil
+	case *ExprObj:
+		expr, err := enc.EncodeExpr(o.expr)
+		return sx.MakeList(kwExprObj, expr), err
+	case *sx.Symbol, *sx.Package, *Frame, Encodable:
+		return enc.encodeRef(obj)
+	}
+	return nil, fmt.Errorf("cannot store %T/%v in an image", obj, obj)
+}
+
+func (enc *ImageEncoder) encodeObjects(objs []sx.Object) (sx.Vector, error) {
+	result := make(sx.Vector, len(objs))
+	for i, obj := range objs {
+		elem, err := enc.EncodeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = elem
+	}
+	return result, nil
+}
+
+func (enc *ImageEncoder) encodeList(lst *sx.Pair) (sx.Object, error) {
+	var lb sx.ListBuilder
+	for node := lst; ; {
+		elem, err := enc.EncodeObject(node.Car())
+		if err != nil {
+			return nil, err
+		}
+		lb.Add(elem)
+		cdr := node.Cdr()
+		if sx.IsNil(cdr) {
+			return lb.List().Cons(kwList), nil
+		}
+		next, isPair := sx.GetPair(cdr)
+		if !isPair {
+			tail, err2 := enc.EncodeObject(cdr)
+			if err2 != nil {
+				return nil, err2
+			}
+			lb.Add(tail)
+			return lb.List().Cons(kwDotted), nil
+		}
+		node = next
+	}
+}
+
+// encodeRef encodes an object, which is stored only once, and returns a
+// reference to it.
+func (enc *ImageEncoder) encodeRef(obj sx.Object) (sx.Object, error) {
+	ref, found := enc.refs[obj]
+	if !found {
+		ref = len(enc.defs)
+		enc.refs[obj] = ref
+		enc.defs = append(enc.defs, nil)
+		def, err := enc.encodeDef(obj)
+		if err != nil {
+			return nil, err
+		}
+		enc.defs[ref] = def
+	}
+	return sx.MakeList(kwRef, sx.Int64(ref)), nil
+}
+
+func (enc *ImageEncoder) encodeDef(obj sx.Object) (sx.Object, error) {
+	switch o := obj.(type) {
+	case *sx.Symbol:
+		pkg, err := enc.EncodeObject(o.Package())
+		if err != nil {
+			return nil, err
+		}
+		external := 0
+		if p := o.Package(); p != nil && p.IsExternal(o) {
+			external = 1
+		}
+		return sx.MakeList(kwSymbol, pkg, sx.MakeString(o.GetValue()), sx.Int64(external)), nil
+	case *sx.Package:
+		uses := o.Uses()
+		encUses := make(sx.Vector, len(uses))
+		for i, used := range uses {
+			encUsed, err := enc.EncodeObject(used)
+			if err != nil {
+				return nil, err
+			}
+			encUses[i] = encUsed
+		}
+		return sx.MakeList(kwPackage, sx.MakeString(o.Name()), sx.MakeList(encUses...)), nil
+	case *Frame:
+		return enc.encodeFrame(o)
+	}
+
+	tag, args, err := obj.(Encodable).Encode(enc)
+	if err != nil {
+		return nil, err
+	}
+	if d, found := enc.reg.objects[tag]; !found || d.numArgs != len(args) {
+		return nil, fmt.Errorf("no decoder for object %q with %d arguments", tag, len(args))
+	}
+	return sx.MakeList(args...).Cons(sx.KeywordPackage().MakeSymbol(tag)), nil
+}
+
+func (enc *ImageEncoder) encodeFrame(f *Frame) (sx.Object, error) {
+	parent, err := enc.EncodeObject(f.parent)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := enc.EncodeLayout(f.layout)
+	if err != nil {
+		return nil, err
+	}
+	slots := make(sx.Vector, len(f.slots))
+	for i, obj := range f.slots {
+		if obj == nil {
+			slots[i] = sx.MakeList(kwUnboundSlot)
+		} else if slots[i], err = enc.EncodeObject(obj); err != nil {
+			return nil, err
+		}
+	}
+
+	var dyn sx.Vector
+	if f.mso != nil {
+		for _, sym := range slices.SortedFunc(maps.Keys(f.mso), compareSymbols) {
+			if dyn, err = enc.appendBinding(dyn, sym, f.mso[sym]); err != nil {
+				return nil, err
+			}
+		}
+	} else if f.sym != nil {
+		if dyn, err = enc.appendBinding(dyn, f.sym, f.obj); err != nil {
+			return nil, err
+		}
+	}
+	return sx.MakeList(kwFrame, sx.MakeString(f.name), parent, layout, sx.MakeList(slots...), sx.MakeList(dyn...)), nil
+}
+
+func (enc *ImageEncoder) appendBinding(objs sx.Vector, sym *sx.Symbol, obj sx.Object) (sx.Vector, error) {
+	esym, err := enc.EncodeObject(sym)
+	if err != nil {
+		return nil, err
+	}
+	val, err := enc.EncodeObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	return append(objs, esym, val), nil
+}
+
+// EncodeExpr encodes the given expression. A nil expression is encoded as
+// the empty list.
+func (enc *ImageEncoder) EncodeExpr(expr Expr) (sx.Object, error) {
+	if expr == nil {
+		return sx.Nil(), nil
+	}
+	e, isEncodable := expr.(Encodable)
+	if !isEncodable {
+		return nil, fmt.Errorf("cannot store expression %T in an image", expr)
+	}
+	tag, args, err := e.Encode(enc)
+	if err != nil {
+		return nil, err
+	}
+	if d, found := enc.reg.exprs[tag]; !found || d.numArgs != len(args) {
+		return nil, fmt.Errorf("no decoder for expression %q with %d arguments", tag, len(args))
+	}
+	return sx.MakeList(args...).Cons(sx.KeywordPackage().MakeSymbol(tag)), nil
+}
+
+// EncodeExprs encodes all given expressions.
+func (enc *ImageEncoder) EncodeExprs(exprs ...Expr) (sx.Vector, error) {
+	result := make(sx.Vector, len(exprs))
+	for i, expr := range exprs {
+		eexpr, err := enc.EncodeExpr(expr)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = eexpr
+	}
+	return result, nil
+}
+
+// EncodeExprList encodes all given expressions as a list.
+func (enc *ImageEncoder) EncodeExprList(exprs []Expr) (sx.Object, error) {
+	eexprs, err := enc.EncodeExprs(exprs...)
+	return sx.MakeList(eexprs...), err
+}
+
+// EncodeSymbols encodes all given symbols. A nil symbol is encoded as the
+// empty list.
+func (enc *ImageEncoder) EncodeSymbols(syms ...*sx.Symbol) (sx.Vector, error) {
+	result := make(sx.Vector, len(syms))
+	for i, sym := range syms {
+		esym, err := enc.EncodeObject(sym)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = esym
+	}
+	return result, nil
+}
+
+// EncodeLayout encodes the given frame layout. A nil layout is encoded as
+// the empty list.
+func (enc *ImageEncoder) EncodeLayout(fl *FrameLayout) (sx.Object, error) {
+	if fl == nil {
+		return sx.Nil(), nil
+	}
+	syms, err := enc.EncodeSymbols(fl.syms...)
+	return sx.MakeList(syms...).Cons(kwLayout), err
+}
+
+// ----- Reading an image
+
+// ReadImage reads an image and restores its bindings into a new root binding,
+// which is frozen. The registry must have the same fingerprint as the one
+// that wrote the image.
+func ReadImage(r io.Reader, reg *ImageRegistry) (*Binding, error) {
+	rd := sxreader.MakeReader(r).SetWorld(reg.world).SetNestingLimit(imageNestingLimit).SetListLimit(0)
+	obj, err := rd.Read()
+	if err != nil {
+		return nil, err
+	}
+	img, isPair := sx.GetPair(obj)
+	if !isPair || img == nil || img.Car() != kwImage {
+		return nil, fmt.Errorf("not an image: %v", obj)
+	}
+	if version := img.Tail().Car(); !sx.Int64(imageVersion).IsEqual(version) {
+		return nil, fmt.Errorf("%w: version %v, but expected %d", ErrImageIncompatible, version, imageVersion)
+	}
+	args, err := imageArgs(img.Tail(), 6)
+	if err != nil {
+		return nil, err
+	}
+	if fp := reg.Fingerprint(); !sx.MakeString(fp).IsEqual(args[1]) {
+		return nil, fmt.Errorf("%w: fingerprint %v, but expected %q", ErrImageIncompatible, args[1], fp)
+	}
+
+	dec := ImageDecoder{reg: reg, curr: -1, frames: -1}
+	name, err := dec.DecodeString(args[2])
+	if err != nil {
+		return nil, err
+	}
+	isolated, err := dec.DecodeInt(args[3])
+	if err != nil {
+		return nil, err
+	}
+	if dec.defs, err = dec.Elems(args[4]); err != nil {
+		return nil, err
+	}
+	dec.objs = make([]sx.Object, len(dec.defs))
+	dec.busy = make([]bool, len(dec.defs))
+	bindings, err := dec.Elems(args[5])
+	if err != nil {
+		return nil, err
+	}
+	if len(bindings)%2 != 0 {
+		return nil, errImageData(args[5])
+	}
+
+	var bind *Binding
+	if isolated != 0 {
+		bind = MakeIsolatedRootBinding(len(bindings) / 2)
+	} else {
+		bind = MakeRootBinding(len(bindings) / 2)
+	}
+	bind.name = name
+	dec.env = MakeEnvironment(bind).SetWorld(reg.world)
+	for i := 0; i < len(bindings); i += 2 {
+		sym, err2 := dec.DecodeSymbol(bindings[i])
+		if err2 != nil {
+			return nil, err2
+		}
+		val, err2 := dec.DecodeObject(bindings[i+1])
+		if err2 != nil {
+			return nil, fmt.Errorf("%v: %w", sym, err2)
+		}
+		if err2 = bind.Bind(sym, val); err2 != nil {
+			return nil, err2
+		}
+	}
+	bind.Freeze()
+	return bind, nil
+}
+
+// errImageData returns an error about invalid data of an image.
+func errImageData(obj sx.Object) error { return fmt.Errorf("invalid image data: %v", obj) }
+
+// imageArgs returns the elements of the given list, which must have the given
+// number of elements.
+func imageArgs(lst *sx.Pair, numArgs int) (sx.Vector, error) {
+	args := make(sx.Vector, 0, numArgs)
+	for node := lst; node != nil; node = node.Tail() {
+		args = append(args, node.Car())
+	}
+	if len(args) != numArgs || (lst != nil && !sx.IsNil(lst.LastPair().Cdr())) {
+		return nil, errImageData(lst)
+	}
+	return args, nil
+}
+
+// ImageDecoder restores objects and expressions that are stored in an image.
+type ImageDecoder struct {
+	reg    *ImageRegistry
+	env    *Environment
+	defs   sx.Vector                // encoded objects that are referenced by index
+	objs   []sx.Object              // decoded objects, nil if not decoded yet
+	busy   []bool                   // objects that are decoded currently
+	curr   int                      // index of the object decoded currently, or -1
+	pkgs   map[*sx.Package]struct{} // packages created by the decoder
+	frames int                      // number of frames around the decoded expression, or -1 if unknown
+}
+
+// Environment returns an environment of the restored binding, e.g. to be
+// used by macros.
+func (dec *ImageDecoder) Environment() *Environment { return dec.env }
+
+// Define sets the object that is decoded currently by an ObjectDecoder. If
+// the data of the object refers to the object itself, e.g. via a frame, the
+// decoder must call Define before it decodes that data.
+func (dec *ImageDecoder) Define(obj sx.Object) {
+	if curr := dec.curr; curr >= 0 {
+		dec.objs[curr] = obj
+	}
+}
+
+// Elems returns the elements of the given encoded list.
+func (dec *ImageDecoder) Elems(obj sx.Object) (sx.Vector, error) {
+	lst, isPair := sx.GetPair(obj)
+	if !isPair {
+		return nil, errImageData(obj)
+	}
+	var result sx.Vector
+	for node := lst; node != nil; {
+		result = append(result, node.Car())
+		cdr := node.Cdr()
+		if sx.IsNil(cdr) {
+			break
+		}
+		next, isNextPair := sx.GetPair(cdr)
+		if !isNextPair {
+			return nil, errImageData(obj)
+		}
+		node = next
+	}
+	return result, nil
+}
+
+// DecodeInt decodes an integer.
+func (dec *ImageDecoder) DecodeInt(obj sx.Object) (int, error) {
+	if i, isInt := obj.(sx.Int64); isInt {
+		return int(i), nil
+	}
+	return 0, errImageData(obj)
+}
+
+// DecodeString decodes a string.
+func (dec *ImageDecoder) DecodeString(obj sx.Object) (string, error) {
+	if s, isString := sx.GetString(obj); isString {
+		return s.GetValue(), nil
+	}
+	return "", errImageData(obj)
+}
+
+// DecodeObject restores an object.
+func (dec *ImageDecoder) DecodeObject(obj sx.Object) (sx.Object, error) {
+	switch o := obj.(type) {
+	case sx.Int64, sx.String:
+		return o, nil
+	case *sx.Pair:
+		if o == nil {
+			return sx.Nil(), nil
+		}
+		args := o.Tail()
+		switch o.Car() {
+		case kwRef:
+			return dec.decodeRef(args)
+		case kwList, kwDotted:
+			return dec.decodeList(args, o.Car() == kwDotted)
+		case kwVector:
+			elems, err := dec.Elems(args)
+			if err != nil {
+				return nil, err
+			}
+			return dec.decodeObjects(elems)
+		case kwUndefined:
+			return sx.MakeUndefined(), nil
+		case kwBuiltin:
+			return dec.decodeNamed(args, func(name string) (sx.Object, bool) {
+				b, found := dec.reg.builtins[name]
+				return b, found
+			})
+		case kwSpecial:
+			return dec.decodeNamed(args, func(name string) (sx.Object, bool) {
+				sp, found := dec.reg.specials[name]
+				return sp, found
+			})
+		case kwExprObj:
+			vals, err := imageArgs(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			// An expression object may be computed anywhere.
+			expr, err := dec.decodeExprFrames(vals[0], -1)
+			if err != nil {
+				return nil, err
+			}
+			return MakeExprObj(expr), nil
+		}
+	}
+	return nil, errImageData(obj)
+}
+
+func (dec *ImageDecoder) decodeObjects(objs sx.Vector) (sx.Vector, error) {
+	result := make(sx.Vector, len(objs))
+	for i, obj := range objs {
+		elem, err := dec.DecodeObject(obj)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = elem
+	}
+	return result, nil
+}
+
+func (dec *ImageDecoder) decodeList(args *sx.Pair, dotted bool) (sx.Object, error) {
+	elems, err := dec.Elems(args)
+	if err != nil {
+		return nil, err
+	}
+	objs, err := dec.decodeObjects(elems)
+	if err != nil {
+		return nil, err
+	}
+	if !dotted {
+		return sx.MakeList(objs...), nil
+	}
+	if len(objs) < 2 {
+		return nil, errImageData(args)
+	}
+	result := objs[len(objs)-1]
+	for i := len(objs) - 2; i >= 0; i-- {
+		result = sx.Cons(objs[i], result)
+	}
+	return result, nil
+}
+
+func (dec *ImageDecoder) decodeNamed(args *sx.Pair, lookup func(string) (sx.Object, bool)) (sx.Object, error) {
+	vals, err := imageArgs(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	name, err := dec.DecodeString(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	if obj, found := lookup(name); found {
+		return obj, nil
+	}
+	return nil, fmt.Errorf("%q is not registered", name)
+}
+
+// decodeRef restores a referenced object. It is decoded only once.
+func (dec *ImageDecoder) decodeRef(args *sx.Pair) (sx.Object, error) {
+	vals, err := imageArgs(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := dec.DecodeInt(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	if ref < 0 || len(dec.defs) <= ref {
+		return nil, fmt.Errorf("invalid image reference %d", ref)
+	}
+	if obj := dec.objs[ref]; obj != nil {
+		return obj, nil
+	}
+	if dec.busy[ref] {
+		return nil, fmt.Errorf("image reference %d refers to itself", ref)
+	}
+	dec.busy[ref] = true
+	saved := dec.curr
+	dec.curr = ref
+	obj, err := dec.decodeDef(dec.defs[ref])
+	dec.curr = saved
+	dec.busy[ref] = false
+	if err != nil {
+		return nil, err
+	}
+	if dec.objs[ref] == nil {
+		dec.objs[ref] = obj
+	}
+	return dec.objs[ref], nil
+}
+
+func (dec *ImageDecoder) decodeDef(obj sx.Object) (sx.Object, error) {
+	def, isPair := sx.GetPair(obj)
+	if !isPair || def == nil {
+		return nil, errImageData(obj)
+	}
+	tag, isSymbol := sx.GetSymbol(def.Car())
+	if !isSymbol || !tag.IsKeyword() {
+		return nil, errImageData(obj)
+	}
+	switch tag {
+	case kwSymbol:
+		return dec.decodeSymbolDef(def.Tail())
+	case kwPackage:
+		return dec.decodePackageDef(def.Tail())
+	case kwFrame:
+		return dec.decodeFrameDef(def.Tail())
+	}
+	d, found := dec.reg.objects[tag.GetValue()]
+	if !found {
+		return nil, fmt.Errorf("no decoder for object %q", tag.GetValue())
+	}
+	args, err := imageArgs(def.Tail(), d.numArgs)
+	if err != nil {
+		return nil, err
+	}
+	return d.fn(dec, args)
+}
+
+func (dec *ImageDecoder) decodeSymbolDef(args *sx.Pair) (sx.Object, error) {
+	vals, err := imageArgs(args, 3)
+	if err != nil {
+		return nil, err
+	}
+	name, err := dec.DecodeString(vals[1])
+	if err != nil {
+		return nil, err
+	}
+	external, err := dec.DecodeInt(vals[2])
+	if err != nil {
+		return nil, err
+	}
+	if sx.IsNil(vals[0]) {
+		return sx.MakeUninternedSymbol(name), nil
+	}
+	obj, err := dec.DecodeObject(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	pkg, isPackage := sx.GetPackage(obj)
+	if !isPackage {
+		return nil, errImageData(vals[0])
+	}
+	sym := pkg.MakeSymbol(name)
+	if sym == nil {
+		return nil, errImageData(args)
+	}
+	if _, created := dec.pkgs[pkg]; created && external != 0 && !pkg.IsExternal(sym) {
+		if err = pkg.Export(sym); err != nil {
+			return nil, err
+		}
+	}
+	return sym, nil
+}
+
+// decodePackageDef restores a package. If it is not present in the world, it
+// is created, and it uses the packages it used when the image was written.
+// Only symbols of created packages are exported by decodeSymbolDef.
+func (dec *ImageDecoder) decodePackageDef(args *sx.Pair) (sx.Object, error) {
+	vals, err := imageArgs(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	name, err := dec.DecodeString(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	if pkg := dec.reg.world.FindPackage(name); pkg != nil {
+		return pkg, nil
+	}
+	pkg, err := dec.reg.world.MakePackage(name)
+	if err != nil {
+		return nil, err
+	}
+	dec.Define(pkg)
+	if dec.pkgs == nil {
+		dec.pkgs = map[*sx.Package]struct{}{}
+	}
+	dec.pkgs[pkg] = struct{}{}
+	elems, err := dec.Elems(vals[1])
+	if err != nil {
+		return nil, err
+	}
+	uses := make([]*sx.Package, len(elems))
+	for i, elem := range elems {
+		obj, err2 := dec.DecodeObject(elem)
+		if err2 != nil {
+			return nil, err2
+		}
+		used, isPackage := sx.GetPackage(obj)
+		if !isPackage {
+			return nil, errImageData(elem)
+		}
+		uses[i] = used
+	}
+	return pkg, pkg.UsePackage(uses...)
+}
+
+func (dec *ImageDecoder) decodeFrameDef(args *sx.Pair) (sx.Object, error) {
+	vals, err := imageArgs(args, 5)
+	if err != nil {
+		return nil, err
+	}
+	name, err := dec.DecodeString(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	parent, err := dec.DecodeFrame(vals[1])
+	if err != nil {
+		return nil, err
+	}
+	layout, err := dec.DecodeLayout(vals[2])
+	if err != nil {
+		return nil, err
+	}
+	slots, err := dec.Elems(vals[3])
+	if err != nil {
+		return nil, err
+	}
+	dyn, err := dec.Elems(vals[4])
+	if err != nil {
+		return nil, err
+	}
+	if (layout == nil && len(slots) > 0) || (layout != nil && layout.Size() != len(slots)) || len(dyn)%2 != 0 {
+		return nil, errImageData(args)
+	}
+
+	f := makeFrame(name, parent, len(dyn)/2)
+	if layout != nil {
+		f.layout, f.slots = layout, make([]sx.Object, len(slots))
+	}
+	dec.Define(f)
+	for i, slot := range slots {
+		if pair, isPair := sx.GetPair(slot); isPair && pair != nil && pair.Car() == kwUnboundSlot {
+			continue
+		}
+		obj, err2 := dec.DecodeObject(slot)
+		if err2 != nil {
+			return nil, err2
+		}
+		f.SetSlot(i, obj)
+	}
+	for i := 0; i < len(dyn); i += 2 {
+		sym, err2 := dec.DecodeSymbol(dyn[i])
+		if err2 != nil {
+			return nil, err2
+		}
+		obj, err2 := dec.DecodeObject(dyn[i+1])
+		if err2 != nil {
+			return nil, err2
+		}
+		f.Bind(sym, obj)
+	}
+	return f, nil
+}
+
+// DecodeSymbol restores a symbol. The empty list is decoded as a nil symbol.
+func (dec *ImageDecoder) DecodeSymbol(obj sx.Object) (*sx.Symbol, error) {
+	if sx.IsNil(obj) {
+		return nil, nil
+	}
+	val, err := dec.DecodeObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	if sym, isSymbol := sx.GetSymbol(val); isSymbol {
+		return sym, nil
+	}
+	return nil, errImageData(obj)
+}
+
+// DecodeSymbols restores all given symbols.
+func (dec *ImageDecoder) DecodeSymbols(objs ...sx.Object) ([]*sx.Symbol, error) {
+	result := make([]*sx.Symbol, len(objs))
+	for i, obj := range objs {
+		sym, err := dec.DecodeSymbol(obj)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = sym
+	}
+	return result, nil
+}
+
+// DecodeFrame restores a frame. The empty list is decoded as a nil frame.
+func (dec *ImageDecoder) DecodeFrame(obj sx.Object) (*Frame, error) {
+	if sx.IsNil(obj) {
+		return nil, nil
+	}
+	val, err := dec.DecodeObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	if f, isFrame := GetFrame(val); isFrame {
+		return f, nil
+	}
+	return nil, errImageData(obj)
+}
+
+// DecodeLayout restores a frame layout. The empty list is decoded as a nil
+// layout.
+func (dec *ImageDecoder) DecodeLayout(obj sx.Object) (*FrameLayout, error) {
+	if sx.IsNil(obj) {
+		return nil, nil
+	}
+	lst, isPair := sx.GetPair(obj)
+	if !isPair || lst.Car() != kwLayout {
+		return nil, errImageData(obj)
+	}
+	var syms []*sx.Symbol
+	if args := lst.Tail(); args != nil {
+		elems, err := dec.Elems(args)
+		if err != nil {
+			return nil, err
+		}
+		if syms, err = dec.DecodeSymbols(elems...); err != nil {
+			return nil, err
+		}
+	}
+	return MakeFrameLayout(syms...), nil
+}
+
+// DecodeExpr restores an expression. The empty list is decoded as a nil
+// expression.
+func (dec *ImageDecoder) DecodeExpr(obj sx.Object) (Expr, error) {
+	if sx.IsNil(obj) {
+		return nil, nil
+	}
+	lst, isPair := sx.GetPair(obj)
+	if !isPair {
+		return nil, errImageData(obj)
+	}
+	tag, isSymbol := sx.GetSymbol(lst.Car())
+	if !isSymbol || !tag.IsKeyword() {
+		return nil, errImageData(obj)
+	}
+	d, found := dec.reg.exprs[tag.GetValue()]
+	if !found {
+		return nil, fmt.Errorf("no decoder for expression %q", tag.GetValue())
+	}
+	args, err := imageArgs(lst.Tail(), d.numArgs)
+	if err != nil {
+		return nil, err
+	}
+	return d.fn(dec, args)
+}
+
+// DecodeChildExpr restores an expression that is computed in a new child
+// frame of the frame of the current expression, e.g. the body of a let.
+func (dec *ImageDecoder) DecodeChildExpr(obj sx.Object) (Expr, error) {
+	frames := dec.frames
+	if frames >= 0 {
+		frames++
+	}
+	return dec.decodeExprFrames(obj, frames)
+}
+
+// DecodeProcExpr restores the body of a procedure, which is computed in a new
+// child frame of the given frame.
+func (dec *ImageDecoder) DecodeProcExpr(obj sx.Object, frame *Frame) (Expr, error) {
+	frames := 1
+	for f := frame; f != nil; f = f.parent {
+		frames++
+	}
+	return dec.decodeExprFrames(obj, frames)
+}
+
+// DecodeDynamicExpr restores the body of a procedure, which is computed in
+// frames that are not known when the image is read, e.g. in the frame of its
+// caller.
+func (dec *ImageDecoder) DecodeDynamicExpr(obj sx.Object) (Expr, error) {
+	return dec.decodeExprFrames(obj, -1)
+}
+
+// decodeExprFrames restores an expression that is computed within the given
+// number of frames.
+func (dec *ImageDecoder) decodeExprFrames(obj sx.Object, frames int) (Expr, error) {
+	saved := dec.frames
+	dec.frames = frames
+	expr, err := dec.DecodeExpr(obj)
+	dec.frames = saved
+	return expr, err
+}
+
+// DecodeExprs restores all given expressions.
+func (dec *ImageDecoder) DecodeExprs(objs ...sx.Object) ([]Expr, error) {
+	result := make([]Expr, len(objs))
+	for i, obj := range objs {
+		expr, err := dec.DecodeExpr(obj)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = expr
+	}
+	return result, nil
+}
+
+// DecodeExprList restores the expressions of the given encoded list.
+func (dec *ImageDecoder) DecodeExprList(obj sx.Object) ([]Expr, error) {
+	elems, err := dec.Elems(obj)
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeExprs(elems...)
+}
+
+// decodeBuiltin restores a builtin.
+func (dec *ImageDecoder) decodeBuiltin(obj sx.Object) (*Builtin, error) {
+	val, err := dec.DecodeObject(obj)
+	if err != nil {
+		return nil, err
+	}
+	if b, isBuiltin := GetBuiltin(val); isBuiltin {
+		return b, nil
+	}
+	return nil, errImageData(obj)
+}
+
+// ----- Encode methods of the basic expressions
+
+// Tags of the basic expressions.
+const (
+	tagNil      = "nil"
+	tagObj      = "obj"
+	tagUnbound  = "unbound"
+	tagLookup   = "lookup"
+	tagGlobal   = "global"
+	tagCall     = "call"
+	tagBCall    = "bcall"
+	tagBCall0   = "bcall-0"
+	tagBCall1   = "bcall-1"
+	tagBCall2   = "bcall-2"
+	tagBCall3   = "bcall-3"
+	tagCompiled = "compiled"
+)
+
+// Encode the expression for an image.
+func (nilExpr) Encode(*ImageEncoder) (string, sx.Vector, error) { return tagNil, nil, nil }
+
+// Encode the expression for an image.
+func (oe ObjExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	obj, err := enc.EncodeObject(oe.Obj)
+	return tagObj, sx.Vector{obj}, err
+}
+
+func decodeObjExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	obj, err := dec.DecodeObject(args[0])
+	return ObjExpr{Obj: obj}, err
+}
+
+// Encode the expression for an image.
+func (use UnboundSymbolExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	sym, err := enc.EncodeObject(use.sym)
+	return tagUnbound, sx.Vector{sym}, err
+}
+
+func decodeUnboundSymbolExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	sym, err := dec.DecodeSymbol(args[0])
+	return UnboundSymbolExpr{sym: sym}, err
+}
+
+// Encode the expression for an image.
+func (fse *frameSymbolExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	sym, err := enc.EncodeObject(fse.sym)
+	return tagLookup, sx.Vector{sym, sx.Int64(fse.lvl), sx.Int64(fse.slot)}, err
+}
+
+func decodeFrameSymbolExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	sym, err := dec.DecodeSymbol(args[0])
+	if err != nil {
+		return nil, err
+	}
+	lvl, err := dec.DecodeInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	if lvl < 0 || (dec.frames >= 0 && lvl >= dec.frames) {
+		return nil, errImageData(args[1])
+	}
+	slot, err := dec.DecodeInt(args[2])
+	if err != nil {
+		return nil, err
+	}
+	if slot < -1 {
+		return nil, errImageData(args[2])
+	}
+	return &frameSymbolExpr{sym: sym, lvl: lvl, slot: slot}, nil
+}
+
+// Encode the expression for an image.
+func (ese *envSymbolExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	sym, err := enc.EncodeObject(ese.sym)
+	return tagGlobal, sx.Vector{sym}, err
+}
+
+func decodeEnvSymbolExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	sym, err := dec.DecodeSymbol(args[0])
+	return &envSymbolExpr{sym: sym}, err
+}
+
+// Encode the expression for an image.
+func (ce *CallExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	proc, err := enc.EncodeExpr(ce.Proc)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := enc.EncodeExprList(ce.Args)
+	return tagCall, sx.Vector{proc, args}, err
+}
+
+func decodeCallExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	proc, err := dec.DecodeExpr(args[0])
+	if err != nil {
+		return nil, err
+	}
+	exprs, err := dec.DecodeExprList(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return &CallExpr{Proc: proc, Args: exprs}, nil
+}
+
+// encodeBuiltinCall encodes a builtin call with the given tag.
+func encodeBuiltinCall(enc *ImageEncoder, tag string, proc *Builtin, args []Expr, asList bool) (string, sx.Vector, error) {
+	eproc, err := enc.EncodeObject(proc)
+	if err != nil {
+		return "", nil, err
+	}
+	eargs, err := enc.EncodeExprs(args...)
+	if err != nil {
+		return "", nil, err
+	}
+	if asList {
+		return tag, sx.Vector{eproc, sx.MakeList(eargs...)}, nil
+	}
+	return tag, append(sx.Vector{eproc}, eargs...), nil
+}
+
+// decodeBuiltinCall restores the builtin and the arguments of a builtin call,
+// where the arguments are given as a vector or as a list.
+func decodeBuiltinCall(dec *ImageDecoder, args sx.Vector, asList bool) (*Builtin, []Expr, error) {
+	proc, err := dec.decodeBuiltin(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	var exprs []Expr
+	if asList {
+		exprs, err = dec.DecodeExprList(args[1])
+	} else {
+		exprs, err = dec.DecodeExprs(args[1:]...)
+	}
+	return proc, exprs, err
+}
+
+// Encode the expression for an image.
+func (bce *builtinCallExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	return encodeBuiltinCall(enc, tagBCall, bce.Proc, bce.Args, true)
+}
+
+func decodeBuiltinCallExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	proc, exprs, err := decodeBuiltinCall(dec, args, true)
+	if err != nil {
+		return nil, err
+	}
+	return &builtinCallExpr{Proc: proc, Args: exprs}, nil
+}
+
+// Encode the expression for an image.
+func (bce *builtinCall0Expr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	return encodeBuiltinCall(enc, tagBCall0, bce.Proc, nil, false)
+}
+
+func decodeBuiltinCall0Expr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	proc, _, err := decodeBuiltinCall(dec, args, false)
+	if err != nil {
+		return nil, err
+	}
+	if proc.Fn0 == nil {
+		return nil, errImageData(args[0])
+	}
+	return &builtinCall0Expr{Proc: proc}, nil
+}
+
+// Encode the expression for an image.
+func (bce *BuiltinCall1Expr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	return encodeBuiltinCall(enc, tagBCall1, bce.Proc, []Expr{bce.Arg}, false)
+}
+
+func decodeBuiltinCall1Expr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	proc, exprs, err := decodeBuiltinCall(dec, args, false)
+	if err != nil {
+		return nil, err
+	}
+	if proc.Fn1 == nil {
+		return nil, errImageData(args[0])
+	}
+	return &BuiltinCall1Expr{Proc: proc, Arg: exprs[0]}, nil
+}
+
+// Encode the expression for an image.
+func (bce *builtinCall2Expr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	return encodeBuiltinCall(enc, tagBCall2, bce.Proc, []Expr{bce.Arg0, bce.Arg1}, false)
+}
+
+func decodeBuiltinCall2Expr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	proc, exprs, err := decodeBuiltinCall(dec, args, false)
+	if err != nil {
+		return nil, err
+	}
+	if proc.Fn2 == nil {
+		return nil, errImageData(args[0])
+	}
+	return &builtinCall2Expr{Proc: proc, Arg0: exprs[0], Arg1: exprs[1]}, nil
+}
+
+// Encode the expression for an image.
+func (bce *builtinCall3Expr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	return encodeBuiltinCall(enc, tagBCall3, bce.Proc, []Expr{bce.Arg0, bce.Arg1, bce.Arg2}, false)
+}
+
+func decodeBuiltinCall3Expr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	proc, exprs, err := decodeBuiltinCall(dec, args, false)
+	if err != nil {
+		return nil, err
+	}
+	if proc.Fn3 == nil {
+		return nil, errImageData(args[0])
+	}
+	return &builtinCall3Expr{Proc: proc, Arg0: exprs[0], Arg1: exprs[1], Arg2: exprs[2]}, nil
+}
+
+// Encode the expression for an image. Only the source expression is stored,
+// it is compiled again, when the image is read.
+func (ce *CompiledExpr) Encode(enc *ImageEncoder) (string, sx.Vector, error) {
+	source, err := enc.EncodeExpr(ce.source)
+	return tagCompiled, sx.Vector{source}, err
+}
+
+func decodeCompiledExpr(dec *ImageDecoder, args sx.Vector) (Expr, error) {
+	source, err := dec.DecodeExpr(args[0])
+	if err != nil {
+		return nil, err
+	}
+	var c Compiler
+	return c.Lower(source)
+}
@@ -0,0 +1,324 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+const imageRules = `
+(defun fact (n) (if (= n 0) 1 (* n (fact (- n 1)))))
+(defvar counter (let ((c 0)) (list (lambda () (set! c (+ c 1)) c) (lambda () c))))
+(defvar even (let ((even? ()) (odd? ()))
+  (set! even? (lambda (n) (if (= n 0) T (odd? (- n 1)))))
+  (set! odd? (lambda (n) (if (= n 0) () (even? (- n 1)))))
+  even?))
+(defmacro twice (x) ` + "`" + `(begin ,x ,x))
+(define-syntax my-or (syntax-rules () ((_) ()) ((_ e) e) ((_ e r ...) (let ((t e)) (if t t (my-or r ...))))))
+(defvar y 3)
+(defdyn dyn-add (x) (+ x y))
+(defvar data (list 1 '(2 . 3) "s" :kw (list->vector '(4 5)) (vector) 'sym))
+(defun safe-car (x) (try (car x) (except T c :error) (finally (list x))))
+(defun catcher (x) (catch 'done (begin (throw 'done (list x)) 2)))
+(defun escape (x) (let/ec k (list x (k (car x)))))
+(defun qq (x) ` + "`" + `(a ,x ,@x))
+(defun logic (a b) (list (and a b) (or b a) (begin1 a b)))
+(defun lets (a) (let* ((b (list a)) (c (cons a b))) (let ((d c) (e b)) (list d e))))
+(defun mkpkg () (defpackage "image-test" (:use "INIT") (:export img)))
+`
+
+func makeImageRoot(t *testing.T, compile bool) *sxeval.Binding {
+	t.Helper()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := sxbuiltins.LoadPrelude(root); err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(root).SetCompile(compile)
+	rd := sxreader.MakeReader(strings.NewReader(imageRules))
+	for {
+		obj, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = env.Eval(obj, nil); err != nil {
+			t.Fatalf("%v: %v", obj, err)
+		}
+	}
+	root.Freeze()
+	return root
+}
+
+func makeImageRegistry(t *testing.T) *sxeval.ImageRegistry {
+	t.Helper()
+	base := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(base); err != nil {
+		t.Fatal(err)
+	}
+	reg := sxeval.MakeImageRegistry(base)
+	sxbuiltins.AddImageDecoders(reg)
+	return reg
+}
+
+func writeImage(t *testing.T, bind *sxeval.Binding, reg *sxeval.ImageRegistry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := sxeval.WriteImage(&buf, bind, reg); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImage(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		src string
+		exp string
+	}{
+		{"(fact 10)", "3628800"},
+		{"(begin ((car counter)) ((car counter)) ((cadr counter)))", "2"},
+		{"(list (even 10) (even 7))", "(T ())"},
+		{"(let ((n 0)) (twice (set! n (+ n 1))) n)", "2"},
+		{"(list (my-or) (my-or () 2) (let ((t 5)) (my-or () t)))", "(() 2 5)"},
+		{"(list (dyn-add 4) (let ((y 17)) (dyn-add 4)))", "(7 21)"},
+		{"data", `(1 (2 . 3) "s" :kw (vector 4 5) () sym)`},
+		{"(list (vector? (nth data 4)) (vector? (nth data 5)) (list? (nth data 5)))", "(T T T)"},
+		{"(list (safe-car '(1)) (safe-car 1))", "(1 :error)"},
+		{"(list (catcher 1) (escape '(2)))", "((1) 2)"},
+		{"(qq '(1 2))", "(a (1 2) 1 2)"},
+		{"(list (logic 1 ()) (logic 1 2))", "((() 1 1) (2 2 1))"},
+		{"(lets 1)", "((1 1) (1))"},
+	}
+	reg := makeImageRegistry(t)
+	for _, compile := range []bool{false, true} {
+		img := writeImage(t, makeImageRoot(t, compile), reg)
+		for _, run := range []bool{false, true} {
+			restored, err := sxeval.ReadImage(bytes.NewReader(img), reg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := writeImage(t, restored, reg); !bytes.Equal(got, img) {
+				t.Errorf("compile=%v: image of restored binding differs:\n%s\nexpected:\n%s", compile, got, img)
+			}
+			env := sxeval.MakeEnvironment(restored.MakeChildBinding("image", 8)).SetCompile(run)
+			for _, tc := range testcases {
+				obj, err2 := sxreader.MakeReader(strings.NewReader(tc.src)).Read()
+				if err2 != nil {
+					t.Fatal(err2)
+				}
+				res, err2 := env.Eval(obj, nil)
+				if err2 != nil {
+					t.Errorf("compile=%v, run=%v: error evaluating %s: %v", compile, run, tc.src, err2)
+					continue
+				}
+				if got := res.String(); got != tc.exp {
+					t.Errorf("compile=%v, run=%v: %s should result in %s, but got %s", compile, run, tc.src, tc.exp, got)
+				}
+			}
+		}
+	}
+}
+
+func TestImageIncompatible(t *testing.T) {
+	t.Parallel()
+	root := makeImageRoot(t, false)
+	img := writeImage(t, root, makeImageRegistry(t))
+
+	base := sxeval.MakeRootBinding(256)
+	if err := sxeval.BindBuiltins(base, &sxbuiltins.Car, &sxbuiltins.Cdr); err != nil {
+		t.Fatal(err)
+	}
+	other := sxeval.MakeImageRegistry(base)
+	sxbuiltins.AddImageDecoders(other)
+	if _, err := sxeval.ReadImage(bytes.NewReader(img), other); !errors.Is(err, sxeval.ErrImageIncompatible) {
+		t.Errorf("other builtins: expected incompatible image, but got %v", err)
+	}
+
+	changed := bytes.Replace(img, []byte("(:sx-image 1 "), []byte("(:sx-image 0 "), 1)
+	if _, err := sxeval.ReadImage(bytes.NewReader(changed), makeImageRegistry(t)); !errors.Is(err, sxeval.ErrImageIncompatible) {
+		t.Errorf("other version: expected incompatible image, but got %v", err)
+	}
+}
+
+func TestWriteImageErrors(t *testing.T) {
+	t.Parallel()
+	reg := makeImageRegistry(t)
+	var buf bytes.Buffer
+	if err := sxeval.WriteImage(&buf, sxeval.MakeRootBinding(1), reg); err == nil {
+		t.Error("binding that is not frozen must not be stored")
+	}
+	root := makeImageRoot(t, false)
+	if err := sxeval.WriteImage(&buf, root.MakeChildBinding("child", 1), reg); err == nil {
+		t.Error("child binding must not be stored")
+	}
+
+	bind := sxeval.MakeRootBinding(1)
+	if err := bind.Bind(sx.MakeSymbol("bind"), root); err != nil {
+		t.Fatal(err)
+	}
+	bind.Freeze()
+	if err := sxeval.WriteImage(&buf, bind, reg); err == nil {
+		t.Error("binding must not be stored")
+	}
+
+	bind = sxeval.MakeRootBinding(1)
+	if err := sxeval.BindBuiltins(bind, &sxeval.Builtin{Name: "unknown", Fn: func(*sxeval.Environment, sx.Vector, *sxeval.Frame) (sx.Object, error) {
+		return sx.Nil(), nil
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	bind.Freeze()
+	if err := sxeval.WriteImage(&buf, bind, reg); err == nil {
+		t.Error("unregistered builtin must not be stored")
+	}
+
+	bind = sxeval.MakeIsolatedRootBinding(1)
+	bind.Freeze()
+	if err := sxeval.WriteImage(&buf, bind, reg); err != nil {
+		t.Errorf("isolated binding without symbol values must be stored, but got %v", err)
+	}
+	bind = sxeval.MakeIsolatedRootBinding(1)
+	if err := sxeval.MakeEnvironment(bind).SetSymbolValue(sx.MakeSymbol("image-isolated"), sx.Int64(1)); err != nil {
+		t.Fatal(err)
+	}
+	bind.Freeze()
+	if err := sxeval.WriteImage(&buf, bind, reg); err == nil {
+		t.Error("symbol values of an isolated binding must not be stored")
+	}
+}
+
+func TestReadImageErrors(t *testing.T) {
+	t.Parallel()
+	base := sxeval.MakeRootBinding(2)
+	if err := sxeval.BindBuiltins(base, &sxbuiltins.Cons); err != nil {
+		t.Fatal(err)
+	}
+	if err := sxeval.BindSpecials(base, &sxbuiltins.LambdaS, &sxbuiltins.DynLambdaS); err != nil {
+		t.Fatal(err)
+	}
+	base.Freeze()
+	root := sxeval.MakeRootBinding(2)
+	for name, src := range map[string]string{"image-read-fn": "(lambda (a b) (cons a b))", "image-read-dyn": "(dyn-lambda (d) d)"} {
+		obj, err := sxreader.MakeReader(strings.NewReader(src)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn, err := sxeval.MakeEnvironment(base).Eval(obj, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = root.Bind(sx.MakeSymbol(name), fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root.Freeze()
+	reg := makeImageRegistry(t)
+	img := string(writeImage(t, root, reg))
+	if _, err := sxeval.ReadImage(strings.NewReader(img), reg); err != nil {
+		t.Fatal(err)
+	}
+
+	// All changes apply to the lexical lambda.
+	pos := strings.Index(img, "(:lambda ")
+	if pos < 0 {
+		t.Fatalf("image does not contain a lambda:\n%s", img)
+	}
+	testcases := []struct {
+		name     string
+		old, new string
+	}{
+		{"no-fn2", `(:bcall-2 (:builtin "cons")`, `(:bcall-2 (:builtin "car")`},
+		{"no-fn3", `(:bcall-2 (:builtin "cons")`, `(:bcall-3 (:builtin "cons") ()`},
+		{"neg-level", " 0 0)", " -1 0)"},
+		{"neg-slot", " 0 0)", " 0 -2)"},
+		{"deep-level", " 0 0)", " 2 0)"},
+		{"outer-level", " 0 0)", " 1 0)"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(img[pos:], tc.old) {
+				t.Fatalf("image does not contain %s:\n%s", tc.old, img)
+			}
+			changed := img[:pos] + strings.Replace(img[pos:], tc.old, tc.new, 1)
+			_, err2 := sxeval.ReadImage(strings.NewReader(changed), reg)
+			if err2 == nil || !strings.Contains(err2.Error(), "invalid image data") {
+				t.Errorf("invalid image data expected, but got %v:\n%s", err2, changed)
+			}
+		})
+	}
+
+	// The frames of a dynamic lambda are not known, so its lookups are
+	// checked when it is called.
+	pos = strings.Index(img, "(:dyn-lambda ")
+	if pos < 0 {
+		t.Fatalf("image does not contain a dynamic lambda:\n%s", img)
+	}
+	dynImg := img[:pos] + strings.Replace(img[pos:], " 0 0)", " 2 0)", 1)
+	restored, err := sxeval.ReadImage(strings.NewReader(dynImg), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, compile := range []bool{false, true} {
+		env := sxeval.MakeEnvironment(restored).SetCompile(compile)
+		if _, err = env.Eval(sx.MakeList(sx.MakeSymbol("image-read-dyn"), sx.Int64(1)), nil); err == nil {
+			t.Errorf("compile=%v: lookup beyond the outermost frame must fail:\n%s", compile, dynImg)
+		}
+	}
+
+	// An image must not export symbols from packages that were present before.
+	const name = "image-read-export"
+	exported := strings.Replace(img, `"image-read-fn" 0)`, `"`+name+`" 1)`, 1)
+	if _, err = sxeval.ReadImage(strings.NewReader(exported), reg); err != nil {
+		t.Fatal(err)
+	}
+	if sym := sx.MakeSymbol(name); sym.Package().IsExternal(sym) {
+		t.Errorf("symbol %v must not be exported from package %v", sym, sym.Package())
+	}
+}
+
+func TestImageWorld(t *testing.T) {
+	t.Parallel()
+	img := writeImage(t, makeImageRoot(t, true), makeImageRegistry(t))
+	w := sx.MakeWorld()
+	restored, err := sxeval.ReadImage(bytes.NewReader(img), makeImageRegistry(t).SetWorld(w))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := sxreader.MakeReader(strings.NewReader("(list (fact 5) (qq '(1)) (let ((n 0)) (twice (set! n (+ n 1))) n))")).SetWorld(w).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(restored.MakeChildBinding("world", 8)).SetWorld(w)
+	res, err := env.Eval(obj, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := res.String(), "(120 (a (1) 1) 2)"; got != exp {
+		t.Errorf("expected %s, but got %s", exp, got)
+	}
+}
@@ -29,6 +29,14 @@ type Improver struct {
 	env      *Environment
 	observer ImproveObserver
 	dynamic  bool // symbol resolving by full search?
+
+	used map[usedSymbol]struct{} // only valid for the root improver
+}
+
+// usedSymbol is a symbol that is bound in the given frame.
+type usedSymbol struct {
+	frame *Frame
+	sym   *sx.Symbol
 }
 
 // ImproveObserver monitors the inner workings of the improve process.
@@ -41,6 +49,30 @@ type ImproveObserver interface {
 	AfterImprove(*Improver, Expr, Expr, error)
 }
 
+// OptimizeObserver is an optional extension of an ImproveObserver. It is
+// notified about each optimization that was applied.
+type OptimizeObserver interface {
+	// Optimized is called when the optimization with the given name replaced
+	// the expression with the resulting expression.
+	Optimized(imp *Improver, name string, expr, result Expr)
+}
+
+// Names of the optimizations, as reported to an OptimizeObserver.
+const (
+	OptFold        = "fold"         // a pure call with constant arguments was computed
+	OptInline      = "inline"       // a call was replaced by the body of the procedure
+	OptDeadBinding = "dead-binding" // an unused binding with a pure value was removed
+	OptDeadBranch  = "dead-branch"  // a branch that will never be computed was removed
+)
+
+// Inliner is an optional interface for a Callable, whose calls may be replaced
+// by an equivalent expression.
+type Inliner interface {
+	// Inline returns an expression that computes the call with the given
+	// (improved) arguments, or nil if the call cannot be inlined.
+	Inline(imp *Improver, args []Expr) Expr
+}
+
 // MakeChildImprover creates a subordinate improver with a new frame.
 func (imp *Improver) MakeChildImprover(name string, baseSize int, dynamic bool) *Improver {
 	return &Improver{
@@ -52,6 +84,19 @@ func (imp *Improver) MakeChildImprover(name string, baseSize int, dynamic bool)
 	}
 }
 
+// MakeSlotImprover creates a subordinate improver with a new frame, where the
+// symbols of the layout are stored in slots. Symbols that are bound in this
+// frame will be looked up by their slot index.
+func (imp *Improver) MakeSlotImprover(name string, layout *FrameLayout, dynamic bool) *Improver {
+	return &Improver{
+		parent:   imp.parent,
+		frame:    imp.frame.MakeSlotFrame(name, layout),
+		env:      imp.env,
+		observer: imp.observer,
+		dynamic:  dynamic,
+	}
+}
+
 // Improve the given expression. Do not call `expr.Improve()` directly.
 func (imp *Improver) Improve(expr Expr) (Expr, error) {
 	if observer := imp.observer; observer != nil {
@@ -82,9 +127,16 @@ func (imp *Improver) ImproveSlice(exprs []Expr) error {
 	return nil
 }
 
+// foldStepsLimit is the maximum number of computation steps to fold a call.
+// It guards against non-terminating or very long computations.
+const foldStepsLimit = 10000
+
 // ImproveFoldCall improves a call if all args are constants and the
 // callable is pure. If successful, the new expression is returned.
-// Otherwise the expression is nil.
+// Otherwise the expression is nil. The call is not folded, if it does not
+// finish within a limited number of computation steps. It is computed with
+// the world of packages, the output, and the allocation handler of the
+// environment of the improver.
 func (imp *Improver) ImproveFoldCall(proc Callable, args []Expr) (Expr, error) {
 	vals := make(sx.Vector, len(args))
 	for i, arg := range args {
@@ -95,14 +147,42 @@ func (imp *Improver) ImproveFoldCall(proc Callable, args []Expr) (Expr, error) {
 		}
 	}
 	if proc.IsPure(vals) {
-		env := MakeEnvironment(imp.env.globals)
-		if result, err := proc.ExecuteCall(env, vals, imp.frame); err == nil {
+		env := imp.env.derive().
+			SetComputeHandler(MakeStepsLimitHandler(foldStepsLimit, DefaultHandler{}))
+		if result, err := env.Apply(proc, vals, imp.frame); err == nil {
 			return imp.Improve(ObjExpr{Obj: result})
 		}
 	}
 	return nil, nil
 }
 
+// Optimized reports that the optimization with the given name replaced the
+// expression with the resulting expression.
+func (imp *Improver) Optimized(name string, expr, result Expr) {
+	if observer, isOptimizeObserver := imp.observer.(OptimizeObserver); isOptimizeObserver {
+		observer.Optimized(imp, name, expr, result)
+	}
+}
+
+// markUsed records that the symbol, which is bound in the given frame, was
+// referenced.
+func (imp *Improver) markUsed(frame *Frame, sym *sx.Symbol) {
+	root := imp.parent
+	if root.used == nil {
+		root.used = map[usedSymbol]struct{}{}
+	}
+	root.used[usedSymbol{frame, sym}] = struct{}{}
+}
+
+// IsUsed returns true, if the symbol bound in the frame of this improver was
+// referenced while improving an expression. Only references that were
+// resolved statically are known. A symbol might still be looked up
+// dynamically, e.g. by an impure expression like `eval`.
+func (imp *Improver) IsUsed(sym *sx.Symbol) bool {
+	_, found := imp.parent.used[usedSymbol{imp.frame, sym}]
+	return found
+}
+
 // Frame returns the frame of this environment.
 func (imp *Improver) Frame() *Frame { return imp.frame }
 
@@ -15,13 +15,23 @@ package sxeval
 
 import (
 	"fmt"
+	"slices"
 
 	"t73f.de/r/sx"
 )
 
 // ParseEnvironment is a parsing environment.
 type ParseEnvironment struct {
-	env *Environment
+	env        *Environment
+	aliases    map[*sx.Symbol]alias
+	expansions int // number of macro expansions
+}
+
+// alias stores the symbol that is renamed by an alias symbol, together with
+// the frame of the definition of the renaming macro.
+type alias struct {
+	sym   *sx.Symbol
+	frame *Frame
 }
 
 // ParseObserver monitors the parsing process.
@@ -55,7 +65,7 @@ restart:
 	}
 	switch f := form.(type) {
 	case *sx.Symbol:
-		return UnboundSymbolExpr{sym: f}, nil
+		return pe.parseSymbol(f, frame), nil
 	case *sx.Pair:
 		expr, err := pe.parsePair(f, frame)
 		if err == nil {
@@ -76,12 +86,17 @@ func (pe *ParseEnvironment) parsePair(pair *sx.Pair, frame *Frame) (Expr, error)
 	var proc Expr
 	first := pair.Car()
 	if sym, isSymbol := sx.GetSymbol(first); isSymbol {
-		if val, found := pe.env.Resolve(sym, frame); found {
-			if sp, isSyntax := GetSyntax(val); isSyntax {
-				return sp.Parse(pe, pair.Tail(), frame)
+		if sy, isSyntax := pe.resolveSyntax(sym, frame); isSyntax {
+			if ex, isExpander := sy.(Expander); isExpander {
+				form, err := pe.expand(ex, pair, frame)
+				if err != nil {
+					return nil, err
+				}
+				return NilExpr, pe.ParseAgain(form)
 			}
+			return sy.Parse(pe, pair.Tail(), frame)
 		}
-		proc = UnboundSymbolExpr{sym: sym}
+		proc = pe.parseSymbol(sym, frame)
 	} else {
 		p, err := pe.Parse(first, frame)
 		if err != nil {
@@ -128,3 +143,112 @@ type errParseAgain struct {
 }
 
 func (e errParseAgain) Error() string { return fmt.Sprintf("Again: %T/%v", e.form, e.form) }
+
+// ----- Aliases for hygienic macros
+
+// MakeAlias creates a new symbol that renames the given symbol. It is used by
+// macros to introduce symbols that cannot capture symbols of the macro call.
+// If the alias is bound by a form of the macro expansion, e.g. by `let` or
+// `lambda`, it denotes that binding. Otherwise it denotes the renamed symbol,
+// as seen from the frame of the macro definition.
+func (pe *ParseEnvironment) MakeAlias(sym *sx.Symbol, frame *Frame) *sx.Symbol {
+	if pe.aliases == nil {
+		pe.aliases = map[*sx.Symbol]alias{}
+	}
+	result := sx.MakeUninternedSymbol(sym.GetValue())
+	pe.aliases[result] = alias{sym: sym, frame: frame}
+	return result
+}
+
+// IsAlias returns true, if the given symbol is an alias.
+func (pe *ParseEnvironment) IsAlias(sym *sx.Symbol) bool {
+	_, isAlias := pe.aliases[sym]
+	return isAlias
+}
+
+// ResolveAlias returns the symbol that is denoted by the given symbol within
+// the given frame. If the symbol is an alias that is not bound in the frame,
+// the renamed symbol is returned. Otherwise the symbol itself is returned.
+func (pe *ParseEnvironment) ResolveAlias(sym *sx.Symbol, frame *Frame) *sx.Symbol {
+	sym, _ = pe.resolveAlias(sym, frame)
+	return sym
+}
+
+// resolveAlias returns the symbol that is denoted by the given symbol,
+// together with the frame where it must be resolved.
+func (pe *ParseEnvironment) resolveAlias(sym *sx.Symbol, frame *Frame) (*sx.Symbol, *Frame) {
+	for {
+		a, isAlias := pe.aliases[sym]
+		if !isAlias {
+			return sym, frame
+		}
+		if _, found := frame.Resolve(sym); found {
+			return sym, frame
+		}
+		sym = a.sym
+		if a.frame == nil {
+			// Macro was defined globally: symbols of frames must not be used.
+			frame = nil
+		}
+	}
+}
+
+// parseSymbol returns the expression to resolve the given symbol.
+func (pe *ParseEnvironment) parseSymbol(sym *sx.Symbol, frame *Frame) Expr {
+	resolved, resolveFrame := pe.resolveAlias(sym, frame)
+	if resolved != sym && resolveFrame == nil && frame != nil {
+		if _, found := frame.Resolve(resolved); found {
+			// The renamed symbol is bound by the macro call, but the macro
+			// refers to the global value.
+			return &envSymbolExpr{sym: resolved}
+		}
+	}
+	return UnboundSymbolExpr{sym: resolved}
+}
+
+// StripAliases returns the given object, where all aliases are replaced by
+// the symbols they rename. It is used for quoted objects.
+func (pe *ParseEnvironment) StripAliases(obj sx.Object) sx.Object {
+	if len(pe.aliases) == 0 {
+		return obj
+	}
+	result, _ := pe.stripAliases(obj)
+	return result
+}
+
+func (pe *ParseEnvironment) stripAliases(obj sx.Object) (sx.Object, bool) {
+	switch o := obj.(type) {
+	case *sx.Symbol:
+		changed := false
+		for {
+			a, isAlias := pe.aliases[o]
+			if !isAlias {
+				return o, changed
+			}
+			o, changed = a.sym, true
+		}
+	case *sx.Pair:
+		if o == nil {
+			return o, false
+		}
+		car, carChanged := pe.stripAliases(o.Car())
+		cdr, cdrChanged := pe.stripAliases(o.Cdr())
+		if carChanged || cdrChanged {
+			return sx.Cons(car, cdr), true
+		}
+	case sx.Vector:
+		var result sx.Vector
+		for i, elem := range o {
+			if stripped, changed := pe.stripAliases(elem); changed {
+				if result == nil {
+					result = slices.Clone(o)
+				}
+				result[i] = stripped
+			}
+		}
+		if result != nil {
+			return result, true
+		}
+	}
+	return obj, false
+}
@@ -16,15 +16,18 @@ package sxeval_test
 import (
 	"fmt"
 	"slices"
+	"strings"
 	"testing"
 
 	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
 	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
 )
 
 func BenchmarkEvenTCO(b *testing.B) {
 	testcases := [...]int{0, 1, 2, 4, 16, 64, 512, 4096, 65536}
-	root := createBindingForTCO()
+	root := createBindingForTCO(false)
 	evenSym := sx.MakeSymbol("even?")
 	for _, tc := range testcases {
 		b.Run(fmt.Sprintf("%5d", tc), func(b *testing.B) {
@@ -47,7 +50,7 @@ func BenchmarkEvenTCO(b *testing.B) {
 
 func BenchmarkHandler(b *testing.B) {
 	testcases := [...]int{0, 1, 65536}
-	root := createBindingForTCO()
+	root := createBindingForTCO(false)
 	evenSym := sx.MakeSymbol("even?")
 	handlers := []sxeval.ComputeHandler{
 		nil,
@@ -107,9 +110,80 @@ func BenchmarkCollatz(b *testing.B) {
 	runBenchmark(b, sx.MakeList(sx.MakeSymbol("collatz"), sx.Int64(63728127), sx.Int64(0)))
 }
 
+// BenchmarkBuiltinFn2 compares builtins with two arguments, which are called
+// via the specialized function or via the general one. The root binding is
+// frozen, so that the builtins are resolved while parsing. The arguments are
+// bound in a child binding to prevent constant folding.
+func BenchmarkBuiltinFn2(b *testing.B) {
+	testcases := []struct {
+		bi  *sxeval.Builtin
+		src string
+	}{
+		{&sxbuiltins.Cons, "(F (F a b) (F (F b a) (F a a)))"},
+		{&sxbuiltins.Equal, "(if (F a b) (F b a) (F a a))"},
+		{&sxbuiltins.NumLess, "(if (F a b) (F b a) (F a a))"},
+	}
+	builtins := make([]*sxeval.Builtin, 0, 2*len(testcases))
+	for _, tc := range testcases {
+		general := *tc.bi
+		general.Name, general.Fn2 = tc.bi.Name+"-fn", nil
+		if general.Fn == nil {
+			fn2 := tc.bi.Fn2
+			general.Fn = func(env *sxeval.Environment, args sx.Vector, frame *sxeval.Frame) (sx.Object, error) {
+				return fn2(env, args[0], args[1], frame)
+			}
+		}
+		builtins = append(builtins, tc.bi, &general)
+	}
+	root := sxeval.MakeRootBinding(len(builtins) + 1)
+	if err := sxeval.BindBuiltins(root, builtins...); err != nil {
+		b.Fatal(err)
+	}
+	if err := sxeval.BindSpecials(root, &sxbuiltins.IfS); err != nil {
+		b.Fatal(err)
+	}
+	root.Freeze()
+	bind := root.MakeChildBinding("bench", 2)
+	if err := bind.Bind(sx.MakeSymbol("a"), sx.Int64(1)); err != nil {
+		b.Fatal(err)
+	}
+	if err := bind.Bind(sx.MakeSymbol("b"), sx.Int64(2)); err != nil {
+		b.Fatal(err)
+	}
+
+	for i, bi := range builtins {
+		src := strings.ReplaceAll(testcases[i/2].src, "F", bi.Name)
+		obj, err := sxreader.MakeReader(strings.NewReader(src)).Read()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, mode := range []string{"walk", "vm"} {
+			b.Run(bi.Name+"/"+mode, func(b *testing.B) {
+				env := sxeval.MakeEnvironment(bind).SetCompile(mode == "vm")
+				expr, err := env.Parse(obj, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for b.Loop() {
+					if _, err = env.Run(expr, nil); err != nil {
+						b.Error(err)
+						break
+					}
+				}
+			})
+		}
+	}
+}
+
+// runBenchmark compares the tree walker with the virtual machine.
 func runBenchmark(b *testing.B, sexpr sx.Object) {
-	root := createBindingForTCO()
-	env := sxeval.MakeEnvironment(root)
+	b.Run("walk", func(b *testing.B) { runBenchmarkMode(b, sexpr, false) })
+	b.Run("vm", func(b *testing.B) { runBenchmarkMode(b, sexpr, true) })
+}
+
+func runBenchmarkMode(b *testing.B, sexpr sx.Object, compile bool) {
+	root := createBindingForTCO(compile)
+	env := sxeval.MakeEnvironment(root).SetCompile(compile)
 	expr, err := env.Parse(sexpr, nil)
 	if err != nil {
 		b.Error(err)
@@ -14,11 +14,14 @@
 package sxeval_test
 
 import (
+	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"testing"
+	"time"
 
 	"t73f.de/r/sx"
 	"t73f.de/r/sx/sxbuiltins"
@@ -65,7 +68,7 @@ type testcase struct {
 }
 type testCases []testcase
 
-func (testcases testCases) Run(t *testing.T, root *sxeval.Binding) {
+func (testcases testCases) Run(t *testing.T, root *sxeval.Binding, compile bool) {
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			rd := sxreader.MakeReader(strings.NewReader(tc.src))
@@ -75,7 +78,7 @@ func (testcases testCases) Run(t *testing.T, root *sxeval.Binding) {
 				return
 			}
 			bind := root.MakeChildBinding(tc.name, 0)
-			env := sxeval.MakeEnvironment(bind)
+			env := sxeval.MakeEnvironment(bind).SetCompile(compile)
 			res, err := env.Eval(obj, nil)
 			if size := env.Size(); size > 0 {
 				t.Error("stack not empty, size:", size)
@@ -114,13 +117,14 @@ func TestEval(t *testing.T) {
 			return sxeval.ObjExpr{Obj: args.Car()}, nil
 		},
 	})
-	testcases.Run(t, root)
+	t.Run("walk", func(t *testing.T) { testcases.Run(t, root, false) })
+	t.Run("vm", func(t *testing.T) { testcases.Run(t, root, true) })
 }
 
 //go:embed tests.sxn
 var sxevalTests string
 
-func createBindingForTCO() *sxeval.Binding {
+func createBindingForTCO(compile bool) *sxeval.Binding {
 	root := sxeval.MakeRootBinding(32)
 	if err := sxbuiltins.LoadPrelude(root); err != nil {
 		panic(err)
@@ -145,7 +149,7 @@ func createBindingForTCO() *sxeval.Binding {
 	root.Freeze()
 	rd := sxreader.MakeReader(strings.NewReader(sxevalTests))
 	bind := root.MakeChildBinding("TCO", 128)
-	env := sxeval.MakeEnvironment(bind)
+	env := sxeval.MakeEnvironment(bind).SetCompile(compile)
 	for {
 		obj, err := rd.Read()
 		if err != nil {
@@ -185,6 +189,65 @@ func TestTailCallOptimization(t *testing.T) {
 		{name: "collatz-27", src: "(collatz 27 0)", exp: "111"},
 		{name: "collatz-63728127", src: "(collatz 63728127 0)", exp: "949"},
 	}
-	root := createBindingForTCO()
-	testcases.Run(t, root)
+	t.Run("walk", func(t *testing.T) { testcases.Run(t, createBindingForTCO(false), false) })
+	t.Run("vm", func(t *testing.T) { testcases.Run(t, createBindingForTCO(true), true) })
+}
+
+func TestRunContext(t *testing.T) {
+	t.Parallel()
+	root := createBindingForTCO(false)
+
+	eval := func(ctx context.Context, bind *sxeval.Binding, src string) (sx.Object, error) {
+		obj, err := sxreader.MakeReader(strings.NewReader(src)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sxeval.MakeEnvironment(bind).EvalContext(ctx, obj, nil)
+	}
+
+	res, err := eval(context.Background(), root, "(fac 10)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.String(); got != "3628800" {
+		t.Errorf("expected 3628800, but got %v", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = eval(ctx, root, "(even? 1000000000000)")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("deadline exceeded error expected, but got %v", err)
+	}
+	var ctxErr sxeval.ContextError
+	if !errors.As(err, &ctxErr) {
+		t.Errorf("context error expected, but got %T/%v", err, err)
+	}
+	var execErr sxeval.ExecuteError
+	if !errors.As(err, &execErr) || len(execErr.CallStack) == 0 {
+		t.Errorf("call stack expected, but got %v", err)
+	}
+
+	// Builtins like `map` must be stopped too.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	bind := root.MakeChildBinding("cancel", 1)
+	calls := 0
+	_ = bind.Bind(sx.MakeSymbol("cancel"), &sxeval.Builtin{
+		Name:     "cancel",
+		MinArity: 1,
+		MaxArity: 1,
+		Fn1: func(*sxeval.Environment, sx.Object, *sxeval.Frame) (sx.Object, error) {
+			calls++
+			cancel()
+			return sx.Nil(), nil
+		},
+	})
+	_, err = eval(ctx, bind, "(map cancel (list 1 2 3 4))")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("cancel error expected, but got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("map should be stopped after first call, but got %d calls", calls)
+	}
 }
@@ -40,14 +40,24 @@ func GetSyntax(obj sx.Object) (Syntax, bool) {
 type Special struct {
 	Name string
 	Fn   func(*ParseEnvironment, *sx.Pair, *Frame) (Expr, error)
+
+	// Walk expands all macros within the arguments of the special form and
+	// returns the resulting arguments. It must respect arguments that are
+	// not evaluated and symbols that are bound by the special form. If Walk
+	// is nil, all arguments are expanded like ordinary forms.
+	Walk func(*ParseEnvironment, *sx.Pair, *Frame) (*sx.Pair, error)
 }
 
-// Bind the special form to a given environment.
+// Bind the special form to a given environment. The symbol of the special
+// form is interned in the package INIT.
 func (sp *Special) Bind(bi *Binding) error {
-	return bi.Bind(sx.MakeSymbol(sp.Name), sp)
+	return bi.Bind(sx.InitPackage().MakeSymbol(sp.Name), sp)
 }
 
-// BindSpecials will bind many builtins to an environment.
+// BindSpecials will bind many special forms to an environment. The symbols of
+// the special forms are interned in the package INIT, but they are not
+// exported. Use Binding.ExportBindings to make them accessible in packages
+// that use INIT.
 func BindSpecials(bind *Binding, sps ...*Special) error {
 	for _, sp := range sps {
 		if err := sp.Bind(bind); err != nil {
@@ -0,0 +1,454 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+import (
+	"fmt"
+	"io"
+
+	"t73f.de/r/sx"
+)
+
+// opcode specifies the operation of an instruction.
+type opcode uint8
+
+const (
+	opConst         opcode = iota // push obj
+	opLookup                      // push value of sym (in slot) in n-th parent frame
+	opGlobal                      // push value of sym in global bindings, cached by expr
+	opResolve                     // push value of sym in frame or global bindings
+	opExpr                        // push value of expr, computed by the tree walker
+	opExprTail                    // return value of expr, computed by the tree walker
+	opCall                        // pop callable, call it with n args, push result
+	opCallTail                    // pop callable, call it with n args, return result
+	opBuiltin                     // call builtin b with n args, push result
+	opBuiltin0                    // call builtin b with no args, push result
+	opBuiltin1                    // call builtin b with one arg, push result
+	opBuiltin2                    // call builtin b with two args, push result
+	opBuiltin3                    // call builtin b with three args, push result
+	opJump                        // continue at n
+	opJumpFalse                   // pop value, continue at n if it is false
+	opJumpFalseKeep               // continue at n if value is false, otherwise pop it
+	opJumpTrueKeep                // continue at n if value is true, otherwise pop it
+	opPop                         // pop value
+	opReturn                      // pop value and return it
+	opLet                         // save frame, create child slot frame, bind first n slots
+	opBind                        // pop value, bind sym in current frame
+	opPopFrame                    // restore saved frame
+)
+
+var opNames = [...]string{
+	opConst:         "CONST",
+	opLookup:        "LOOKUP",
+	opGlobal:        "GLOBAL",
+	opResolve:       "RESOLVE",
+	opExpr:          "EXPR",
+	opExprTail:      "EXPR-TAIL",
+	opCall:          "CALL",
+	opCallTail:      "CALL-TAIL",
+	opBuiltin:       "BCALL",
+	opBuiltin0:      "BCALL-0",
+	opBuiltin1:      "BCALL-1",
+	opBuiltin2:      "BCALL-2",
+	opBuiltin3:      "BCALL-3",
+	opJump:          "JUMP",
+	opJumpFalse:     "JUMP-FALSE",
+	opJumpFalseKeep: "JUMP-FALSE-KEEP",
+	opJumpTrueKeep:  "JUMP-TRUE-KEEP",
+	opPop:           "POP",
+	opReturn:        "RETURN",
+	opLet:           "LET",
+	opBind:          "BIND",
+	opPopFrame:      "POP-FRAME",
+}
+
+func (op opcode) String() string { return opNames[op] }
+
+// instruction is a single operation of the virtual machine. Only the fields
+// needed by the opcode are set. Field level stores the expressions that are
+// reported on an error.
+type instruction struct {
+	op     opcode
+	n      int
+	sym    *sx.Symbol
+	slot   int
+	layout *FrameLayout
+	name   string
+	obj    sx.Object
+	b      *Builtin
+	expr   Expr
+	level  *level
+}
+
+// CompiledExpr is an expression that was compiled into instructions of the
+// virtual machine. Its value is the same as the value of the source
+// expression.
+type CompiledExpr struct {
+	source Expr
+	code   []instruction
+}
+
+// Source returns the expression that was compiled.
+func (ce *CompiledExpr) Source() Expr { return ce.source }
+
+// IsPure signals an expression that has no side effects.
+func (ce *CompiledExpr) IsPure() bool { return ce.source.IsPure() }
+
+// Unparse the expression back into a form object.
+func (ce *CompiledExpr) Unparse() sx.Object { return ce.source.Unparse() }
+
+// Print the expression on the given writer.
+func (ce *CompiledExpr) Print(w io.Writer) (int, error) {
+	length, err := io.WriteString(w, "{COMPILED ")
+	if err != nil {
+		return length, err
+	}
+	l, err := ce.source.Print(w)
+	length += l
+	if err != nil {
+		return length, err
+	}
+	l, err = io.WriteString(w, "}")
+	length += l
+	return length, err
+}
+
+// Disassemble writes the instructions on the given writer, one per line.
+func (ce *CompiledExpr) Disassemble(w io.Writer) (int, error) {
+	length := 0
+	for pc, ins := range ce.code {
+		l, err := fmt.Fprintf(w, "%3d %s", pc, ins.op)
+		length += l
+		if err != nil {
+			return length, err
+		}
+		switch ins.op {
+		case opConst:
+			l, err = fmt.Fprintf(w, " %v", ins.obj)
+		case opLookup:
+			if ins.slot >= 0 {
+				l, err = fmt.Fprintf(w, " %v/%d.%d", ins.sym, ins.n, ins.slot)
+			} else {
+				l, err = fmt.Fprintf(w, " %v/%d", ins.sym, ins.n)
+			}
+		case opGlobal, opResolve, opBind:
+			l, err = fmt.Fprintf(w, " %v", ins.sym)
+		case opExpr, opExprTail:
+			l, err = io.WriteString(w, " ")
+			if err == nil {
+				var l2 int
+				l2, err = ins.expr.Print(w)
+				l += l2
+			}
+		case opCall, opCallTail, opJump, opJumpFalse, opJumpFalseKeep, opJumpTrueKeep:
+			l, err = fmt.Fprintf(w, " %d", ins.n)
+		case opBuiltin:
+			l, err = fmt.Fprintf(w, " %s %d", ins.b.Name, ins.n)
+		case opBuiltin0, opBuiltin1, opBuiltin2, opBuiltin3:
+			l, err = fmt.Fprintf(w, " %s", ins.b.Name)
+		case opLet:
+			l, err = fmt.Fprintf(w, " %q %v %d", ins.name, ins.layout.syms, ins.n)
+		default:
+			l = 0
+		}
+		length += l
+		if err != nil {
+			return length, err
+		}
+		l, err = io.WriteString(w, "\n")
+		length += l
+		if err != nil {
+			return length, err
+		}
+	}
+	return length, nil
+}
+
+// vmState is the state of the virtual machine, while it computes some code.
+type vmState struct {
+	code   []instruction
+	pc     int
+	frame  *Frame
+	frames []*Frame // frames saved by let forms
+	base   int      // size of the stack, when the code was entered
+}
+
+// Compute the expression in a frame and return the result.
+//
+// If a procedure with a compiled body is called, and there is no compute
+// handler, the state of the caller is saved on a heap-allocated control
+// stack, and the body is computed by the same loop. Therefore, deep
+// recursion does not consume the stack of the Go runtime.
+func (ce *CompiledExpr) Compute(env *Environment, frame *Frame) (sx.Object, error) {
+	st := vmState{code: ce.code, frame: frame, base: len(env.stack)}
+	var conts []vmState // saved states of callers
+	for ; ; st.pc++ {
+		ins := &st.code[st.pc]
+		switch ins.op {
+		case opConst:
+			env.Push(ins.obj)
+
+		case opLookup:
+			obj, found := st.frame.lookupN(ins.sym, ins.n, ins.slot)
+			if !found {
+				return env.vmFail(&st, conts, ins.level, env.MakeNotBoundError(ins.sym, st.frame))
+			}
+			env.Push(obj)
+
+		case opGlobal:
+			obj, err := ins.expr.Compute(env, st.frame)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level, err)
+			}
+			env.Push(obj)
+
+		case opResolve:
+			obj, found := env.Resolve(ins.sym, st.frame)
+			if !found {
+				return env.vmFail(&st, conts, ins.level, env.MakeNotBoundError(ins.sym, st.frame))
+			}
+			env.Push(obj)
+
+		case opExpr:
+			obj, err := env.Execute(ins.expr, st.frame)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level.parent, err)
+			}
+			env.Push(obj)
+
+		case opExprTail:
+			if len(conts) == 0 {
+				return env.ExecuteTCO(ins.expr, st.frame)
+			}
+			obj, err := env.Execute(ins.expr, st.frame)
+			if err != nil {
+				return env.unwind(conts, err)
+			}
+			st, conts = env.resume(conts, obj)
+
+		case opCall, opCallTail:
+			val := env.pop()
+			proc, isCallable := GetCallable(val)
+			if !isCallable {
+				return env.vmFail(&st, conts, ins.level, NotCallableError{Obj: val})
+			}
+			obj, err := proc.ExecuteCall(env, env.Args(ins.n), st.frame)
+			if err == errExecuteAgain {
+				env.Kill(ins.n)
+				next, isCompiled := env.newExpr.(*CompiledExpr)
+				if ins.op == opCallTail {
+					if len(conts) == 0 {
+						return nil, err
+					}
+					if isCompiled {
+						if err = env.CheckContext(); err != nil {
+							return env.unwind(conts, env.addExecuteError(next, env.newFrame, err))
+						}
+						st = vmState{code: next.code, pc: -1, frame: env.newFrame, base: st.base}
+						continue
+					}
+					if obj, err = env.Execute(env.newExpr, env.newFrame); err != nil {
+						return env.unwind(conts, err)
+					}
+					st, conts = env.resume(conts, obj)
+					continue
+				}
+				if isCompiled && env.handler == nil {
+					conts = append(conts, st)
+					env.depth++
+					if err = env.enterCall(); err != nil {
+						return env.unwind(conts, env.addExecuteError(next, env.newFrame, err))
+					}
+					st = vmState{code: next.code, pc: -1, frame: env.newFrame, base: len(env.stack)}
+					continue
+				}
+				if obj, err = env.Execute(env.newExpr, env.newFrame); err != nil {
+					return env.vmFail(&st, conts, ins.level.parent, err)
+				}
+			} else if err != nil {
+				return env.vmFail(&st, conts, ins.level, err)
+			} else if env.Kill(ins.n); ins.op == opCallTail {
+				if len(conts) == 0 {
+					return obj, nil
+				}
+				st, conts = env.resume(conts, obj)
+				continue
+			}
+			env.Push(obj)
+
+		case opBuiltin:
+			obj, err := ins.b.callN(env, env.Args(ins.n), st.frame)
+			env.Kill(ins.n)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level, ins.b.handleCallError(err))
+			}
+			env.Push(obj)
+
+		case opBuiltin0:
+			obj, err := ins.b.Fn0(env, st.frame)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level, ins.b.handleCallError(err))
+			}
+			env.Push(obj)
+
+		case opBuiltin1:
+			obj, err := ins.b.Fn1(env, env.pop(), st.frame)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level, ins.b.handleCallError(err))
+			}
+			env.Push(obj)
+
+		case opBuiltin2:
+			args := env.Args(2)
+			obj, err := ins.b.Fn2(env, args[0], args[1], st.frame)
+			env.Kill(2)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level, ins.b.handleCallError(err))
+			}
+			env.Push(obj)
+
+		case opBuiltin3:
+			args := env.Args(3)
+			obj, err := ins.b.Fn3(env, args[0], args[1], args[2], st.frame)
+			env.Kill(3)
+			if err != nil {
+				return env.vmFail(&st, conts, ins.level, ins.b.handleCallError(err))
+			}
+			env.Push(obj)
+
+		case opJump:
+			st.pc = ins.n - 1
+
+		case opJumpFalse:
+			if sx.IsFalse(env.pop()) {
+				st.pc = ins.n - 1
+			}
+
+		case opJumpFalseKeep:
+			if sx.IsFalse(env.stack[len(env.stack)-1]) {
+				st.pc = ins.n - 1
+			} else {
+				env.Kill(1)
+			}
+
+		case opJumpTrueKeep:
+			if sx.IsTrue(env.stack[len(env.stack)-1]) {
+				st.pc = ins.n - 1
+			} else {
+				env.Kill(1)
+			}
+
+		case opPop:
+			env.Kill(1)
+
+		case opReturn:
+			obj := env.pop()
+			if len(conts) == 0 {
+				return obj, nil
+			}
+			st, conts = env.resume(conts, obj)
+
+		case opLet:
+			st.frames = append(st.frames, st.frame)
+			st.frame = st.frame.MakeSlotFrame(ins.name, ins.layout)
+			st.frame.setSlots(env.Args(ins.n))
+			env.Kill(ins.n)
+
+		case opBind:
+			st.frame.Bind(ins.sym, env.pop())
+
+		case opPopFrame:
+			last := len(st.frames) - 1
+			st.frame = st.frames[last]
+			st.frames = st.frames[:last]
+
+		default:
+			panic(fmt.Sprintf("unknown opcode %v", ins.op))
+		}
+	}
+}
+
+// enterCall checks whether a procedure may be called by the virtual machine
+// without using the stack of the Go runtime.
+func (env *Environment) enterCall() error {
+	if maxDepth := env.maxDepth; maxDepth > 0 && env.depth > maxDepth {
+		return ErrControlDepth{maxDepth}
+	}
+	return env.CheckContext()
+}
+
+// resume the state of the last caller with the result of its call.
+func (env *Environment) resume(conts []vmState, obj sx.Object) (vmState, []vmState) {
+	last := len(conts) - 1
+	env.depth--
+	env.Push(obj)
+	return conts[last], conts[:last]
+}
+
+// vmFail returns the error of an instruction, after adding the expressions of
+// the given levels and the ones of all callers.
+func (env *Environment) vmFail(st *vmState, conts []vmState, lvl *level, err error) (sx.Object, error) {
+	_, err = env.vmError(st.base, lvl, st.frame, err)
+	return env.unwind(conts, err)
+}
+
+// unwind the control stack of the virtual machine. The given error is the
+// one, which `Environment.Execute` of the current code would return. The
+// expressions of all callers are added, as the tree walker would do.
+func (env *Environment) unwind(conts []vmState, err error) (sx.Object, error) {
+	env.depth -= len(conts)
+	for i := len(conts) - 1; i >= 0; i-- {
+		if compErr, isCompiled := err.(compiledError); isCompiled {
+			err = compErr.ExecuteError
+		}
+		k := &conts[i]
+		_, err = env.vmError(k.base, k.code[k.pc].level.parent, k.frame, err)
+	}
+	return nil, err
+}
+
+// ErrControlDepth is an error to signal that the maximum depth of the control
+// stack of the virtual machine is exceeded.
+type ErrControlDepth struct{ depth int }
+
+func (e ErrControlDepth) Error() string {
+	return fmt.Sprintf("maximum control stack depth exceeded: %d", e.depth)
+}
+
+// pop removes the top value of the stack and returns it.
+func (env *Environment) pop() sx.Object {
+	sp := len(env.stack) - 1
+	obj := env.stack[sp]
+	env.stack = env.stack[:sp]
+	return obj
+}
+
+// vmError adds the expressions of the given levels to the error, as the
+// tree walker would do, and restores the stack of the virtual machine. The
+// outermost level is the compiled expression itself.
+func (env *Environment) vmError(base int, lvl *level, frame *Frame, err error) (sx.Object, error) {
+	for ; lvl != nil; lvl = lvl.parent {
+		err = env.addExecuteError(lvl.expr, frame, err)
+	}
+	env.stack = env.stack[:base]
+	if execErr, isExecErr := err.(ExecuteError); isExecErr {
+		return nil, compiledError{execErr}
+	}
+	return nil, err
+}
+
+// compiledError is an ExecuteError that already contains the expressions of
+// a compiled expression. It must not be extended by the `Environment.Execute`
+// that computed the compiled expression.
+type compiledError struct{ ExecuteError }
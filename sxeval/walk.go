@@ -0,0 +1,166 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval
+
+import "slices"
+
+// Composite is an additional interface for `Expr` that consists of
+// sub-expressions. It allows to analyze and to transform expressions without
+// knowing their concrete types.
+//
+// Some sub-expressions might be computed in another frame than the
+// expression itself, e.g. the body of a let expression. A transformation must
+// not move a sub-expression into another frame.
+type Composite interface {
+	// Children returns the direct sub-expressions.
+	Children() []Expr
+
+	// WithChildren returns a new expression, where the sub-expressions are
+	// replaced by the given ones. They must be given in the same order and
+	// number, as returned by Children. The expression itself is not changed.
+	WithChildren([]Expr) Expr
+}
+
+// Walk traverses the expression and all its sub-expressions in depth-first
+// order. The function is called for an expression before its children. If
+// it returns false, the children of the expression are not visited.
+func Walk(expr Expr, fn func(Expr) bool) {
+	if !fn(expr) {
+		return
+	}
+	if comp, isComposite := expr.(Composite); isComposite {
+		for _, child := range comp.Children() {
+			Walk(child, fn)
+		}
+	}
+}
+
+// Rewrite returns the expression, where all sub-expressions and then the
+// expression itself are replaced by the result of the function. If the
+// function returns nil, the expression is kept. An expression is rebuilt
+// only if some of its children were replaced. No expression is changed in
+// place.
+func Rewrite(expr Expr, fn func(Expr) Expr) Expr {
+	result, _ := rewrite(expr, fn)
+	return result
+}
+
+func rewrite(expr Expr, fn func(Expr) Expr) (Expr, bool) {
+	changed := false
+	if comp, isComposite := expr.(Composite); isComposite {
+		children := comp.Children()
+		var newChildren []Expr
+		for i, child := range children {
+			if newChild, childChanged := rewrite(child, fn); childChanged {
+				if newChildren == nil {
+					newChildren = slices.Clone(children)
+				}
+				newChildren[i] = newChild
+			}
+		}
+		if newChildren != nil {
+			expr, changed = comp.WithChildren(newChildren), true
+		}
+	}
+	if result := fn(expr); result != nil {
+		return result, true
+	}
+	return expr, changed
+}
+
+// ----- Composite methods of the basic expressions
+
+// Children returns the procedure and the arguments.
+func (ce *CallExpr) Children() []Expr { return append([]Expr{ce.Proc}, ce.Args...) }
+
+// WithChildren returns a call with the given procedure and arguments.
+func (ce *CallExpr) WithChildren(children []Expr) Expr {
+	return &CallExpr{Proc: children[0], Args: slices.Clone(children[1:])}
+}
+
+// Children returns the arguments.
+func (bce *builtinCallExpr) Children() []Expr { return slices.Clone(bce.Args) }
+
+// WithChildren returns a call with the given arguments.
+func (bce *builtinCallExpr) WithChildren(children []Expr) Expr {
+	return &builtinCallExpr{Proc: bce.Proc, Args: slices.Clone(children)}
+}
+
+// Children returns the argument.
+func (bce *BuiltinCall1Expr) Children() []Expr { return []Expr{bce.Arg} }
+
+// WithChildren returns a call with the given argument.
+func (bce *BuiltinCall1Expr) WithChildren(children []Expr) Expr {
+	return &BuiltinCall1Expr{Proc: bce.Proc, Arg: children[0]}
+}
+
+// Children returns the arguments.
+func (bce *builtinCall2Expr) Children() []Expr { return []Expr{bce.Arg0, bce.Arg1} }
+
+// WithChildren returns a call with the given arguments.
+func (bce *builtinCall2Expr) WithChildren(children []Expr) Expr {
+	return &builtinCall2Expr{Proc: bce.Proc, Arg0: children[0], Arg1: children[1]}
+}
+
+// Children returns the arguments.
+func (bce *builtinCall3Expr) Children() []Expr { return []Expr{bce.Arg0, bce.Arg1, bce.Arg2} }
+
+// WithChildren returns a call with the given arguments.
+func (bce *builtinCall3Expr) WithChildren(children []Expr) Expr {
+	return &builtinCall3Expr{Proc: bce.Proc, Arg0: children[0], Arg1: children[1], Arg2: children[2]}
+}
+
+// Children returns the source expression.
+func (ce *CompiledExpr) Children() []Expr { return []Expr{ce.source} }
+
+// WithChildren returns the given source expression. Since the instructions
+// do not match the new source any more, it is not compiled.
+func (*CompiledExpr) WithChildren(children []Expr) Expr { return children[0] }
+
+// Copy returns a deep copy of the expression, where all composite
+// sub-expressions are copied too. Expressions without sub-expressions are
+// shared. A copy may be used at another place, where it might be changed in
+// place, e.g. when it is compiled.
+func Copy(expr Expr) Expr {
+	comp, isComposite := expr.(Composite)
+	if !isComposite {
+		return expr
+	}
+	children := comp.Children()
+	newChildren := make([]Expr, len(children))
+	for i, child := range children {
+		newChildren[i] = Copy(child)
+	}
+	return comp.WithChildren(newChildren)
+}
+
+// DependsOnGlobals returns true, if the value of the expression might depend
+// on a binding outside of its frames, i.e. on a global binding or on a
+// symbol value. Such an expression may have no side effects, but its value
+// is not constant. An unknown expression without sub-expressions is assumed
+// to depend on global bindings.
+func DependsOnGlobals(expr Expr) bool {
+	result := false
+	Walk(expr, func(e Expr) bool {
+		switch e.(type) {
+		case ObjExpr, nilExpr, *frameSymbolExpr, *builtinCall0Expr:
+		default:
+			if _, isComposite := e.(Composite); !isComposite {
+				result = true
+			}
+		}
+		return !result
+	})
+	return result
+}
@@ -0,0 +1,233 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxeval_test
+
+import (
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxbuiltins"
+	"t73f.de/r/sx/sxeval"
+	"t73f.de/r/sx/sxreader"
+)
+
+func makeWalkRoot(t *testing.T) *sxeval.Binding {
+	t.Helper()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	return root
+}
+
+func parseForWalk(t *testing.T, env *sxeval.Environment, src string) sxeval.Expr {
+	t.Helper()
+	obj, err := sxreader.MakeReader(strings.NewReader(src)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := env.Parse(obj, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return expr
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+	env := sxeval.MakeEnvironment(makeWalkRoot(t))
+	expr := parseForWalk(t, env, "(lambda (a) (list 1 (if a (list a 2) (begin (set! a 3) a))))")
+
+	collect := func(skipIf bool) string {
+		var consts []string
+		sxeval.Walk(expr, func(e sxeval.Expr) bool {
+			if oe, isObj := e.(sxeval.ObjExpr); isObj {
+				consts = append(consts, oe.Obj.String())
+			}
+			_, isIf := e.(*sxbuiltins.IfExpr)
+			return !skipIf || !isIf
+		})
+		return strings.Join(consts, " ")
+	}
+	if got, exp := collect(false), "1 2 3"; got != exp {
+		t.Errorf("expected constants %q, but got %q", exp, got)
+	}
+	if got, exp := collect(true), "1"; got != exp {
+		t.Errorf("expected constants %q without if, but got %q", exp, got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	t.Parallel()
+	root := makeWalkRoot(t)
+	src := "(let ((f (lambda (x y) (cons x y)))) (list (f 1 2) (if (car (f 3 ())) 4 5) (let* ((a 6) (b 7)) (list a b))))"
+	double := func(e sxeval.Expr) sxeval.Expr {
+		if oe, isObj := e.(sxeval.ObjExpr); isObj {
+			if i, isInt := oe.Obj.(sx.Int64); isInt {
+				return sxeval.ObjExpr{Obj: i * 2}
+			}
+		}
+		return nil
+	}
+	for _, compile := range []bool{false, true} {
+		env := sxeval.MakeEnvironment(root).SetCompile(compile)
+		expr := parseForWalk(t, env, src)
+		if got := sxeval.Rewrite(expr, func(sxeval.Expr) sxeval.Expr { return nil }); got != expr {
+			t.Errorf("compile=%v: identity rewrite must return the same expression", compile)
+		}
+
+		rewritten := sxeval.Rewrite(expr, double)
+		if compile {
+			var err error
+			if rewritten, err = env.Compile(rewritten); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkRun(t, env, rewritten, "((2 . 4) 8 (12 14))")
+		checkRun(t, env, expr, "((1 . 2) 4 (6 7))")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	root := makeWalkRoot(t)
+	src := "(let ((f (lambda (x y) (cons x y)))) (list (f 1 2) (if (car (f 3 ())) 4 5) (let* ((a 6) (b 7)) (list a b))))"
+	env := sxeval.MakeEnvironment(root)
+	expr := parseForWalk(t, env, src)
+	composites := map[sxeval.Expr]bool{}
+	sxeval.Walk(expr, func(e sxeval.Expr) bool {
+		if _, isComposite := e.(sxeval.Composite); isComposite {
+			composites[e] = true
+		}
+		return true
+	})
+	cp := sxeval.Copy(expr)
+	sxeval.Walk(cp, func(e sxeval.Expr) bool {
+		if composites[e] {
+			t.Errorf("composite expression %v must not be shared", e)
+		}
+		return true
+	})
+
+	compiled, err := env.Compile(cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRun(t, env, compiled, "((1 . 2) 4 (6 7))")
+	checkRun(t, env, expr, "((1 . 2) 4 (6 7))")
+}
+
+func TestWithChildren(t *testing.T) {
+	t.Parallel()
+	root := makeWalkRoot(t)
+	testcases := []struct {
+		name string
+		src  string
+		exp  string
+	}{
+		{"let", "((lambda (x) (let ((a x) (b (list x))) (cons a b))) 1)", "(1 1)"},
+		{"let*", "((lambda (x) (let* ((a x) (b (list a))) (cons a b))) 1)", "(1 1)"},
+		{"lambda", "(map (lambda (x) (list x x)) '(1 2))", "((1 1) (2 2))"},
+		{"begin", "((lambda (x) (list (begin (car x) x) (begin1 x (car x)))) '(1))", "((1) (1))"},
+		{"and-or", "((lambda (x y) (list (and x y) (or y x))) 1 ())", "(() 1)"},
+		{"quasiquote", "((lambda (x) `(1 ,@x ,x)) '(2))", "(1 2 (2))"},
+		{"defvar", "((lambda (x) (defvar walk-var (list x))) 1)", "(1)"},
+		{"set!", "((lambda (x) (set! x (list x)) x) 1)", "(1)"},
+		{"if", "((lambda (x) (if (car x) (cdr x) x)) '(1 . 2))", "2"},
+		{"catch", "((lambda (x) (catch 'a (list x (throw 'a (car x))))) '(1))", "1"},
+		{"let/ec", "((lambda (x) (let/ec k (list x (k (car x))))) '(1))", "1"},
+		{"try", "((lambda (x) (try (car x) (except T c (list x)) (finally (list x)))) 1)", "(1)"},
+		{"builtin-calls", "((lambda (x) (list (car x) (cons x x) (fold + 0 x) (apply list x))) '(1 2))", "(1 ((1 2) 1 2) 3 (1 2))"},
+	}
+	rebuild := func(e sxeval.Expr) sxeval.Expr {
+		if comp, isComposite := e.(sxeval.Composite); isComposite {
+			return comp.WithChildren(comp.Children())
+		}
+		return nil
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, compile := range []bool{false, true} {
+				env := sxeval.MakeEnvironment(root.MakeChildBinding("children", 8)).SetCompile(compile)
+				expr := parseForWalk(t, env, tc.src)
+				sxeval.Walk(expr, func(e sxeval.Expr) bool {
+					if comp, isComposite := e.(sxeval.Composite); isComposite {
+						rebuilt := comp.WithChildren(comp.Children())
+						if got, exp := rebuilt.Unparse().String(), e.Unparse().String(); got != exp {
+							t.Errorf("compile=%v: rebuilt %T unparses to %s, but expected %s", compile, e, got, exp)
+						}
+					}
+					return true
+				})
+
+				rebuilt := sxeval.Rewrite(expr, rebuild)
+				if compile {
+					var err error
+					if rebuilt, err = env.Compile(rebuilt); err != nil {
+						t.Fatal(err)
+					}
+				}
+				checkRun(t, env, rebuilt, tc.exp)
+				checkRun(t, env, expr, tc.exp)
+			}
+		})
+	}
+}
+
+func checkRun(t *testing.T, env *sxeval.Environment, expr sxeval.Expr, exp string) {
+	t.Helper()
+	res, err := env.Run(expr, nil)
+	if err != nil {
+		t.Errorf("error running %v: %v", expr.Unparse(), err)
+		return
+	}
+	if got := res.String(); got != exp {
+		t.Errorf("%v should result in %s, but got %s", expr.Unparse(), exp, got)
+	}
+}
+
+func TestDependsOnGlobals(t *testing.T) {
+	t.Parallel()
+	root := sxeval.MakeRootBinding(256)
+	if err := sxbuiltins.BindAll(root); err != nil {
+		t.Fatal(err)
+	}
+	root.Freeze()
+	bind := root.MakeChildBinding("walk", 1)
+	if err := bind.Bind(sx.MakeSymbol("walk-global"), sx.Int64(7)); err != nil {
+		t.Fatal(err)
+	}
+	env := sxeval.MakeEnvironment(bind)
+	testcases := []struct {
+		src string
+		exp bool
+	}{
+		{"1", false},
+		{"(lambda (a) (list a 1))", false},
+		{"(let ((a 1)) (cons a a))", false},
+		{"walk-global", true},
+		{"(lambda (a) (list a walk-global))", true},
+		{"undefined-walk-symbol", true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.src, func(t *testing.T) {
+			expr := parseForWalk(t, env, tc.src)
+			if got := sxeval.DependsOnGlobals(expr); got != tc.exp {
+				t.Errorf("%v expected, but got %v for %v", tc.exp, got, expr)
+			}
+		})
+	}
+}
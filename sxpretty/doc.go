@@ -0,0 +1,217 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxpretty
+
+// Provides the document algebra and the layout algorithm.
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Doc is a document to be laid out. It is built by the functions Text,
+// Line, HardLine, BreakParent, Concat, Nest, Align, and Group.
+type Doc struct {
+	kind   docKind
+	text   string
+	indent int
+	docs   []Doc
+}
+
+type docKind uint8
+
+const (
+	docNil docKind = iota
+	docText
+	docLine
+	docHardLine
+	docBreakParent
+	docConcat
+	docNest
+	docAlign
+	docGroup
+)
+
+// Text returns a document that contains the given text. The text must not
+// contain a newline character.
+func Text(s string) Doc { return Doc{kind: docText, text: s} }
+
+// Line returns a document that is laid out as a space character, if it fits
+// into the current line. Otherwise, a new line is started.
+func Line() Doc { return Doc{kind: docLine} }
+
+// HardLine returns a document that always starts a new line. All enclosing
+// groups cannot be laid out on a single line.
+func HardLine() Doc { return Doc{kind: docHardLine} }
+
+// BreakParent returns an empty document that forces all enclosing groups to
+// be laid out on multiple lines. It is useful, if a line must end after the
+// following document, e.g. after a comment. Therefore, the following document
+// is not considered when deciding whether a preceding group fits.
+func BreakParent() Doc { return Doc{kind: docBreakParent} }
+
+// Concat returns the concatenation of all given documents.
+func Concat(docs ...Doc) Doc { return Doc{kind: docConcat, docs: docs} }
+
+// Nest returns a document, where all new lines are indented by the given
+// number of spaces, relative to the current indentation.
+func Nest(indent int, doc Doc) Doc { return Doc{kind: docNest, indent: indent, docs: []Doc{doc}} }
+
+// Align returns a document, where all new lines are indented to the column,
+// where the document starts.
+func Align(doc Doc) Doc { return Doc{kind: docAlign, docs: []Doc{doc}} }
+
+// Group returns a document that is laid out on a single line, if it fits.
+// Otherwise, all lines of the document (but not of its sub-groups) start a
+// new line.
+func Group(doc Doc) Doc { return Doc{kind: docGroup, docs: []Doc{doc}} }
+
+// IsNil returns true, if the document is the zero document.
+func (d Doc) IsNil() bool { return d.kind == docNil }
+
+// layoutCmd is an element of the layout stack.
+type layoutCmd struct {
+	indent int
+	flat   bool
+	doc    *Doc
+}
+
+// Render lays out the document, so that lines do not exceed the given
+// width, if possible, and writes the result to the writer.
+func Render(w io.Writer, width int, doc Doc) (int, error) {
+	lw := layoutWriter{w: w}
+	stack := []layoutCmd{{indent: 0, flat: false, doc: &doc}}
+	col := 0
+	for len(stack) > 0 && lw.err == nil {
+		cmd := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d := cmd.doc
+		switch d.kind {
+		case docText:
+			lw.writeString(d.text)
+			col += utf8.RuneCountInString(d.text)
+		case docLine:
+			if cmd.flat {
+				lw.writeString(" ")
+				col++
+				continue
+			}
+			lw.newline(cmd.indent)
+			col = cmd.indent
+		case docHardLine:
+			lw.newline(cmd.indent)
+			col = cmd.indent
+		case docConcat:
+			for i := len(d.docs) - 1; i >= 0; i-- {
+				stack = append(stack, layoutCmd{indent: cmd.indent, flat: cmd.flat, doc: &d.docs[i]})
+			}
+		case docNest:
+			stack = append(stack, layoutCmd{indent: cmd.indent + d.indent, flat: cmd.flat, doc: &d.docs[0]})
+		case docAlign:
+			stack = append(stack, layoutCmd{indent: col, flat: cmd.flat, doc: &d.docs[0]})
+		case docGroup:
+			next := layoutCmd{indent: cmd.indent, flat: true, doc: &d.docs[0]}
+			if !cmd.flat && !fits(width-col, next, stack) {
+				next.flat = false
+			}
+			stack = append(stack, next)
+		}
+	}
+	return lw.length, lw.err
+}
+
+// fits returns true, if the given command, followed by the commands of the
+// stack up to the next line break, fits into the remaining width.
+func fits(remaining int, cmd layoutCmd, stack []layoutCmd) bool {
+	cmds := []layoutCmd{cmd}
+	for remaining >= 0 {
+		if len(cmds) == 0 {
+			if len(stack) == 0 {
+				return true
+			}
+			cmds = append(cmds, stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+		}
+		c := cmds[len(cmds)-1]
+		cmds = cmds[:len(cmds)-1]
+		d := c.doc
+		switch d.kind {
+		case docText:
+			remaining -= utf8.RuneCountInString(d.text)
+		case docLine:
+			if !c.flat {
+				return true
+			}
+			remaining--
+		case docHardLine:
+			return !c.flat
+		case docBreakParent:
+			return !c.flat
+		case docConcat:
+			for i := len(d.docs) - 1; i >= 0; i-- {
+				cmds = append(cmds, layoutCmd{indent: c.indent, flat: c.flat, doc: &d.docs[i]})
+			}
+		case docNest, docAlign, docGroup:
+			cmds = append(cmds, layoutCmd{indent: c.indent, flat: c.flat, doc: &d.docs[0]})
+		}
+	}
+	return false
+}
+
+// layoutWriter writes to an io.Writer and remembers the first error.
+// Indentation and other spaces are written lazily, to avoid trailing spaces.
+type layoutWriter struct {
+	w      io.Writer
+	length int
+	err    error
+	indent int
+}
+
+func (lw *layoutWriter) writeString(s string) {
+	if s != "" && strings.Trim(s, " ") == "" {
+		lw.indent += len(s)
+		return
+	}
+	for lw.indent > 0 {
+		n := min(lw.indent, len(spaces))
+		lw.write(spaces[:n])
+		lw.indent -= n
+	}
+	lw.write(s)
+}
+
+func (lw *layoutWriter) write(s string) {
+	if lw.err == nil {
+		var l int
+		l, lw.err = io.WriteString(lw.w, s)
+		lw.length += l
+	}
+}
+
+// 80 spaces
+const spaces = "                                                                                "
+
+func (lw *layoutWriter) newline(indent int) {
+	lw.indent = 0
+	lw.write("\n")
+	lw.indent = indent
+}
+
+// String returns the document, laid out for the given width.
+func (d Doc) String() string {
+	var sb strings.Builder
+	_, _ = Render(&sb, DefaultWidth, d)
+	return sb.String()
+}
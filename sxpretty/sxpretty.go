@@ -0,0 +1,232 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+// Package sxpretty implements a pretty printer for symbolic expressions.
+//
+// The layout algorithm follows the ideas of Oppen and Wadler: an object is
+// translated into a document, which is laid out to respect a maximum line
+// width, if possible.
+package sxpretty
+
+import (
+	"io"
+	"strings"
+
+	"t73f.de/r/sx"
+)
+
+// DefaultWidth is the default maximum line width.
+const DefaultWidth = 80
+
+// Rule specifies how a list is laid out, when its first element is a
+// specific symbol.
+type Rule struct {
+	// Special is the number of arguments that are placed on the same line as
+	// the head symbol, e.g. the name and the parameters of a `defun`.
+	Special int
+
+	// Indent is the indentation of all other arguments, the body, relative
+	// to the opening parenthesis of the list.
+	Indent int
+}
+
+// DefaultRules returns the rules that are used by default.
+func DefaultRules() map[string]Rule {
+	return map[string]Rule{
+		"begin":      {Special: 0, Indent: 2},
+		"defdyn":     {Special: 2, Indent: 2},
+		"defmacro":   {Special: 2, Indent: 2},
+		"defun":      {Special: 2, Indent: 2},
+		"defvar":     {Special: 1, Indent: 2},
+		"dyn-lambda": {Special: 1, Indent: 2},
+		"if":         {Special: 1, Indent: 4},
+		"lambda":     {Special: 1, Indent: 2},
+		"let":        {Special: 1, Indent: 2},
+		"let*":       {Special: 1, Indent: 2},
+		"when":       {Special: 1, Indent: 2},
+		"unless":     {Special: 1, Indent: 2},
+	}
+}
+
+// Printer pretty-prints objects to a writer.
+type Printer struct {
+	w         io.Writer
+	width     int
+	maxDepth  int
+	maxLength int
+	radix     int
+	rules     map[string]Rule
+}
+
+// MakePrinter creates a new printer that writes to the given writer.
+func MakePrinter(w io.Writer) *Printer {
+	return &Printer{
+		w:     w,
+		width: DefaultWidth,
+		radix: 10,
+		rules: DefaultRules(),
+	}
+}
+
+// SetWidth sets the maximum line width.
+func (pr *Printer) SetWidth(width int) *Printer {
+	pr.width = width
+	return pr
+}
+
+// SetMaxDepth sets the maximum nesting depth of lists. Deeper lists are
+// printed as "...". A value of 0 signals no limit.
+func (pr *Printer) SetMaxDepth(depth int) *Printer {
+	pr.maxDepth = depth
+	return pr
+}
+
+// SetMaxLength sets the maximum number of list elements to be printed.
+// Remaining elements are printed as "...". A value of 0 signals no limit.
+func (pr *Printer) SetMaxLength(length int) *Printer {
+	pr.maxLength = length
+	return pr
+}
+
+// SetRadix sets the radix for printing numbers. Supported values are 2, 8,
+// 10, and 16.
+func (pr *Printer) SetRadix(radix int) *Printer {
+	pr.radix = radix
+	return pr
+}
+
+// SetRule sets the layout rule for lists that start with a symbol of the
+// given name.
+func (pr *Printer) SetRule(name string, rule Rule) *Printer {
+	pr.rules[name] = rule
+	return pr
+}
+
+// RemoveRule removes the layout rule for the given symbol name.
+func (pr *Printer) RemoveRule(name string) *Printer {
+	delete(pr.rules, name)
+	return pr
+}
+
+// Print writes the object, followed by a new line, to the writer of the printer.
+func (pr *Printer) Print(obj sx.Object) (int, error) {
+	return Render(pr.w, pr.width, Concat(pr.Doc(obj), HardLine()))
+}
+
+// Doc returns the document of the given object.
+func (pr *Printer) Doc(obj sx.Object) Doc { return pr.doc(obj, 0) }
+
+// Abbreviations for quotation forms.
+var abbrevs = map[*sx.Symbol]string{
+	sx.SymbolQuote:           "'",
+	sx.SymbolQuasiquote:      "`",
+	sx.SymbolUnquote:         ",",
+	sx.SymbolUnquoteSplicing: ",@",
+}
+
+func (pr *Printer) doc(obj sx.Object, depth int) Doc {
+	if sx.IsNil(obj) {
+		return Text("()")
+	}
+	switch o := obj.(type) {
+	case *sx.Pair:
+		if pr.maxDepth > 0 && depth >= pr.maxDepth {
+			return Text("...")
+		}
+		if sym, isSymbol := sx.GetSymbol(o.Car()); isSymbol {
+			if abbrev, found := abbrevs[sym]; found {
+				if next, isPair := sx.GetPair(o.Cdr()); isPair && sx.IsNil(next.Cdr()) {
+					return Concat(Text(abbrev), pr.doc(next.Car(), depth))
+				}
+			}
+		}
+		return pr.docList(o, depth)
+	case sx.Vector:
+		if pr.maxDepth > 0 && depth >= pr.maxDepth {
+			return Text("...")
+		}
+		return pr.docList(sx.MakeList(o...).Cons(sx.MakeSymbol(sx.VectorName)), depth)
+	case sx.Int64:
+		return Text(o.FormatRadix(pr.radix))
+	}
+	var sb strings.Builder
+	_, _ = sx.Print(&sb, obj)
+	return Text(sb.String())
+}
+
+func (pr *Printer) docList(lst *sx.Pair, depth int) Doc {
+	var elems []Doc
+	var tail Doc
+	for node := lst; ; {
+		if pr.maxLength > 0 && len(elems) >= pr.maxLength {
+			elems = append(elems, Text("..."))
+			break
+		}
+		elems = append(elems, pr.doc(node.Car(), depth+1))
+		cdr := node.Cdr()
+		if sx.IsNil(cdr) {
+			break
+		}
+		next, isPair := sx.GetPair(cdr)
+		if !isPair {
+			tail = pr.doc(cdr, depth+1)
+			break
+		}
+		node = next
+	}
+
+	if !tail.IsNil() {
+		elems = append(elems, Text("."), tail)
+	}
+	if len(elems) == 1 {
+		return Concat(Text("("), elems[0], Text(")"))
+	}
+
+	sym, isSymbol := sx.GetSymbol(lst.Car())
+	if !isSymbol {
+		// A data list: all elements are aligned.
+		return Group(Concat(Text("("), Align(joinLines(elems)), Text(")")))
+	}
+	if rule, found := pr.rules[sym.GetValue()]; found {
+		special := min(rule.Special, len(elems)-1)
+		header := []Doc{Text("("), elems[0]}
+		if special > 0 {
+			header = append(header, Text(" "), Align(Group(joinLines(elems[1:special+1]))))
+		}
+		var body []Doc
+		for _, elem := range elems[special+1:] {
+			body = append(body, Line(), elem)
+		}
+		return Group(Align(Concat(
+			Concat(header...),
+			Nest(rule.Indent, Concat(body...)),
+			Text(")"))))
+	}
+	// A function call: arguments are aligned to the first argument.
+	return Group(Concat(Text("("), elems[0], Text(" "), Align(joinLines(elems[1:])), Text(")")))
+}
+
+func joinLines(docs []Doc) Doc {
+	result := make([]Doc, 0, 2*len(docs))
+	for i, d := range docs {
+		if i > 0 {
+			result = append(result, Line())
+		}
+		result = append(result, d)
+	}
+	return Concat(result...)
+}
+
+// Print writes the object in a pretty way to the given writer, using the
+// default settings.
+func Print(w io.Writer, obj sx.Object) (int, error) { return MakePrinter(w).Print(obj) }
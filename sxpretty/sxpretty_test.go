@@ -0,0 +1,132 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxpretty_test
+
+import (
+	"strings"
+	"testing"
+
+	"t73f.de/r/sx"
+	"t73f.de/r/sx/sxpretty"
+	"t73f.de/r/sx/sxreader"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+	doc := sxpretty.Group(sxpretty.Concat(
+		sxpretty.Text("begin"),
+		sxpretty.Nest(2, sxpretty.Concat(sxpretty.Line(), sxpretty.Text("stmt1"), sxpretty.Line(), sxpretty.Text("stmt2"))),
+		sxpretty.Line(),
+		sxpretty.Text("end"),
+	))
+	testcases := []struct {
+		width int
+		exp   string
+	}{
+		{80, "begin stmt1 stmt2 end"},
+		{21, "begin stmt1 stmt2 end"},
+		{20, "begin\n  stmt1\n  stmt2\nend"},
+	}
+	for _, tc := range testcases {
+		var sb strings.Builder
+		if _, err := sxpretty.Render(&sb, tc.width, doc); err != nil {
+			t.Error(err)
+		}
+		if got := sb.String(); got != tc.exp {
+			t.Errorf("width %d: expected %q, but got %q", tc.width, tc.exp, got)
+		}
+	}
+
+	hard := sxpretty.Group(sxpretty.Concat(sxpretty.Text("a"), sxpretty.Line(), sxpretty.Text("b"), sxpretty.HardLine(), sxpretty.Text("c")))
+	if got := hard.String(); got != "a\nb\nc" {
+		t.Errorf("hard line must break group, but got %q", got)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		name  string
+		src   string
+		setup func(*sxpretty.Printer)
+		exp   string
+	}{
+		{name: "atom", src: "abc", exp: "abc"},
+		{name: "nil", src: "()", exp: "()"},
+		{name: "short", src: "(defun fac (n) (if (= n 0) 1 (* n (fac (- n 1)))))",
+			exp: "(defun fac (n) (if (= n 0) 1 (* n (fac (- n 1)))))"},
+		{name: "defun", src: "(defun fac (n) (if (= n 0) 1 (* n (fac (- n 1)))))",
+			setup: func(pr *sxpretty.Printer) { pr.SetWidth(30) },
+			exp:   "(defun fac (n)\n  (if (= n 0)\n      1\n      (* n (fac (- n 1)))))"},
+		{name: "let", src: "(let ((x 1) (y 2)) (+ x y) (list x y))",
+			setup: func(pr *sxpretty.Printer) { pr.SetWidth(30) },
+			exp:   "(let ((x 1) (y 2))\n  (+ x y)\n  (list x y))"},
+		{name: "call", src: "(list alpha beta gamma delta)",
+			setup: func(pr *sxpretty.Printer) { pr.SetWidth(20) },
+			exp:   "(list alpha\n      beta\n      gamma\n      delta)"},
+		{name: "data", src: "(1 2 3)",
+			setup: func(pr *sxpretty.Printer) { pr.SetWidth(5) },
+			exp:   "(1\n 2\n 3)"},
+		{name: "dotted", src: "(a b . c)", exp: "(a b . c)"},
+		{name: "quote", src: "(f 'a `(b ,c ,@d))", exp: "(f 'a `(b ,c ,@d))"},
+		{name: "quote-list", src: "(quote a b)", exp: "(quote a b)"},
+		{name: "custom-rule", src: "(my-form a b c)",
+			setup: func(pr *sxpretty.Printer) {
+				pr.SetWidth(10).SetRule("my-form", sxpretty.Rule{Special: 1, Indent: 1})
+			},
+			exp: "(my-form a\n b\n c)"},
+		{name: "removed-rule", src: "(let a b)",
+			setup: func(pr *sxpretty.Printer) { pr.SetWidth(5).RemoveRule("let") },
+			exp:   "(let a\n     b)"},
+		{name: "max-depth", src: "(a (b (c (d))))",
+			setup: func(pr *sxpretty.Printer) { pr.SetMaxDepth(2) },
+			exp:   "(a (b ...))"},
+		{name: "max-length", src: "(1 2 3 4 5)",
+			setup: func(pr *sxpretty.Printer) { pr.SetMaxLength(3) },
+			exp:   "(1 2 3 ...)"},
+		{name: "radix", src: "(255 -8 abc)",
+			setup: func(pr *sxpretty.Printer) { pr.SetRadix(16) },
+			exp:   "(#xff #x-8 abc)"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj, err := sxreader.MakeReader(strings.NewReader(tc.src)).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sb strings.Builder
+			pr := sxpretty.MakePrinter(&sb)
+			if tc.setup != nil {
+				tc.setup(pr)
+			}
+			if _, err = pr.Print(obj); err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tc.exp+"\n" {
+				t.Errorf("expected:\n%s\nbut got:\n%s", tc.exp, got)
+			}
+		})
+	}
+}
+
+func TestPrintVector(t *testing.T) {
+	t.Parallel()
+	var sb strings.Builder
+	if _, err := sxpretty.Print(&sb, sx.Vector{sx.Int64(1), sx.MakeString("a")}); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := sb.String(), "(vector 1 \"a\")\n"; got != exp {
+		t.Errorf("expected %q, but got %q", exp, got)
+	}
+}
@@ -0,0 +1,206 @@
+//-----------------------------------------------------------------------------
+// Copyright (c) 2026-present Detlef Stern
+//
+// This file is part of sx.
+//
+// sx is licensed under the latest version of the EUPL (European Union
+// Public License). Please see file LICENSE.txt for your rights and obligations
+// under this license.
+//
+// SPDX-License-Identifier: EUPL-1.2
+// SPDX-FileCopyrightText: 2026-present Detlef Stern
+//-----------------------------------------------------------------------------
+
+package sxreader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"t73f.de/r/sx"
+)
+
+// readHash is the reader macro for '#'. It dispatches on the next rune to one
+// of the hash macros, e.g. `#r`, `#i`, or `#x`.
+func readHash(rd *Reader, firstCh rune) (sx.Object, error) {
+	beginPos := rd.Position()
+	ch, err := rd.nextRune()
+	if err == nil {
+		if m, found := rd.hashes[ch]; found {
+			return m(rd, ch)
+		}
+		rd.unreadRunes(ch)
+	}
+	return nil, rd.annotateError(fmt.Errorf("'%c' not allowed here", firstCh), beginPos)
+}
+
+// readRadixNumber returns a reader macro to read a number with the given
+// radix prefix, e.g. `#xFF`, `#o-17`, or `#b1010_1010`.
+func readRadixNumber(prefix string) macroFn {
+	return func(rd *Reader, _ rune) (sx.Object, error) {
+		beginPos := rd.Position()
+		tok, err := rd.readToken(0, rd.isTerminal)
+		if err != nil {
+			return nil, rd.annotateError(err, beginPos)
+		}
+		sign, digits := "", tok
+		if len(digits) > 0 && (digits[0] == '+' || digits[0] == '-') {
+			sign, digits = digits[:1], digits[1:]
+		}
+		if digits == "" {
+			return nil, rd.annotateError(numberFormatError(tok), beginPos)
+		}
+		num, err := sx.ParseInteger(sign + prefix + digits)
+		if err != nil {
+			return nil, rd.annotateError(numberFormatError(tok), beginPos)
+		}
+		return num, nil
+	}
+}
+
+// readRawString reads a string without any escape processing.
+//
+// The string starts after an optional delimiter and a double quote. It ends
+// with a double quote, followed by the same delimiter. Therefore, `#r"a\b"`,
+// `#r#"say "hi""#`, and `#rEOS"..."EOS` are all valid raw strings.
+func readRawString(rd *Reader, _ rune) (sx.Object, error) {
+	beginPos := rd.Position()
+	var delim []rune
+	for {
+		ch, err := rd.nextRune()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrEOF
+			}
+			return nil, rd.annotateError(err, beginPos)
+		}
+		if ch == '"' {
+			break
+		}
+		if isSpace(ch) {
+			return nil, rd.annotateError(errRawStringDelimiter, beginPos)
+		}
+		delim = append(delim, ch)
+	}
+
+	var content []rune
+	for {
+		ch, err := rd.nextRune()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrEOF
+			}
+			return nil, rd.annotateError(err, beginPos)
+		}
+		content = append(content, ch)
+		if len(content) > len(delim) && content[len(content)-len(delim)-1] == '"' && hasRuneSuffix(content, delim) {
+			return sx.MakeString(string(content[:len(content)-len(delim)-1])), nil
+		}
+	}
+}
+
+var errRawStringDelimiter = errors.New("raw string delimiter must not contain space")
+
+func hasRuneSuffix(s, suffix []rune) bool {
+	if len(s) < len(suffix) {
+		return false
+	}
+	for i, ch := range suffix {
+		if s[len(s)-len(suffix)+i] != ch {
+			return false
+		}
+	}
+	return true
+}
+
+// readInterpolatedString reads a string with embedded expressions.
+//
+// The string uses the same escape sequences as a normal string, with the
+// addition of `\$`. An expression is embedded by `${expr}`. The result is a
+// list `(concat "..." (->string expr) ...)`, which will be evaluated later.
+// If there is no embedded expression, the string itself is returned.
+func readInterpolatedString(rd *Reader, _ rune) (sx.Object, error) {
+	beginPos := rd.Position()
+	ch, err := rd.nextRune()
+	if err != nil || ch != '"' {
+		if err == io.EOF {
+			err = ErrEOF
+		} else if err == nil {
+			err = fmt.Errorf("'\"' expected, but got '%c'", ch)
+		}
+		return nil, rd.annotateError(err, beginPos)
+	}
+
+	var lb sx.ListBuilder
+	var sb strings.Builder
+	hasExpr := false
+	for {
+		ch, err = rd.nextRune()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrEOF
+			}
+			return nil, rd.annotateError(err, beginPos)
+		}
+
+		switch ch {
+		case '\\':
+			ch, err = readEscape(rd)
+			if err != nil {
+				return nil, rd.annotateError(err, beginPos)
+			}
+		case '"':
+			if !hasExpr {
+				return sx.MakeString(sb.String()), nil
+			}
+			if sb.Len() > 0 {
+				lb.Add(sx.MakeString(sb.String()))
+			}
+			return lb.List().Cons(sx.SymbolConcat), nil
+		case '$':
+			ch2, err2 := rd.nextRune()
+			if err2 == nil && ch2 == '{' {
+				obj, errObj := rd.readEmbeddedExpr()
+				if errObj != nil {
+					return nil, rd.annotateError(errObj, beginPos)
+				}
+				if sb.Len() > 0 {
+					lb.Add(sx.MakeString(sb.String()))
+					sb.Reset()
+				}
+				lb.Add(sx.MakeList(sx.SymbolToString, obj))
+				hasExpr = true
+				continue
+			}
+			if err2 == nil {
+				rd.unreadRunes(ch2)
+			}
+		}
+		sb.WriteRune(ch)
+	}
+}
+
+// readEmbeddedExpr reads an expression of an interpolated string, up to the
+// closing curly bracket.
+func (rd *Reader) readEmbeddedExpr() (sx.Object, error) {
+	obj, err := rd.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, ErrEOF
+		}
+		return nil, err
+	}
+	ch, err := rd.skipSpace()
+	if err != nil {
+		if err == io.EOF {
+			return nil, ErrEOF
+		}
+		return nil, err
+	}
+	if ch != '}' {
+		return nil, fmt.Errorf("'}' expected, but got '%c'", ch)
+	}
+	return obj, nil
+}
@@ -33,6 +33,39 @@ func notAllowedHere(rd *Reader, firstCh rune) (sx.Object, error) {
 	return nil, rd.annotateError(fmt.Errorf("'%c' not allowed here", firstCh), beginPos)
 }
 
+// ellipsisName is the name of the only symbol that may start with a '.'.
+const ellipsisName = "..."
+
+// readDot reads the ellipsis symbol "...", which is used in macro patterns.
+// All other uses of a '.' are not allowed here.
+func readDot(rd *Reader, firstCh rune) (sx.Object, error) {
+	var chs []rune
+	for range len(ellipsisName) - 1 {
+		ch, err := rd.nextRune()
+		if err != nil {
+			break
+		}
+		chs = append(chs, ch)
+		if ch != '.' {
+			break
+		}
+	}
+	if len(chs) == len(ellipsisName)-1 && chs[len(chs)-1] == '.' {
+		ch, err := rd.nextRune()
+		if err != nil {
+			return rd.world.MakeSymbol(ellipsisName), nil
+		}
+		rd.unreadRunes(ch)
+		if ch != '.' && rd.isTerminal(ch) {
+			return rd.world.MakeSymbol(ellipsisName), nil
+		}
+	}
+	for i := len(chs) - 1; i >= 0; i-- {
+		rd.unreadRunes(chs[i])
+	}
+	return notAllowedHere(rd, firstCh)
+}
+
 func reserved(rd *Reader, firstCh rune) (sx.Object, error) {
 	beginPos := rd.Position()
 	return nil, rd.annotateError(fmt.Errorf("'%c' is reserved", firstCh), beginPos)
@@ -59,13 +92,14 @@ func readNumber(rd *Reader, firstCh rune) (sx.Object, error) {
 		return nil, rd.annotateError(err, beginPos)
 	}
 	num, err := sx.ParseInteger(tok)
-	if err == nil {
-		return num, nil
+	if err != nil {
+		return nil, rd.annotateError(numberFormatError(tok), beginPos)
 	}
-	sym := sx.MakeSymbol(tok)
-	return sym, nil
+	return num, nil
 }
 
+func numberFormatError(tok string) error { return fmt.Errorf("%w: %q", ErrNumberFormat, tok) }
+
 func readSymbol(rd *Reader, firstCh rune) (sx.Object, error) {
 	beginPos := rd.Position()
 	tok, err := rd.readToken(firstCh, rd.isTerminal)
@@ -75,20 +109,36 @@ func readSymbol(rd *Reader, firstCh rune) (sx.Object, error) {
 	ch, err := rd.nextRune()
 	if err != nil || ch != ':' {
 		rd.unreadRunes(ch)
-		sym := sx.MakeSymbol(tok)
+		sym := rd.world.MakeSymbol(tok)
 		return sym, nil
 	}
-	pkg := sx.FindPackage(tok)
+	pkg := rd.world.FindPackage(tok)
 	if pkg == nil {
 		return nil, rd.annotateError(fmt.Errorf("package %s not found", tok), beginPos)
 	}
+
+	// "pkg:name" denotes an external symbol, "pkg::name" an internal one.
+	internal := false
+	if ch, err = rd.nextRune(); err == nil {
+		if ch == ':' {
+			internal = true
+		} else {
+			rd.unreadRunes(ch)
+		}
+	}
 	tok, err = rd.readSymbolAfterColon()
 	if err != nil {
 		return nil, rd.annotateError(err, beginPos)
 	}
-	if sym := pkg.FindSymbol(tok); sym != nil {
+	if internal {
+		return pkg.MakeSymbol(tok), nil
+	}
+	if sym := pkg.FindExternalSymbol(tok); sym != nil {
 		return sym, nil
 	}
+	if pkg.FindSymbol(tok) != nil {
+		return nil, rd.annotateError(fmt.Errorf("symbol %s is not external in %s", tok, pkg.String()), beginPos)
+	}
 	return nil, rd.annotateError(fmt.Errorf("symbol %s not found in %s", tok, pkg.String()), beginPos)
 }
 
@@ -102,6 +152,17 @@ func readKeyword(rd *Reader, _ rune) (sx.Object, error) {
 	return keyword, nil
 }
 
+// readUninternedSymbol reads a symbol that does not belong to any package,
+// e.g. `#:g17`. Each read creates a new symbol.
+func readUninternedSymbol(rd *Reader, _ rune) (sx.Object, error) {
+	beginPos := rd.Position()
+	tok, err := rd.readSymbolAfterColon()
+	if err != nil {
+		return nil, rd.annotateError(err, beginPos)
+	}
+	return sx.MakeUninternedSymbol(tok), nil
+}
+
 func (rd *Reader) readSymbolAfterColon() (string, error) {
 	ch, err := rd.nextRune()
 	if err != nil {
@@ -110,6 +171,9 @@ func (rd *Reader) readSymbolAfterColon() (string, error) {
 		}
 		return "", err
 	}
+	if ch == chSymbolQuote {
+		return rd.readQuotedSymbolName()
+	}
 	if rd.isTerminal(ch) {
 		return "", errNoSymbolStart
 	}
@@ -118,8 +182,22 @@ func (rd *Reader) readSymbolAfterColon() (string, error) {
 
 var errNoSymbolStart = errors.New("no begin of symbol found")
 
-func readString(rd *Reader, _ rune) (sx.Object, error) {
+// chSymbolQuote is the character that encloses a quoted symbol name.
+const chSymbolQuote = '|'
+
+// readQuotedSymbol reads a symbol, which name is enclosed in '|'
+// characters. It allows to use all characters in a symbol name, e.g.
+// `|hello world|`. Escape sequences are the same as within strings.
+func readQuotedSymbol(rd *Reader, _ rune) (sx.Object, error) {
 	beginPos := rd.Position()
+	name, err := rd.readQuotedSymbolName()
+	if err != nil {
+		return nil, rd.annotateError(err, beginPos)
+	}
+	return rd.world.MakeSymbol(name), nil
+}
+
+func (rd *Reader) readQuotedSymbolName() (string, error) {
 	var sb strings.Builder
 	for {
 		ch, err := rd.nextRune()
@@ -127,37 +205,40 @@ func readString(rd *Reader, _ rune) (sx.Object, error) {
 			if err == io.EOF {
 				err = ErrEOF
 			}
-			return nil, rd.annotateError(err, beginPos)
+			return "", err
 		}
-
 		if ch == '\\' {
-			ch, err = rd.nextRune()
+			ch, err = readEscape(rd)
 			if err != nil {
-				if err == io.EOF {
-					err = ErrEOF
-				}
-				return nil, rd.annotateError(err, beginPos)
+				return "", err
 			}
-			switch ch {
-			case '"':
-			case '\\':
-			case 'n':
-				ch = '\n'
-			case 'r':
-				ch = '\r'
-			case 't':
-				ch = '\t'
-			case 'x':
-				ch, err = readRune(rd, 2)
-			case 'u':
-				ch, err = readRune(rd, 4)
-			case 'U':
-				ch, err = readRune(rd, 6)
+		} else if ch == chSymbolQuote {
+			if sb.Len() == 0 {
+				return "", errEmptySymbol
+			}
+			return sb.String(), nil
+		}
+		sb.WriteRune(ch)
+	}
+}
+
+var errEmptySymbol = errors.New("empty symbol name")
+
+func readString(rd *Reader, _ rune) (sx.Object, error) {
+	beginPos := rd.Position()
+	var sb strings.Builder
+	for {
+		ch, err := rd.nextRune()
+		if err != nil {
+			if err == io.EOF {
+				err = ErrEOF
 			}
+			return nil, rd.annotateError(err, beginPos)
+		}
+
+		if ch == '\\' {
+			ch, err = readEscape(rd)
 			if err != nil {
-				if err == io.EOF {
-					err = ErrEOF
-				}
 				return nil, rd.annotateError(err, beginPos)
 			}
 		} else if ch == '"' {
@@ -168,6 +249,31 @@ func readString(rd *Reader, _ rune) (sx.Object, error) {
 	}
 }
 
+// readEscape reads the rest of an escape sequence, after the backslash.
+func readEscape(rd *Reader) (rune, error) {
+	ch, err := rd.nextRune()
+	if err == nil {
+		switch ch {
+		case 'n':
+			ch = '\n'
+		case 'r':
+			ch = '\r'
+		case 't':
+			ch = '\t'
+		case 'x':
+			ch, err = readRune(rd, 2)
+		case 'u':
+			ch, err = readRune(rd, 4)
+		case 'U':
+			ch, err = readRune(rd, 6)
+		}
+	}
+	if err == io.EOF {
+		err = ErrEOF
+	}
+	return ch, err
+}
+
 func readRune(rd *Reader, numDigits int) (rune, error) {
 	result := rune(0)
 	for range numDigits {
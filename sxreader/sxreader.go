@@ -37,6 +37,8 @@ type Reader struct {
 	col     int
 	prevCol int
 	macros  macroMap
+	hashes  macroMap
+	world   *sx.World
 
 	maxDepth, curDepth uint
 	maxLength          uint
@@ -63,6 +65,12 @@ func (rp *Position) String() string {
 	return fmt.Sprintf("%s:%d:%d", name, rp.Line, rp.Col)
 }
 
+// SetWorld sets the world of packages, which is used to create symbols.
+func (rd *Reader) SetWorld(world *sx.World) *Reader {
+	rd.world = world
+	return rd
+}
+
 // SetNestingLimit sets the maximum nesting for a object.
 func (rd *Reader) SetNestingLimit(depth uint) *Reader {
 	rd.maxDepth = depth
@@ -96,7 +104,7 @@ func MakeReader(r io.Reader) *Reader {
 		prevCol: 0,
 		macros: macroMap{
 			'"':       readString,
-			'#':       notAllowedHere,
+			'#':       readHash,
 			'\'':      readQuote,
 			'(':       readList(')'),
 			')':       unmatchedDelimiter,
@@ -105,11 +113,21 @@ func MakeReader(r io.Reader) *Reader {
 			'{':       reserved,
 			'}':       reserved,
 			',':       readUnquote,
-			'.':       notAllowedHere,
+			'.':       readDot,
 			':':       readKeyword,
 			chComment: readComment,
 			'`':       readQuasiquote,
+			'|':       readQuotedSymbol,
+		},
+		hashes: macroMap{
+			':': readUninternedSymbol,
+			'b': readRadixNumber("0b"),
+			'i': readInterpolatedString,
+			'o': readRadixNumber("0o"),
+			'r': readRawString,
+			'x': readRadixNumber("0x"),
 		},
+		world:     sx.DefaultWorld(),
 		maxDepth:  DefaultNestingLimit,
 		maxLength: DefaultListLimit,
 	}
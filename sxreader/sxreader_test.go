@@ -62,21 +62,47 @@ func TestReaderInteger(t *testing.T) {
 		{name: "NegativeInt", src: "-6543", exp: "-6543"},
 		{name: "WithComment", src: " 234;comment", exp: "234"},
 		{name: "TrailingSpace", src: "345 ", exp: "345"},
-		{name: "InvalidValue", src: "123x", exp: "123x"},
-		{name: "NoNumberSymbol", src: "17-4", exp: "17-4"},
+		{name: "InvalidValue", src: "123x", exp: `ReaderError 1-4: invalid number format: "123x"`, mustErr: true},
+		{name: "NoNumberSymbol", src: "17-4", exp: `ReaderError 1-4: invalid number format: "17-4"`, mustErr: true},
+		{name: "TooLarge", src: "99999999999999999999", exp: `ReaderError 1-20: invalid number format: "99999999999999999999"`, mustErr: true},
+		{name: "Underscores", src: "1_000_000", exp: "1000000"},
+		{name: "DoubleUnderscore", src: "1__000", exp: `ReaderError 1-6: invalid number format: "1__000"`, mustErr: true},
+		{name: "TrailingUnderscore", src: "-1_", exp: `ReaderError 1-3: invalid number format: "-1_"`, mustErr: true},
+		{name: "Hex", src: "0xFF", exp: "255"},
+		{name: "NegativeHex", src: "-0x1_0", exp: "-16"},
+		{name: "Octal", src: "0o17", exp: "15"},
+		{name: "Binary", src: "+0b1010", exp: "10"},
+		{name: "InvalidBinary", src: "0b102", exp: `ReaderError 1-5: invalid number format: "0b102"`, mustErr: true},
+		{name: "HashHex", src: "#xff", exp: "255"},
+		{name: "HashNegativeHex", src: "#x-Ff", exp: "-255"},
+		{name: "HashOctal", src: "#o1_7", exp: "15"},
+		{name: "HashBinary", src: "#b1010", exp: "10"},
+		{name: "HashBinaryInList", src: "(#b1 #b10)", exp: "(1 2)"},
+		{name: "HashEmpty", src: "#x", exp: `ReaderError 1-2: invalid number format: ""`, mustErr: true},
+		{name: "HashSignOnly", src: "#x-", exp: `ReaderError 1-3: invalid number format: "-"`, mustErr: true},
+		{name: "HashPrefix", src: "#x0xff", exp: `ReaderError 1-6: invalid number format: "0xff"`, mustErr: true},
 	})
 }
 
 func TestReaderSymbol(t *testing.T) {
 	pkgHTML := sx.MustMakePackage("html")
-	_ = pkgHTML.MakeSymbol("body")
+	_ = pkgHTML.Export(pkgHTML.MakeSymbol("body"))
+	_ = pkgHTML.MakeSymbol("div")
 	performReaderTestCases(t, []readerTestCase{
 		{name: "bang zero", src: "!0", exp: "!0"},
 		{name: "Ascii", src: "moin", exp: "moin"},
 		{name: "Unicode", src: "µ☺", exp: "µ☺"},
 		{name: "Single char +", src: "+", exp: "+"},
 		{name: "Single char -", src: "-", exp: "-"},
+		{name: "Ellipsis", src: "...", exp: "..."},
+		{name: "EllipsisList", src: "(a ... . b)", exp: "(a ... . b)"},
 		{name: "NamespaceSymbol", src: "html:body", exp: "html:body"},
+		{name: "InternalSymbol", src: "html::div", exp: "html::div"},
+		{name: "InternalNewSymbol", src: "html::span", exp: "html::span"},
+		{name: "NotExternalSymbol",
+			src:     "html:div",
+			exp:     "ReaderError 1-8: symbol div is not external in #<package:html>",
+			mustErr: true},
 		{name: "NamespaceNoSymbol",
 			src:     "html:nobody",
 			exp:     "ReaderError 1-11: symbol nobody not found in #<package:html>",
@@ -86,8 +112,8 @@ func TestReaderSymbol(t *testing.T) {
 			exp:     "ReaderError 1-5: unexpected EOF",
 			mustErr: true},
 		{name: "NamespaceOnlyColon",
-			src:     "html::",
-			exp:     "ReaderError 1-6: no begin of symbol found",
+			src:     "html:::",
+			exp:     "ReaderError 1-7: no begin of symbol found",
 			mustErr: true},
 		{name: "UnknownNamespace",
 			src:     "jml:js",
@@ -96,6 +122,71 @@ func TestReaderSymbol(t *testing.T) {
 	})
 }
 
+func TestReaderQuotedSymbol(t *testing.T) {
+	pkgQuoted := sx.MustMakePackage("quoted")
+	_ = pkgQuoted.Export(pkgQuoted.MakeSymbol("a b"))
+	performReaderTestCases(t, []readerTestCase{
+		{name: "Simple", src: "|moin|", exp: "moin"},
+		{name: "Space", src: "|hello world|", exp: "|hello world|"},
+		{name: "Delimiter", src: `|(a "b")|`, exp: `|(a "b")|`},
+		{name: "Escape", src: `|a\|b\\c\x41|`, exp: `|a\|b\\cA|`},
+		{name: "Number", src: "|123|", exp: "|123|"},
+		{name: "InList", src: "(|a b| c)", exp: "(|a b| c)"},
+		{name: "Package", src: "quoted:|a b|", exp: "quoted:|a b|"},
+		{name: "InternalPackage", src: "quoted::|c d|", exp: "quoted::|c d|"},
+		{name: "Keyword", src: ":|a b|", exp: ":|a b|"},
+		{name: "Empty", src: "||", exp: "ReaderError 1-2: empty symbol name", mustErr: true},
+		{name: "MissingEnd", src: "|abc", exp: "ReaderError 1-4: unexpected EOF", mustErr: true},
+	})
+}
+
+func TestSymbolRoundTrip(t *testing.T) {
+	pkg := sx.MustMakePackage("roundtrip")
+	for _, name := range []string{
+		"a", "hello world", "(", ")", `"`, "#", "'", ",", ".", ":", ";", "`", "|", "\\",
+		"[x]", "{y}", "a:b", "1", "+1", "-1a", "+", "-", "1+", "tab\tchar", "nl\n", "\x00", "µ☺",
+	} {
+		_ = sx.MakeSymbol(name)
+		_ = pkg.MakeSymbol(name)
+		_ = sx.KeywordPackage().MakeSymbol(name)
+	}
+	for p := range sx.AllPackages() {
+		for sym := range p.AllSymbols() {
+			src := sym.String()
+			obj, err := sxreader.MakeReader(strings.NewReader(src)).Read()
+			if err != nil {
+				t.Errorf("symbol %q, printed as %q, cannot be read: %v", sym.GoString(), src, err)
+				continue
+			}
+			if !sym.IsEqual(obj) {
+				t.Errorf("symbol %q, printed as %q, was read as %v", sym.GoString(), src, obj)
+			}
+		}
+	}
+}
+
+func TestReaderWorld(t *testing.T) {
+	t.Parallel()
+	w := sx.MakeWorld()
+	pkg := w.MustMakePackage("reader-world")
+	_ = pkg.MakeSymbol("known")
+	if err := w.SetCurrentPackage(pkg); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := sxreader.MakeReader(strings.NewReader("sym |quoted sym| reader-world::known")).SetWorld(w).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if sym, isSymbol := sx.GetSymbol(obj); !isSymbol || sym.Package() != pkg {
+			t.Errorf("symbol of package %v expected, but got %v", pkg, obj)
+		}
+	}
+	if _, err = sxreader.MakeReader(strings.NewReader("reader-world::known")).Read(); err == nil {
+		t.Error("package of other world must not be found")
+	}
+}
+
 func TestReadKeyword(t *testing.T) {
 	performReaderTestCases(t, []readerTestCase{
 		{name: "bang zero", src: ":!0", exp: ":!0"},
@@ -188,6 +279,60 @@ func TestReadQuotes(t *testing.T) {
 func TestReadHash(t *testing.T) {
 	performReaderTestCases(t, []readerTestCase{
 		{name: "hash only", src: "#", exp: "ReaderError 1-1: '#' not allowed here", mustErr: true},
+		{name: "hash unknown", src: "#?", exp: "ReaderError 1-1: '#' not allowed here", mustErr: true},
+	})
+}
+
+func TestReadUninternedSymbol(t *testing.T) {
+	performReaderTestCases(t, []readerTestCase{
+		{name: "simple", src: "#:g17", exp: "#:g17"},
+		{name: "quoted", src: "#:|a b|", exp: "#:|a b|"},
+		{name: "in list", src: "(#:a b)", exp: "(#:a b)"},
+		{name: "no name", src: "#:", exp: "ReaderError 1-2: unexpected EOF", mustErr: true},
+		{name: "no begin", src: "#:)", exp: "ReaderError 1-3: no begin of symbol found", mustErr: true},
+	})
+
+	objs, err := sxreader.MakeReader(strings.NewReader("#:a #:a")).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym1, _ := sx.GetSymbol(objs[0])
+	sym2, _ := sx.GetSymbol(objs[1])
+	if sym1 == nil || sym1.IsInterned() || sym1 == sym2 {
+		t.Errorf("two different uninterned symbols expected, but got %v and %v", objs[0], objs[1])
+	}
+}
+
+func TestReadRawString(t *testing.T) {
+	performReaderTestCases(t, []readerTestCase{
+		{name: "Empty", src: `#r""`, exp: `""`},
+		{name: "Simple", src: `#r"moin"`, exp: `"moin"`},
+		{name: "Backslash", src: `#r"\d+\.\d*"`, exp: `"\\d+\\.\\d*"`},
+		{name: "NoEscape", src: `#r"a\nb"`, exp: `"a\\nb"`},
+		{name: "Newline", src: "#r\"a\nb\"", exp: `"a\nb"`},
+		{name: "HashDelim", src: `#r#"say "hi""#`, exp: `"say \"hi\""`},
+		{name: "WordDelim", src: `#rEOS"a "b"E "c"EOS`, exp: `"a \"b\"E \"c"`},
+		{name: "DelimSuffix", src: `#r#""#`, exp: `""`},
+		{name: "MissingQuote", src: `#r`, exp: "ReaderError 1-2: unexpected EOF", mustErr: true},
+		{name: "MissingEnd", src: `#r#"abc"`, exp: "ReaderError 1-8: unexpected EOF", mustErr: true},
+		{name: "SpaceDelim", src: `#r a"b" a`, exp: "ReaderError 1-3: raw string delimiter must not contain space", mustErr: true},
+	})
+}
+
+func TestReadInterpolatedString(t *testing.T) {
+	performReaderTestCases(t, []readerTestCase{
+		{name: "Empty", src: `#i""`, exp: `""`},
+		{name: "NoExpr", src: `#i"moin"`, exp: `"moin"`},
+		{name: "Escapes", src: `#i"a\tb\$\"c"`, exp: `"a\tb$\"c"`},
+		{name: "Dollar", src: `#i"$ ${}"`, exp: "ReaderError 1-8: '}' is reserved", mustErr: true},
+		{name: "LoneDollar", src: `#i"5$"`, exp: `"5$"`},
+		{name: "Symbol", src: `#i"${name}"`, exp: `(concat (->string name))`},
+		{name: "Mixed", src: `#i"Hello ${name}!"`, exp: `(concat "Hello " (->string name) "!")`},
+		{name: "Expr", src: `#i"${ (+ 1 2) } and ${"x"}"`, exp: `(concat (->string (+ 1 2)) " and " (->string "x"))`},
+		{name: "Nested", src: `#i"a${#i"b${c}"}"`, exp: `(concat "a" (->string (concat "b" (->string c))))`},
+		{name: "NoQuote", src: `#ix`, exp: "ReaderError 1-3: '\"' expected, but got 'x'", mustErr: true},
+		{name: "MissingEnd", src: `#i"${a`, exp: "ReaderError 1-5: unexpected EOF", mustErr: true},
+		{name: "MissingBrace", src: `#i"${a b}"`, exp: "ReaderError 1-8: '}' expected, but got 'b'", mustErr: true},
 	})
 }
 
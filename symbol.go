@@ -16,14 +16,23 @@ package sx
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode"
 )
 
 // Symbol represent a symbol value.
 type Symbol struct {
-	name   string   // symbol name
-	pkg    *Package // home package
-	bound  Object
+	name  string   // symbol name
+	pkg   *Package // home package, nil for uninterned symbols
+	value atomic.Pointer[symbolValue]
+}
+
+// symbolValue is the bound value of a symbol. It is never updated, to allow
+// atomic changes of both fields.
+type symbolValue struct {
+	obj    Object
 	frozen bool // value cannot be changed
 }
 
@@ -32,6 +41,28 @@ func MakeSymbol(name string) *Symbol {
 	return CurrentPackage().MakeSymbol(name)
 }
 
+// MakeUninternedSymbol creates a symbol that does not belong to any package.
+// Each call creates a new symbol, even if the name is the same. Therefore, an
+// uninterned symbol is only identical to itself.
+func MakeUninternedSymbol(name string) *Symbol {
+	if name == "" {
+		return nil
+	}
+	return &Symbol{name: name}
+}
+
+// gensymCounter is used to create unique names for GenSym.
+var gensymCounter atomic.Uint64
+
+// GenSym creates a new uninterned symbol, whose name is the given prefix,
+// followed by a unique number. If the prefix is empty, "g" is used.
+func GenSym(prefix string) *Symbol {
+	if prefix == "" {
+		prefix = "g"
+	}
+	return MakeUninternedSymbol(prefix + strconv.FormatUint(gensymCounter.Add(1), 10))
+}
+
 // GetValue return the string value of the symbol.
 func (sym *Symbol) GetValue() string { return sym.name }
 
@@ -72,34 +103,77 @@ func (sym *Symbol) String() string {
 func (sym *Symbol) GoString() string { return sym.name }
 
 // Print write the string representation to the given Writer.
+//
+// If the name of the symbol cannot be read back by the reader, it is
+// enclosed in '|' characters, e.g. `|hello world|`. If the symbol is not
+// accessible in the current package, the symbol is prefixed by the name of its
+// package, followed by ":" for external symbols and by "::" for internal
+// symbols. Use a WorldWriter to specify the world of the current package. An
+// uninterned symbol is prefixed by "#:".
 func (sym *Symbol) Print(w io.Writer) (length int, err error) {
-	if pkg := sym.pkg; pkg == keywordPackage {
-		var l int
-		l, err = io.WriteString(w, ":")
-		length += l
+	if pkg := sym.pkg; pkg == nil {
+		length, err = io.WriteString(w, "#:")
+		if err != nil {
+			return length, err
+		}
+	} else if pkg == keywordPackage {
+		length, err = io.WriteString(w, ":")
+		if err != nil {
+			return length, err
+		}
+	} else if cur := writerWorld(w).CurrentPackage(); pkg != cur && cur.FindSymbol(sym.name) != sym {
+		length, err = io.WriteString(w, pkg.name)
 		if err != nil {
 			return length, err
 		}
-	} else if pkg != CurrentPackage() {
-		if sym.pkg != keywordPackage {
-			length, err = io.WriteString(w, pkg.name)
-			if err != nil {
-				return length, err
-			}
+		sep := "::"
+		if pkg.IsExternal(sym) {
+			sep = ":"
 		}
 		var l int
-		l, err = io.WriteString(w, ":")
+		l, err = io.WriteString(w, sep)
 		length += l
 		if err != nil {
 			return length, err
 		}
 	}
 
-	// TODO: provide escape of symbol contains non-printable chars.
-	l, err := io.WriteString(w, sym.name)
+	var l int
+	if symbolNeedsQuote(sym.name) {
+		l, err = printQuoted(w, sym.name, '|')
+	} else {
+		l, err = io.WriteString(w, sym.name)
+	}
 	return length + l, err
 }
 
+// symbolDelimiter contains all characters that have a special meaning for the
+// reader. They must be in sync with the read macros of package sxreader.
+const symbolDelimiter = "\"#'(),.:;[]`{|}"
+
+// symbolNeedsQuote returns true, if the given symbol name cannot be read back
+// without quoting it.
+func symbolNeedsQuote(name string) bool {
+	if name == "" {
+		return true
+	}
+	if name == "..." {
+		// The ellipsis is read as a symbol, despite its delimiter characters.
+		return false
+	}
+	if isDigit(name[0]) || ((name[0] == '+' || name[0] == '-') && len(name) > 1 && isDigit(name[1])) {
+		return true
+	}
+	for _, ch := range name {
+		if strings.ContainsRune(symbolDelimiter, ch) || unicode.In(ch, unicode.C, unicode.Z) || !unicode.IsGraphic(ch) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigit(ch byte) bool { return '0' <= ch && ch <= '9' }
+
 // GetSymbol returns the object as a symbol if possible.
 func GetSymbol(obj Object) (*Symbol, bool) {
 	if IsNil(obj) {
@@ -109,9 +183,13 @@ func GetSymbol(obj Object) (*Symbol, bool) {
 	return sym, ok
 }
 
-// Package returns the Package that created the symbol.
+// Package returns the Package that created the symbol. It returns nil for an
+// uninterned symbol.
 func (sym *Symbol) Package() *Package { return sym.pkg }
 
+// IsInterned returns true, if the symbol belongs to a package.
+func (sym *Symbol) IsInterned() bool { return sym != nil && sym.pkg != nil }
+
 // IsKeyword returns true, if the symbols package is the keyword package.
 func (sym *Symbol) IsKeyword() bool {
 	if sym != nil {
@@ -132,19 +210,48 @@ func (err ErrSymbolFrozen) Error() string {
 }
 
 // Bind a value to the symbol.
+//
+// The value is global, i.e. it is shared by all environments. It is safe to
+// call this method concurrently.
 func (sym *Symbol) Bind(val Object) error {
-	if sym.frozen {
-		return ErrSymbolFrozen{Symbol: sym}
+	for {
+		old := sym.value.Load()
+		if old != nil && old.frozen {
+			return ErrSymbolFrozen{Symbol: sym}
+		}
+		if sym.value.CompareAndSwap(old, &symbolValue{obj: val}) {
+			return nil
+		}
 	}
-	sym.bound = val
-	return nil
 }
 
 // Bound returns the bound value.
-func (sym *Symbol) Bound() (Object, bool) { return sym.bound, sym.bound != nil }
+func (sym *Symbol) Bound() (Object, bool) {
+	if v := sym.value.Load(); v != nil && v.obj != nil {
+		return v.obj, true
+	}
+	return nil, false
+}
 
 // Freeze the symbol so that bound value cannot be changed any more.
-func (sym *Symbol) Freeze() { sym.frozen = true }
+func (sym *Symbol) Freeze() {
+	for {
+		old := sym.value.Load()
+		if old != nil && old.frozen {
+			return
+		}
+		v := &symbolValue{frozen: true}
+		if old != nil {
+			v.obj = old.obj
+		}
+		if sym.value.CompareAndSwap(old, v) {
+			return
+		}
+	}
+}
 
 // IsFrozen returns true if symbol is frozen.
-func (sym *Symbol) IsFrozen() bool { return sym.frozen }
+func (sym *Symbol) IsFrozen() bool {
+	v := sym.value.Load()
+	return v != nil && v.frozen
+}
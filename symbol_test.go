@@ -14,6 +14,7 @@
 package sx_test
 
 import (
+	"strings"
 	"testing"
 
 	"t73f.de/r/sx"
@@ -77,14 +78,30 @@ func TestSymbolString(t *testing.T) {
 	if myPkg == nil {
 		myPkg = sx.MustMakePackage("my")
 	}
+	extSym := myPkg.MakeSymbol("ext")
+	if err := myPkg.Export(extSym); err != nil {
+		t.Fatal(err)
+	}
 	testcases := []struct {
 		name string
 		sym  *sx.Symbol
 		exp  string
 	}{
 		{"simple symbol", sx.MakeSymbol("abc"), "abc"},
-		{"simple other pkg", myPkg.MakeSymbol("abc"), "my:abc"},
+		{"simple other pkg", myPkg.MakeSymbol("abc"), "my::abc"},
+		{"external other pkg", extSym, "my:ext"},
 		{"simple keyword", kwPkg.MakeSymbol("abc"), ":abc"},
+		{"space", sx.MakeSymbol("hello world"), "|hello world|"},
+		{"delimiter", sx.MakeSymbol("a(b"), "|a(b|"},
+		{"quote", sx.MakeSymbol(`a|b\c`), `|a\|b\\c|`},
+		{"number", sx.MakeSymbol("123"), "|123|"},
+		{"signed number", sx.MakeSymbol("-1x"), "|-1x|"},
+		{"sign", sx.MakeSymbol("-"), "-"},
+		{"newline", sx.MakeSymbol("a\nb"), `|a\nb|`},
+		{"other pkg space", myPkg.MakeSymbol("a b"), "my::|a b|"},
+		{"keyword space", kwPkg.MakeSymbol("a b"), ":|a b|"},
+		{"uninterned", sx.MakeUninternedSymbol("abc"), "#:abc"},
+		{"uninterned space", sx.MakeUninternedSymbol("a b"), "#:|a b|"},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -94,3 +111,37 @@ func TestSymbolString(t *testing.T) {
 		})
 	}
 }
+
+func TestUninternedSymbol(t *testing.T) {
+	t.Parallel()
+	if sym := sx.MakeUninternedSymbol(""); sym != nil {
+		t.Errorf("symbol with no name must result in nil, but got %v", sym)
+	}
+	sym1, sym2 := sx.MakeUninternedSymbol("a"), sx.MakeUninternedSymbol("a")
+	if sym1 == sym2 || sym1.IsEqual(sym2) {
+		t.Errorf("uninterned symbols %v and %v must be different", sym1, sym2)
+	}
+	if sym1.IsInterned() || sym1.Package() != nil || sym1.IsKeyword() {
+		t.Errorf("symbol %v must not belong to a package", sym1)
+	}
+	if sym := sx.MakeSymbol("a"); sym == sym1 || !sym.IsInterned() {
+		t.Errorf("interned symbol %v must be different from %v", sym, sym1)
+	}
+	if err := sym1.Bind(sx.Int64(7)); err != nil {
+		t.Fatal(err)
+	}
+	if val, bound := sym1.Bound(); !bound || !val.IsEqual(sx.Int64(7)) {
+		t.Errorf("uninterned symbol must be bound to 7, but got %v", val)
+	}
+
+	g1, g2 := sx.GenSym(""), sx.GenSym("tmp")
+	if g1 == g2 || g1.IsInterned() || g2.IsInterned() {
+		t.Errorf("two different uninterned symbols expected, but got %v and %v", g1, g2)
+	}
+	if got := g1.String(); !strings.HasPrefix(got, "#:g") {
+		t.Errorf("gensym %q must start with #:g", got)
+	}
+	if got := g2.String(); !strings.HasPrefix(got, "#:tmp") {
+		t.Errorf("gensym %q must start with #:tmp", got)
+	}
+}